@@ -0,0 +1,109 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Struct implementing the Limiter interface.
+type adaptiveLimiter struct {
+	limiter  Limiter
+	window   time.Duration
+	minLimit int
+	maxLimit int
+	increase int
+	decrease float64
+	mutex    sync.Mutex
+	limit    int
+}
+
+// Creates a new adaptive limiter, admitting initial calls per window to start
+// with.
+//
+// The limit follows the feedback given with Feedback, additively increasing
+// on success and multiplicatively decreasing on failure, within [min, max].
+// By default, successes add 1 and failures halve the limit, see WithAIMD.
+// Calls are spread over the window by an underlying reservoir limiter, which
+// receives the other options.
+//
+// Panics if the settings are invalid.
+func NewAdaptiveLimiter(initial, min, max int, window time.Duration, opts ...Option) Limiter {
+	o := newOptions(opts)
+	switch {
+	case min <= 0 || min > initial || initial > max:
+		panic(fmt.Errorf("%w: limits must satisfy 0 < min %d <= initial %d <= max %d", ErrInvalidConfig, min, initial, max))
+	case window <= 0:
+		panic(fmt.Errorf("%w: window %v is not positive", ErrInvalidConfig, window))
+	case window/time.Duration(max) <= 0:
+		panic(fmt.Errorf("%w: window %v is too short for %d calls", ErrInvalidConfig, window, max))
+	case o.increase <= 0:
+		panic(fmt.Errorf("%w: increase %d is not positive", ErrInvalidConfig, o.increase))
+	case o.decrease <= 0 || o.decrease >= 1:
+		panic(fmt.Errorf("%w: decrease factor %v out of range (0, 1)", ErrInvalidConfig, o.decrease))
+	}
+	return &adaptiveLimiter{
+		limiter:  NewReservoirLimiter(initial, window/time.Duration(initial), opts...),
+		window:   window,
+		minLimit: min,
+		maxLimit: max,
+		increase: o.increase,
+		decrease: o.decrease,
+		limit:    initial,
+	}
+}
+
+// Blocks until a token is available or the context is canceled.
+func (l *adaptiveLimiter) Limit(ctx context.Context) error {
+	return l.limiter.Limit(ctx)
+}
+
+// Blocks until n tokens are available or the context is canceled.
+func (l *adaptiveLimiter) LimitN(ctx context.Context, n int) error {
+	return l.limiter.LimitN(ctx, n)
+}
+
+// Consumes a token if one is immediately available, without blocking.
+func (l *adaptiveLimiter) TryLimit() bool {
+	return l.limiter.TryLimit()
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *adaptiveLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Adjusts the limit after a protected operation succeeded or failed.
+//
+// The limit is left as is if the rate of the underlying limiter cannot be
+// changed, e.g. once closed.
+func (l *adaptiveLimiter) Feedback(success bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	limit := l.limit
+	if success {
+		limit = min(limit+l.increase, l.maxLimit)
+	} else {
+		limit = max(int(float64(limit)*l.decrease), l.minLimit)
+	}
+	if limit == l.limit {
+		return
+	}
+	if l.limiter.(RateSetter).SetRate(limit, l.window/time.Duration(limit)) == nil {
+		l.limit = limit
+	}
+}
+
+// Returns the number of calls currently admitted per window.
+func (l *adaptiveLimiter) CurrentLimit() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.limit
+}
+
+// Closes the underlying limiter.
+func (l *adaptiveLimiter) Close() error {
+	return l.limiter.(io.Closer).Close()
+}
@@ -0,0 +1,94 @@
+package limiters_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestAdaptiveLimiterConverges(t *testing.T) {
+	const capacity = 20
+	limiter := limiters.NewAdaptiveLimiter(5, 1, 100, time.Second)
+	adaptive := limiter.(limiters.Adaptive)
+
+	// The downstream fails whenever it gets more than its capacity.
+	peak := 0
+	for i := 0; i < 500; i++ {
+		limit := adaptive.CurrentLimit()
+		if i >= 100 && (limit < capacity/2 || limit > capacity+1) {
+			t.Fatalf("iteration %d: limit %d did not converge around %d", i, limit, capacity)
+		}
+		peak = max(peak, limit)
+		adaptive.Feedback(limit <= capacity)
+	}
+	if peak != capacity+1 {
+		t.Fatalf("expected the limit to probe up to %d, got %d", capacity+1, peak)
+	}
+}
+
+func TestAdaptiveLimiterBounds(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewAdaptiveLimiter(4, 2, 6, time.Second,
+		limiters.WithClock(clock),
+		limiters.WithAIMD(3, 0.1),
+	)
+	adaptive := limiter.(limiters.Adaptive)
+
+	adaptive.Feedback(true)
+	if got := adaptive.CurrentLimit(); got != 6 {
+		t.Fatalf("expected the limit to be capped at 6, got %d", got)
+	}
+	clock.Advance(time.Second)
+	for i := 0; i < 6; i++ {
+		if !limiter.TryLimit() {
+			t.Fatalf("expected call %d to be admitted", i)
+		}
+	}
+	adaptive.Feedback(false)
+	if got := adaptive.CurrentLimit(); got != 2 {
+		t.Fatalf("expected the limit to floor at 2, got %d", got)
+	}
+	clock.Advance(time.Second)
+	for i := 0; i < 2; i++ {
+		if !limiter.TryLimit() {
+			t.Fatalf("expected refilled call %d to be admitted", i)
+		}
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected the reduced limit to apply")
+	}
+}
+
+func TestAdaptiveLimiterInvalid(t *testing.T) {
+	for _, build := range []func(){
+		func() { limiters.NewAdaptiveLimiter(1, 2, 3, time.Second) },
+		func() { limiters.NewAdaptiveLimiter(2, 1, 3, 0) },
+		func() { limiters.NewAdaptiveLimiter(2, 1, 3, 2*time.Nanosecond) },
+		func() { limiters.NewAdaptiveLimiter(2, 1, 3, time.Second, limiters.WithAIMD(0, 0.5)) },
+		func() { limiters.NewAdaptiveLimiter(2, 1, 3, time.Second, limiters.WithAIMD(1, 1)) },
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, limiters.ErrInvalidConfig) {
+					t.Errorf("expected a panic with ErrInvalidConfig, got %v", err)
+				}
+			}()
+			build()
+		}()
+	}
+}
+
+func TestAdaptiveLimiterFeedbackAfterClose(t *testing.T) {
+	limiter := limiters.NewAdaptiveLimiter(5, 1, 10, time.Second)
+	adaptive := limiter.(limiters.Adaptive)
+	limiter.(io.Closer).Close()
+	adaptive.Feedback(true)
+	if got := adaptive.CurrentLimit(); got != 5 {
+		t.Fatalf("expected the limit to stay at the rate in force, got %d", got)
+	}
+}
@@ -0,0 +1,68 @@
+package limiters
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy deciding how long to wait before retrying, e.g. for limiters
+// polling a remote store.
+type Backoff interface {
+	// Returns the delay before the given retry attempt, starting at zero.
+	Next(attempt int) time.Duration
+}
+
+// Backoff waiting the same delay before every attempt.
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+// Returns the constant delay.
+func (b ConstantBackoff) Next(attempt int) time.Duration {
+	return b.Delay
+}
+
+// Backoff multiplying the delay after every attempt.
+type ExponentialBackoff struct {
+	// Delay before the first attempt.
+	Initial time.Duration
+	// Largest delay, no limit if zero.
+	Max time.Duration
+	// Factor applied to the delay after every attempt, 2 if zero.
+	Multiplier float64
+}
+
+// Returns Initial times Multiplier to the power of attempt, capped at Max.
+func (b ExponentialBackoff) Next(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(attempt))
+	if b.Max > 0 && delay > float64(b.Max) {
+		return b.Max
+	}
+	if delay >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(delay)
+}
+
+// Exponential backoff drawing each delay uniformly between zero and the
+// exponential delay, so that clients retrying together spread out.
+type FullJitterBackoff struct {
+	ExponentialBackoff
+	// Source of randomness, the global one if nil.
+	Rand *rand.Rand
+}
+
+// Returns a random delay in [0, d), d being the exponential delay of the
+// attempt.
+func (b FullJitterBackoff) Next(attempt int) time.Duration {
+	random := rand.Float64
+	if b.Rand != nil {
+		random = b.Rand.Float64
+	}
+	return time.Duration(random() * float64(b.ExponentialBackoff.Next(attempt)))
+}
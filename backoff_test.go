@@ -0,0 +1,57 @@
+package limiters_test
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+func TestConstantBackoff(t *testing.T) {
+	b := limiters.ConstantBackoff{Delay: time.Second}
+	for attempt := 0; attempt < 5; attempt++ {
+		if got := b.Next(attempt); got != time.Second {
+			t.Errorf("attempt %d: expected %v, got %v", attempt, time.Second, got)
+		}
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := limiters.ExponentialBackoff{Initial: time.Millisecond, Max: 10 * time.Millisecond}
+	want := []time.Duration{1, 2, 4, 8, 10, 10}
+	for attempt, w := range want {
+		if got := b.Next(attempt); got != w*time.Millisecond {
+			t.Errorf("attempt %d: expected %v, got %v", attempt, w*time.Millisecond, got)
+		}
+	}
+
+	b = limiters.ExponentialBackoff{Initial: time.Second, Multiplier: 1.5}
+	if got := b.Next(2); got != 2250*time.Millisecond {
+		t.Errorf("expected %v, got %v", 2250*time.Millisecond, got)
+	}
+	if got := b.Next(1000); got <= 0 {
+		t.Errorf("expected a huge delay not to overflow, got %v", got)
+	}
+}
+
+func TestFullJitterBackoff(t *testing.T) {
+	b := limiters.FullJitterBackoff{
+		ExponentialBackoff: limiters.ExponentialBackoff{Initial: time.Millisecond, Max: 8 * time.Millisecond},
+		Rand:               rand.New(rand.NewPCG(1, 2)),
+	}
+	for attempt := 0; attempt < 10; attempt++ {
+		ceiling := b.ExponentialBackoff.Next(attempt)
+		distinct := make(map[time.Duration]bool)
+		for i := 0; i < 100; i++ {
+			got := b.Next(attempt)
+			if got < 0 || got >= ceiling {
+				t.Fatalf("attempt %d: delay %v out of [0, %v)", attempt, got, ceiling)
+			}
+			distinct[got] = true
+		}
+		if len(distinct) < 50 {
+			t.Errorf("attempt %d: expected delays to vary, got %d distinct values", attempt, len(distinct))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package limiters
+
+import "context"
+
+// Struct implementing the Limiter interface.
+type blockingLimiter struct{}
+
+// Creates a limiter admitting no call, e.g. to inject failures in tests.
+func NewBlockingLimiter() Limiter {
+	return blockingLimiter{}
+}
+
+// Blocks until the context is canceled.
+func (blockingLimiter) Limit(ctx context.Context) error {
+	<-ctx.Done()
+	return contextError(ctx)
+}
+
+// Blocks until the context is canceled.
+func (blockingLimiter) LimitN(ctx context.Context, n int) error {
+	<-ctx.Done()
+	return contextError(ctx)
+}
+
+// Never admits the call.
+func (blockingLimiter) TryLimit() bool {
+	return false
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (blockingLimiter) Allow() bool {
+	return false
+}
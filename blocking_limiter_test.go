@@ -0,0 +1,32 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+func TestBlockingLimiter(t *testing.T) {
+	limiter := limiters.NewBlockingLimiter()
+	if limiter.TryLimit() {
+		t.Fatal("expected the call not to be admitted")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Limit(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	done, cancelDone := context.WithCancel(context.Background())
+	cancelDone()
+	allocs := testing.AllocsPerRun(100, func() {
+		limiter.LimitN(done, 1)
+		limiter.TryLimit()
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocation, got %v", allocs)
+	}
+}
@@ -0,0 +1,64 @@
+package limiters
+
+import "context"
+
+// Key of the bypass marker carried by a context.
+type bypassKey struct{}
+
+// Returns a copy of ctx letting calls skip limiters created with
+// NewBypassLimiter.
+//
+// Bypass is only as safe as the code able to build such contexts: never
+// derive it from request data, such as a header, without authenticating the
+// caller first.
+func ContextWithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// Reports whether ctx carries the bypass marker.
+func HasBypass(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
+
+// Struct implementing the Limiter interface.
+type bypassLimiter struct {
+	limiter Limiter
+}
+
+// Creates a limiter admitting right away, without consuming tokens, the calls
+// whose context carries the bypass marker set with ContextWithBypass, and
+// passing the others on to the given limiter.
+//
+// TryLimit and Allow have no context and are never bypassed.
+func NewBypassLimiter(l Limiter) Limiter {
+	return &bypassLimiter{limiter: l}
+}
+
+// Blocks until a token is available or the context is canceled, unless the
+// context bypasses the limiter.
+func (l *bypassLimiter) Limit(ctx context.Context) error {
+	if HasBypass(ctx) {
+		return nil
+	}
+	return l.limiter.Limit(ctx)
+}
+
+// Blocks until n tokens are available or the context is canceled, unless
+// the context bypasses the limiter.
+func (l *bypassLimiter) LimitN(ctx context.Context, n int) error {
+	if HasBypass(ctx) {
+		return nil
+	}
+	return l.limiter.LimitN(ctx, n)
+}
+
+// Consumes a token if one is immediately available, without blocking.
+func (l *bypassLimiter) TryLimit() bool {
+	return l.limiter.TryLimit()
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *bypassLimiter) Allow() bool {
+	return l.limiter.Allow()
+}
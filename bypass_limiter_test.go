@@ -0,0 +1,44 @@
+package limiters_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestBypassLimiter(t *testing.T) {
+	reservoir := limiters.NewReservoirLimiter(1, time.Hour, limiters.WithClock(limiterstest.NewClock()))
+	counter := reservoir.(limiters.TokenCounter)
+	limiter := limiters.NewBypassLimiter(reservoir)
+	bypass := limiters.ContextWithBypass(context.Background())
+
+	for i := 0; i < 3; i++ {
+		if err := limiter.LimitN(bypass, 5); err != nil {
+			t.Fatalf("expected bypassed calls to be admitted, got %v", err)
+		}
+	}
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected bypassed calls not to consume tokens, %d left", got)
+	}
+
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := counter.Available(); got != 0 {
+		t.Fatalf("expected other calls to consume tokens, %d left", got)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := limiter.Limit(ctx); err == nil {
+		t.Fatal("expected other calls to be limited")
+	}
+	if err := limiter.Limit(limiters.ContextWithBypass(ctx)); err != nil {
+		t.Fatalf("expected bypassed calls to be admitted even when exhausted, got %v", err)
+	}
+	if limiters.HasBypass(context.Background()) {
+		t.Fatal("expected no bypass by default")
+	}
+}
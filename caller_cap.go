@@ -0,0 +1,74 @@
+package limiters
+
+import (
+	"context"
+	"time"
+)
+
+// Key of the caller key carried by a context.
+type callerKey struct{}
+
+// Returns a copy of ctx identifying its calls as made by the given caller,
+// e.g. a worker or a client, for limiters created with WithPerCallerCap.
+func ContextWithCaller(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, callerKey{}, key)
+}
+
+// Returns the caller key carried by ctx, and whether there is one.
+func CallerFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(callerKey{}).(string)
+	return key, ok
+}
+
+// Tokens granted to a caller since the start of its current window.
+type callerUsage struct {
+	start time.Time
+	count int
+}
+
+// Returns the window over which the tokens of each caller are capped: the
+// time to refill the whole reservoir.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) callerWindow() time.Duration {
+	return time.Duration(l.maxTokens) * l.refillDuration
+}
+
+// Reports whether the caller may be granted n more tokens in its window.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) underCallerCap(key string, n int, now time.Time) bool {
+	if l.callerCap == 0 || key == "" {
+		return true
+	}
+	u, ok := l.callers[key]
+	if !ok || now.Sub(u.start) >= l.callerWindow() {
+		return true
+	}
+	return u.count+n <= l.callerCap
+}
+
+// Counts n tokens granted to the caller, forgetting once per window the
+// callers whose window is over.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) chargeCaller(key string, n int, now time.Time) {
+	if l.callerCap == 0 || key == "" {
+		return
+	}
+	window := l.callerWindow()
+	if now.Sub(l.callersSwept) >= window {
+		for k, u := range l.callers {
+			if now.Sub(u.start) >= window {
+				delete(l.callers, k)
+			}
+		}
+		l.callersSwept = now
+	}
+	u, ok := l.callers[key]
+	if !ok || now.Sub(u.start) >= window {
+		l.callers[key] = &callerUsage{start: now, count: n}
+		return
+	}
+	u.count += n
+}
@@ -0,0 +1,51 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestPerCallerCap(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(10, time.Second, limiters.WithClock(clock), limiters.WithPerCallerCap(3))
+	reporter := limiter.(limiters.StatsReporter)
+	greedy := limiters.ContextWithCaller(context.Background(), "greedy")
+	for i := 0; i < 3; i++ {
+		if err := limiter.Limit(greedy); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The greedy caller waits although tokens are free, others proceed.
+	done := make(chan error)
+	go func() { done <- limiter.Limit(greedy) }()
+	eventually(t, func() bool { return reporter.Stats().Waiting == 1 })
+	polite := limiters.ContextWithCaller(context.Background(), "polite")
+	for i := 0; i < 3; i++ {
+		if err := limiter.Limit(polite); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("expected the greedy caller to wait, got %v", err)
+	default:
+	}
+
+	// Its window ends once the reservoir could have been refilled.
+	clock.Advance(10 * time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := limiter.LimitN(greedy, 4); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity beyond the cap, got %v", err)
+	}
+}
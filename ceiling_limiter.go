@@ -0,0 +1,27 @@
+package limiters
+
+import (
+	"fmt"
+	"time"
+)
+
+// Creates a limiter smoothing calls with a reservoir, while admitting at most
+// ceiling calls over any trailing window, however many tokens the reservoir
+// saved up, e.g. to honor the hard limit of a downstream.
+//
+// A call first takes its tokens from the reservoir, then waits for the window
+// to have room for it, holding the tokens meanwhile so that calls keep their
+// order. A call given up while waiting for the window gives its tokens back
+// to the reservoir, and only admitted calls count against the ceiling. The
+// options apply to the reservoir, and the clock to the window too. Panics if
+// the ceiling or the window is not positive, or if the options are invalid.
+//
+// The limiter implements io.Closer, closing the reservoir.
+func NewCeilingLimiter(maxTokens int, refillDuration time.Duration, ceiling int, window time.Duration, opts ...Option) Limiter {
+	if ceiling <= 0 || window <= 0 {
+		panic(fmt.Errorf("%w: ceiling of %d calls per %v", ErrInvalidConfig, ceiling, window))
+	}
+	reservoir := NewReservoirLimiter(maxTokens, refillDuration, opts...)
+	sliding := NewSlidingWindowLimiter(ceiling, window, WithClock(newOptions(opts).clock))
+	return NewChainLimiter(reservoir, sliding)
+}
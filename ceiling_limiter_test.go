@@ -0,0 +1,99 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestCeilingLimiterBursts(t *testing.T) {
+	const (
+		maxTokens = 10
+		refill    = 50 * time.Millisecond
+		ceiling   = 15
+		window    = time.Second
+		step      = 10 * time.Millisecond
+	)
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewCeilingLimiter(maxTokens, refill, ceiling, window, limiters.WithClock(clock))
+
+	// Bursts at every step, recording when calls are admitted.
+	var grants []time.Duration
+	for elapsed := time.Duration(0); elapsed < 5*time.Second; elapsed += step {
+		if elapsed%(700*time.Millisecond) == 0 {
+			// Pause for the reservoir to fill up again.
+			clock.Advance(700 * time.Millisecond)
+			elapsed += 700 * time.Millisecond
+		}
+		for i := 0; i < 20; i++ {
+			if limiter.TryLimit() {
+				grants = append(grants, elapsed)
+			}
+		}
+		clock.Advance(step)
+	}
+	for i, at := range grants {
+		inWindow := 0
+		for _, other := range grants[:i+1] {
+			if at-other < window {
+				inWindow++
+			}
+		}
+		if inWindow > ceiling {
+			t.Fatalf("expected at most %d calls per %v, got %d at %v", ceiling, window, inWindow, at)
+		}
+		if max := maxTokens + int(at/refill); i+1 > max {
+			t.Fatalf("expected at most %d calls after %v, got %d", max, at, i+1)
+		}
+	}
+	if len(grants) == 0 {
+		t.Fatal("expected some calls to be admitted")
+	}
+}
+
+func TestCeilingLimiterWaitsForWindow(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewCeilingLimiter(10, time.Second, 2, time.Second, limiters.WithClock(clock))
+	if err := limiter.LimitN(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens are left, but the ceiling is hit.
+	if limiter.TryLimit() {
+		t.Fatal("expected the ceiling to reject the call")
+	}
+	done := make(chan error)
+	go func() { done <- limiter.Limit(context.Background()) }()
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := limiter.LimitN(context.Background(), 3); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity above the ceiling, got %v", err)
+	}
+}
+
+func TestCeilingLimiterInvalid(t *testing.T) {
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, limiters.ErrInvalidConfig) {
+			t.Errorf("expected a panic with ErrInvalidConfig, got %v", err)
+		}
+	}()
+	limiters.NewCeilingLimiter(10, time.Second, 0, time.Second)
+}
+
+func TestCeilingLimiterClose(t *testing.T) {
+	limiter := limiters.NewCeilingLimiter(5, time.Second, 10, time.Minute)
+	if err := limiter.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := limiter.Limit(context.Background()); !errors.Is(err, limiters.ErrLimiterClosed) {
+		t.Fatalf("expected the reservoir to be closed, got %v", err)
+	}
+}
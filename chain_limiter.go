@@ -0,0 +1,82 @@
+package limiters
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Struct implementing the Limiter interface.
+type chainLimiter struct {
+	limiters []Limiter
+}
+
+// Creates a limiter admitting calls only when all the given limiters do.
+//
+// Limiters are acquired from in order. If one of them fails, the tokens taken
+// from the previous ones are given back, provided they implement
+// TokenReturner: tokens of the other limiters are lost.
+func NewChainLimiter(limiters ...Limiter) Limiter {
+	return &chainLimiter{limiters: limiters}
+}
+
+// Blocks until every limiter admits the call or the context is canceled.
+func (l *chainLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until every limiter admits n tokens or the context is canceled.
+func (l *chainLimiter) LimitN(ctx context.Context, n int) error {
+	for i, limiter := range l.limiters {
+		if err := limiter.LimitN(ctx, n); err != nil {
+			returnTokens(l.limiters[:i], n)
+			return err
+		}
+	}
+	return nil
+}
+
+// Consumes a token from every limiter if all of them have one immediately
+// available, without blocking.
+//
+// Returns false if any limiter has no token, in which case no token was
+// consumed from the limiters implementing TokenReturner.
+func (l *chainLimiter) TryLimit() bool {
+	for i, limiter := range l.limiters {
+		if !limiter.TryLimit() {
+			returnTokens(l.limiters[:i], 1)
+			return false
+		}
+	}
+	return true
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *chainLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Gives n unused tokens back to every limiter.
+func (l *chainLimiter) ReturnTokens(n int) {
+	returnTokens(l.limiters, n)
+}
+
+// Closes the limiters implementing io.Closer, returning their errors joined.
+func (l *chainLimiter) Close() error {
+	var errs []error
+	for _, limiter := range l.limiters {
+		if closer, ok := limiter.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Gives n tokens back to the limiters implementing TokenReturner.
+func returnTokens(limiters []Limiter, n int) {
+	for _, limiter := range limiters {
+		if returner, ok := limiter.(TokenReturner); ok {
+			returner.ReturnTokens(n)
+		}
+	}
+}
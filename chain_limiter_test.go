@@ -0,0 +1,64 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestChainLimiterTryLimitRollback(t *testing.T) {
+	perSecond := limiters.NewReservoirLimiter(5, time.Hour)
+	perMinute := limiters.NewReservoirLimiter(2, time.Hour)
+	chain := limiters.NewChainLimiter(perSecond, perMinute)
+
+	for i := 0; i < 2; i++ {
+		if !chain.TryLimit() {
+			t.Fatalf("expected call %d to be admitted", i)
+		}
+	}
+	if chain.TryLimit() {
+		t.Fatal("expected the chain to reject once the second limiter is exhausted")
+	}
+	if got := perSecond.(limiters.TokenCounter).Available(); got != 3 {
+		t.Fatalf("expected the first limiter's token to be given back, got %d tokens", got)
+	}
+}
+
+func TestChainLimiterLimitRollback(t *testing.T) {
+	clock := limiterstest.NewClock()
+	fast := limiters.NewReservoirLimiter(3, time.Millisecond, limiters.WithClock(clock))
+	slow := limiters.NewGCRALimiter(time.Hour, 1)
+	chain := limiters.NewChainLimiter(fast, slow)
+
+	if err := chain.Limit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := chain.LimitN(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if got := fast.(limiters.TokenCounter).Available(); got != 2 {
+		t.Fatalf("expected the fast limiter's token to be given back, got %d tokens", got)
+	}
+}
+
+func TestChainLimiterClose(t *testing.T) {
+	first := limiters.NewReservoirLimiter(1, time.Hour)
+	second := limiters.NewReservoirLimiter(1, time.Hour)
+	chain := limiters.NewChainLimiter(first, limiters.NewGCRALimiter(time.Hour, 1), second)
+
+	if err := chain.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i, member := range []limiters.Limiter{first, second} {
+		if err := member.Limit(context.Background()); !errors.Is(err, limiters.ErrLimiterClosed) {
+			t.Errorf("expected member %d to be closed, got %v", i, err)
+		}
+	}
+}
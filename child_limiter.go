@@ -0,0 +1,27 @@
+package limiters
+
+import "time"
+
+// Struct implementing the Limiter interface.
+type childLimiter struct {
+	*chainLimiter
+	own *reservoirLimiter
+}
+
+// Creates a limiter with its own reservoir, also taking each token from a
+// parent limiter shared with other children, e.g. per tenant under a global
+// limit.
+//
+// Tokens are taken from the child first, then from the parent. If the
+// parent does not admit the call, e.g. because the context deadline passes,
+// the child token is given back. Panics if the options are invalid.
+func NewChildLimiter(parent Limiter, maxTokens int, refillDuration time.Duration, opts ...Option) Limiter {
+	own := NewReservoirLimiter(maxTokens, refillDuration, opts...).(*reservoirLimiter)
+	return &childLimiter{chainLimiter: &chainLimiter{limiters: []Limiter{own, parent}}, own: own}
+}
+
+// Closes the reservoir of the child, leaving the parent open for the other
+// children.
+func (l *childLimiter) Close() error {
+	return l.own.Close()
+}
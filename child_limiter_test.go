@@ -0,0 +1,63 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestChildLimiterCaps(t *testing.T) {
+	clock := limiterstest.NewClock()
+	parent := limiters.NewReservoirLimiter(5, time.Hour, limiters.WithClock(clock))
+	tenantA := limiters.NewChildLimiter(parent, 3, time.Hour, limiters.WithClock(clock))
+	tenantB := limiters.NewChildLimiter(parent, 3, time.Hour, limiters.WithClock(clock))
+
+	for i := 0; i < 3; i++ {
+		if !tenantA.TryLimit() {
+			t.Fatalf("expected call %d of tenant A to be admitted", i)
+		}
+	}
+	if tenantA.TryLimit() {
+		t.Fatal("expected tenant A not to exceed its own limit")
+	}
+	if got := parent.(limiters.TokenCounter).Available(); got != 2 {
+		t.Fatalf("expected tenant A to take 3 parent tokens, %d left", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := tenantB.Limit(context.Background()); err != nil {
+			t.Fatalf("expected call %d of tenant B to be admitted, got %v", i, err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := tenantB.Limit(ctx); !errors.Is(err, limiters.ErrDeadlineExceeded) {
+		t.Fatalf("expected the aggregate not to exceed the parent limit, got %v", err)
+	}
+
+	// The child token of the failed call was given back.
+	parent.(limiters.TokenReturner).ReturnTokens(1)
+	if !tenantB.TryLimit() {
+		t.Fatal("expected tenant B to have a token left")
+	}
+}
+
+func TestChildLimiterClose(t *testing.T) {
+	parent := limiters.NewReservoirLimiter(5, time.Hour)
+	child := limiters.NewChildLimiter(parent, 3, time.Hour)
+
+	if err := child.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Limit(context.Background()); !errors.Is(err, limiters.ErrLimiterClosed) {
+		t.Fatalf("expected the child to be closed, got %v", err)
+	}
+	if !parent.TryLimit() {
+		t.Fatal("expected the parent to stay open")
+	}
+}
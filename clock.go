@@ -0,0 +1,101 @@
+package limiters
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Source of time used by limiters.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Delivers ticks at regular intervals, like time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Stops a function scheduled to run once, like time.Timer.
+type Timer interface {
+	// Returns false if the function already ran or the timer was stopped.
+	Stop() bool
+}
+
+// Implemented by clocks able to run a function once after a delay, like
+// time.AfterFunc.
+type TimerClock interface {
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Implemented by clocks able to run a function periodically without a
+// goroutine per caller.
+type scheduler interface {
+	schedule(first, period time.Duration, f func()) (stop func())
+}
+
+// Clock backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{ticker: time.NewTicker(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// Runs f once after d with the clock, in its own goroutine for clocks not
+// implementing TimerClock.
+func afterFunc(c Clock, d time.Duration, f func()) Timer {
+	if tc, ok := c.(TimerClock); ok {
+		return tc.AfterFunc(d, f)
+	}
+	t := &tickerTimer{ticker: c.NewTicker(d), stop: make(chan struct{})}
+	go t.run(f)
+	return t
+}
+
+// Timer backed by a ticker, stopped after its first tick.
+type tickerTimer struct {
+	ticker  Ticker
+	stop    chan struct{}
+	stopped atomic.Bool
+}
+
+func (t *tickerTimer) run(f func()) {
+	defer t.ticker.Stop()
+	select {
+	case <-t.ticker.C():
+		if t.stopped.CompareAndSwap(false, true) {
+			f()
+		}
+	case <-t.stop:
+	}
+}
+
+func (t *tickerTimer) Stop() bool {
+	if !t.stopped.CompareAndSwap(false, true) {
+		return false
+	}
+	close(t.stop)
+	return true
+}
+
+// Ticker backed by a time.Ticker.
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t realTicker) Stop() {
+	t.ticker.Stop()
+}
@@ -0,0 +1,108 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+)
+
+// Limiter bounding the number of calls in flight rather than their rate.
+//
+// Permits are taken with Acquire, or any method of the Limiter interface, and
+// given back with Release instead of being refilled over time.
+type ConcurrencyLimiter struct {
+	permits chan struct{}
+	// Held by the call gathering several permits, so that two such calls never
+	// hold part of their permits each while waiting for the others.
+	turn chan struct{}
+}
+
+// Creates a new concurrency limiter letting at most max callers hold a
+// permit at once.
+//
+// Panics if max is negative.
+func NewConcurrencyLimiter(max int) *ConcurrencyLimiter {
+	if max < 0 {
+		panic(fmt.Errorf("%w: max %d is negative", ErrInvalidConfig, max))
+	}
+	return &ConcurrencyLimiter{
+		permits: make(chan struct{}, max),
+		turn:    make(chan struct{}, 1),
+	}
+}
+
+// Blocks until a permit is available or the context is canceled.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Gives back a permit taken earlier.
+//
+// Panics if no permit is held.
+func (l *ConcurrencyLimiter) Release() {
+	l.ReturnTokens(1)
+}
+
+// Blocks until a permit is available or the context is canceled.
+func (l *ConcurrencyLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until n permits are available or the context is canceled, in which
+// case the permits already taken are given back.
+//
+// Calls taking several permits gather them one after the other, taking turns.
+func (l *ConcurrencyLimiter) LimitN(ctx context.Context, n int) error {
+	if n > cap(l.permits) {
+		return ErrExceedsCapacity
+	}
+	if n > 1 {
+		select {
+		case l.turn <- struct{}{}:
+			defer func() { <-l.turn }()
+		case <-ctx.Done():
+			return contextError(ctx)
+		}
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case l.permits <- struct{}{}:
+		case <-ctx.Done():
+			l.ReturnTokens(i)
+			return contextError(ctx)
+		}
+	}
+	return nil
+}
+
+// Takes a permit if one is immediately available, without blocking.
+func (l *ConcurrencyLimiter) TryLimit() bool {
+	select {
+	case l.permits <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *ConcurrencyLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Gives back n permits taken earlier.
+//
+// Panics if fewer than n permits are held.
+func (l *ConcurrencyLimiter) ReturnTokens(n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-l.permits:
+		default:
+			panic("limiters: release of a permit that is not held")
+		}
+	}
+}
+
+// Returns the number of permits currently held.
+func (l *ConcurrencyLimiter) InFlight() int {
+	return len(l.permits)
+}
@@ -0,0 +1,116 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+func TestConcurrencyLimiterBoundsInFlight(t *testing.T) {
+	const maxInFlight = 5
+	limiter := limiters.NewConcurrencyLimiter(maxInFlight)
+
+	var (
+		inFlight, peak atomic.Int64
+		wg             sync.WaitGroup
+	)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := limiter.Acquire(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+				current := inFlight.Add(1)
+				for {
+					observed := peak.Load()
+					if current <= observed || peak.CompareAndSwap(observed, current) {
+						break
+					}
+				}
+				inFlight.Add(-1)
+				limiter.Release()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > maxInFlight {
+		t.Fatalf("expected at most %d calls in flight, got %d", maxInFlight, got)
+	}
+	if got := limiter.InFlight(); got != 0 {
+		t.Fatalf("expected every permit to be released, got %d held", got)
+	}
+}
+
+func TestConcurrencyLimiterCancel(t *testing.T) {
+	limiter := limiters.NewConcurrencyLimiter(2)
+	if !limiter.TryLimit() {
+		t.Fatal("expected a permit")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.LimitN(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if got := limiter.InFlight(); got != 1 {
+		t.Fatalf("expected the partial permits to be given back, got %d held", got)
+	}
+	if err := limiter.LimitN(context.Background(), 3); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity, got %v", err)
+	}
+}
+
+func TestConcurrencyLimiterReleaseWithoutAcquire(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	limiters.NewConcurrencyLimiter(1).Release()
+}
+
+func TestConcurrencyLimiterLimitNNoDeadlock(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		limiter := limiters.NewConcurrencyLimiter(4)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+		// Two calls each holding two permits would wait for each other forever.
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for j := 0; j < 2; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				if err := limiter.LimitN(ctx, 3); err != nil {
+					t.Errorf("run %d: expected both calls to be served, got %v", i, err)
+					return
+				}
+				limiter.ReturnTokens(3)
+			}()
+		}
+		close(start)
+		wg.Wait()
+		cancel()
+		if t.Failed() {
+			return
+		}
+	}
+}
+
+func TestConcurrencyLimiterInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	limiters.NewConcurrencyLimiter(-1)
+}
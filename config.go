@@ -0,0 +1,183 @@
+package limiters
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Types of limiters built by NewFromConfig.
+const (
+	// Reservoir limiter, see NewReservoirLimiter.
+	TypeReservoir = "reservoir"
+	// Leaky bucket limiter releasing a call every refill duration, with room
+	// for max tokens queued calls, see NewLeakyBucketLimiter.
+	TypeLeakyBucket = "leaky_bucket"
+	// GCRA limiter admitting a call every refill duration, with bursts of max
+	// tokens calls, see NewGCRALimiter.
+	TypeGCRA = "gcra"
+	// Sliding window limiter admitting max tokens calls over any trailing
+	// window, see NewSlidingWindowLimiter.
+	TypeSlidingWindow = "sliding_window"
+	// Fixed window limiter admitting max tokens calls per window, see
+	// NewFixedWindowLimiter.
+	TypeFixedWindow = "fixed_window"
+)
+
+// Declarative settings of a limiter, e.g. read from a configuration file.
+//
+// In JSON, durations are strings such as "200ms", as parsed by
+// time.ParseDuration.
+type Config struct {
+	// Type of the limiter, one of the Type constants.
+	Type string
+	// Capacity of the limiter, whose meaning depends on its type.
+	MaxTokens int
+	// Time to refill a token, or between two calls for the leaky bucket and
+	// GCRA limiters.
+	RefillDuration time.Duration
+	// Window of the window limiters.
+	Window time.Duration
+	// Tokens a reservoir limiter starts with, nil for a full reservoir.
+	InitialTokens *int
+	// Burst of a reservoir limiter, zero for no burst limit.
+	Burst int
+	// Name of a reservoir limiter, reported through Namer, e.g. in logs and
+	// registries. The other types have no name and ignore it.
+	Name string
+}
+
+// JSON form of a Config.
+type configJSON struct {
+	Type           string `json:"type"`
+	MaxTokens      int    `json:"max_tokens,omitempty"`
+	RefillDuration string `json:"refill_duration,omitempty"`
+	Window         string `json:"window,omitempty"`
+	InitialTokens  *int   `json:"initial_tokens,omitempty"`
+	Burst          int    `json:"burst,omitempty"`
+	Name           string `json:"name,omitempty"`
+}
+
+// Encodes the configuration, with durations as strings.
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(configJSON{
+		Type:           c.Type,
+		MaxTokens:      c.MaxTokens,
+		RefillDuration: formatDuration(c.RefillDuration),
+		Window:         formatDuration(c.Window),
+		InitialTokens:  c.InitialTokens,
+		Burst:          c.Burst,
+		Name:           c.Name,
+	})
+}
+
+// Decodes the configuration, parsing durations from strings.
+//
+// Returns an error wrapping ErrInvalidConfig if a duration is malformed.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var j configJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	refill, err := parseDuration("refill_duration", j.RefillDuration)
+	if err != nil {
+		return err
+	}
+	window, err := parseDuration("window", j.Window)
+	if err != nil {
+		return err
+	}
+	*c = Config{
+		Type:           j.Type,
+		MaxTokens:      j.MaxTokens,
+		RefillDuration: refill,
+		Window:         window,
+		InitialTokens:  j.InitialTokens,
+		Burst:          j.Burst,
+		Name:           j.Name,
+	}
+	return nil
+}
+
+// Returns the string form of a duration, empty for zero.
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// Parses a duration of the given field, empty meaning zero.
+func parseDuration(field, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %w", ErrInvalidConfig, field, err)
+	}
+	return d, nil
+}
+
+// Creates the limiter described by the configuration.
+//
+// Returns an error wrapping ErrInvalidConfig if the type is unknown, or if a
+// field it needs is missing or invalid.
+func NewFromConfig(c Config) (Limiter, error) {
+	var opts []Option
+	if c.Name != "" {
+		opts = append(opts, WithName(c.Name))
+	}
+	switch c.Type {
+	case TypeReservoir:
+		if err := c.require(true, false); err != nil {
+			return nil, err
+		}
+		if c.InitialTokens != nil {
+			opts = append(opts, WithInitialTokens(*c.InitialTokens))
+		}
+		if c.Burst != 0 {
+			opts = append(opts, WithBurst(c.Burst))
+		}
+		return NewReservoirLimiterWithError(c.MaxTokens, c.RefillDuration, opts...)
+	case TypeLeakyBucket:
+		if err := c.require(true, false); err != nil {
+			return nil, err
+		}
+		return NewLeakyBucketLimiter(c.RefillDuration, c.MaxTokens, opts...), nil
+	case TypeGCRA:
+		if err := c.require(true, false); err != nil {
+			return nil, err
+		}
+		return NewGCRALimiter(c.RefillDuration, c.MaxTokens, opts...), nil
+	case TypeSlidingWindow:
+		if err := c.require(false, true); err != nil {
+			return nil, err
+		}
+		return NewSlidingWindowLimiter(c.MaxTokens, c.Window, opts...), nil
+	case TypeFixedWindow:
+		if err := c.require(false, true); err != nil {
+			return nil, err
+		}
+		return NewFixedWindowLimiter(c.MaxTokens, c.Window, opts...), nil
+	case "":
+		return nil, fmt.Errorf("%w: missing type", ErrInvalidConfig)
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidConfig, c.Type)
+	}
+}
+
+// Checks that the capacity is positive, and so are the refill duration and
+// the window if needed.
+func (c Config) require(refill, window bool) error {
+	if c.MaxTokens <= 0 {
+		return fmt.Errorf("%w: %s limiter needs positive max tokens, got %d", ErrInvalidConfig, c.Type, c.MaxTokens)
+	}
+	if refill && c.RefillDuration <= 0 {
+		return fmt.Errorf("%w: %s limiter needs a positive refill duration, got %v", ErrInvalidConfig, c.Type, c.RefillDuration)
+	}
+	if window && c.Window <= 0 {
+		return fmt.Errorf("%w: %s limiter needs a positive window, got %v", ErrInvalidConfig, c.Type, c.Window)
+	}
+	return nil
+}
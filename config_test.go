@@ -0,0 +1,79 @@
+package limiters_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+func TestConfigRoundTrip(t *testing.T) {
+	initial := 2
+	for _, config := range []limiters.Config{
+		{Type: limiters.TypeReservoir, MaxTokens: 5, RefillDuration: 200 * time.Millisecond, InitialTokens: &initial, Burst: 3, Name: "api"},
+		{Type: limiters.TypeLeakyBucket, MaxTokens: 10, RefillDuration: time.Second},
+		{Type: limiters.TypeGCRA, MaxTokens: 4, RefillDuration: 1500 * time.Microsecond},
+		{Type: limiters.TypeSlidingWindow, MaxTokens: 100, Window: time.Minute},
+		{Type: limiters.TypeFixedWindow, MaxTokens: 100, Window: time.Hour, Name: "daily"},
+	} {
+		data, err := json.Marshal(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded limiters.Config
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%s: unexpected error decoding %s: %v", config.Type, data, err)
+		}
+		if !reflect.DeepEqual(decoded, config) {
+			t.Errorf("%s: expected %+v after a round trip through %s, got %+v", config.Type, config, data, decoded)
+		}
+		if _, err := limiters.NewFromConfig(decoded); err != nil {
+			t.Errorf("%s: unexpected error building the limiter: %v", config.Type, err)
+		}
+	}
+}
+
+func TestConfigFromJSON(t *testing.T) {
+	var config limiters.Config
+	data := `{"type": "reservoir", "max_tokens": 3, "refill_duration": "200ms"}`
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.RefillDuration != 200*time.Millisecond {
+		t.Fatalf("expected a refill duration of 200ms, got %v", config.RefillDuration)
+	}
+	limiter, err := limiters.NewFromConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	granted := 0
+	for limiter.TryLimit() {
+		granted++
+	}
+	if granted != 3 {
+		t.Fatalf("expected a reservoir of 3 tokens, got %d", granted)
+	}
+
+	if err := json.Unmarshal([]byte(`{"type": "gcra", "refill_duration": "soon"}`), &config); !errors.Is(err, limiters.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig for a malformed duration, got %v", err)
+	}
+}
+
+func TestNewFromConfigInvalid(t *testing.T) {
+	tooMany := 2
+	for name, config := range map[string]limiters.Config{
+		"missing type":     {MaxTokens: 1, RefillDuration: time.Second},
+		"unknown type":     {Type: "token_bucket", MaxTokens: 1, RefillDuration: time.Second},
+		"missing tokens":   {Type: limiters.TypeReservoir, RefillDuration: time.Second},
+		"missing refill":   {Type: limiters.TypeLeakyBucket, MaxTokens: 1},
+		"missing window":   {Type: limiters.TypeSlidingWindow, MaxTokens: 1},
+		"too many initial": {Type: limiters.TypeReservoir, MaxTokens: 1, RefillDuration: time.Second, InitialTokens: &tooMany},
+	} {
+		if _, err := limiters.NewFromConfig(config); !errors.Is(err, limiters.ErrInvalidConfig) {
+			t.Errorf("%s: expected ErrInvalidConfig, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// Struct implementing the Limiter interface.
+type costBudgetLimiter struct {
+	limiter  Limiter
+	budget   float64
+	window   time.Duration
+	alpha    float64
+	maxLimit int
+	mutex    sync.Mutex
+	average  float64
+	limit    int
+}
+
+// Creates a limiter admitting as many calls per window as the cost budget
+// allows, given the average cost of recent calls.
+//
+// Callers report the actual cost of each call once it completed with Report.
+// The average is an exponentially-weighted moving average, starting at 1 and
+// weighting each report by the alpha set with WithCostSmoothing. At least one
+// call is admitted per window. Calls are spread over the window by an
+// underlying reservoir limiter, which receives the other options.
+//
+// Panics if the budget or the window is not positive, or if the options are
+// invalid.
+func NewCostBudgetLimiter(budget float64, window time.Duration, opts ...Option) Limiter {
+	o := newOptions(opts)
+	switch {
+	case !(budget > 0) || math.IsInf(budget, 1):
+		panic(fmt.Errorf("%w: cost budget %v is not positive", ErrInvalidConfig, budget))
+	case window <= 0:
+		panic(fmt.Errorf("%w: window %v is not positive", ErrInvalidConfig, window))
+	case !(o.costAlpha > 0) || o.costAlpha > 1:
+		panic(fmt.Errorf("%w: smoothing factor %v out of range (0, 1]", ErrInvalidConfig, o.costAlpha))
+	}
+	l := &costBudgetLimiter{
+		budget: budget,
+		window: window,
+		alpha:  o.costAlpha,
+		// The refill duration of the reservoir must stay positive.
+		maxLimit: int(min(window, math.MaxInt32)),
+		average:  1,
+	}
+	l.limit = l.limitFor(l.average)
+	l.limiter = NewReservoirLimiter(l.limit, window/time.Duration(l.limit), opts...)
+	return l
+}
+
+// Blocks until a token is available or the context is canceled.
+func (l *costBudgetLimiter) Limit(ctx context.Context) error {
+	return l.limiter.Limit(ctx)
+}
+
+// Blocks until n tokens are available or the context is canceled.
+func (l *costBudgetLimiter) LimitN(ctx context.Context, n int) error {
+	return l.limiter.LimitN(ctx, n)
+}
+
+// Consumes a token if one is immediately available, without blocking.
+func (l *costBudgetLimiter) TryLimit() bool {
+	return l.limiter.TryLimit()
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *costBudgetLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Folds the actual cost of a completed call into the average, and adjusts
+// the limit to it.
+//
+// Negative and NaN costs are ignored.
+func (l *costBudgetLimiter) Report(cost float64) {
+	if !(cost >= 0) {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.average += l.alpha * (min(cost, math.MaxFloat64) - l.average)
+	limit := l.limitFor(l.average)
+	if limit == l.limit {
+		return
+	}
+	if l.limiter.(RateSetter).SetRate(limit, l.window/time.Duration(limit)) == nil {
+		l.limit = limit
+	}
+}
+
+// Returns the average cost of recent calls.
+func (l *costBudgetLimiter) AverageCost() float64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.average
+}
+
+// Returns the number of calls currently admitted per window.
+func (l *costBudgetLimiter) CurrentLimit() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.limit
+}
+
+// Closes the underlying limiter.
+func (l *costBudgetLimiter) Close() error {
+	return l.limiter.(io.Closer).Close()
+}
+
+// Returns the number of calls of the given average cost fitting in the
+// budget.
+func (l *costBudgetLimiter) limitFor(average float64) int {
+	if average <= l.budget/float64(l.maxLimit) {
+		return l.maxLimit
+	}
+	return max(int(l.budget/average), 1)
+}
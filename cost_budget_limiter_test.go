@@ -0,0 +1,89 @@
+package limiters_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestCostBudgetLimiterConverges(t *testing.T) {
+	limiter := limiters.NewCostBudgetLimiter(1000, time.Second)
+	reporter := limiter.(limiters.CostReporter)
+
+	if got := reporter.CurrentLimit(); got != 1000 {
+		t.Fatalf("expected calls to cost 1 until reported otherwise, got a limit of %d", got)
+	}
+	for i := 0; i < 100; i++ {
+		reporter.Report(4)
+	}
+	if got := reporter.CurrentLimit(); got < 249 || got > 251 {
+		t.Fatalf("expected the limit to converge to 250 calls, got %d", got)
+	}
+
+	// Costs alternating around 10 converge to their mean.
+	for i := 0; i < 200; i++ {
+		reporter.Report(float64(8 + 4*(i%2)))
+		if limit := reporter.CurrentLimit(); i >= 50 && (limit < 90 || limit > 110) {
+			t.Fatalf("report %d: limit %d did not converge around 100", i, limit)
+		}
+	}
+	if got := reporter.AverageCost(); got < 9.5 || got > 10.5 {
+		t.Fatalf("expected an average cost around 10, got %v", got)
+	}
+
+	reporter.Report(-1)
+	if got := reporter.AverageCost(); got < 9.5 || got > 10.5 {
+		t.Fatalf("expected negative costs to be ignored, got an average of %v", got)
+	}
+}
+
+func TestCostBudgetLimiterEnforcesLimit(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewCostBudgetLimiter(100, time.Second,
+		limiters.WithClock(clock), limiters.WithCostSmoothing(1))
+	limiter.(limiters.CostReporter).Report(20)
+
+	admitted := 0
+	for i := 0; i < 10; i++ {
+		if limiter.TryLimit() {
+			admitted++
+		}
+	}
+	if admitted != 5 {
+		t.Fatalf("expected 5 calls of cost 20 to fit in the budget, got %d", admitted)
+	}
+
+	limiter.(limiters.CostReporter).Report(1000)
+	clock.Advance(time.Second)
+	if !limiter.TryLimit() || limiter.TryLimit() {
+		t.Fatal("expected a single call per window once calls cost more than the budget")
+	}
+}
+
+func TestCostBudgetLimiterKeepsLimitWhenSetRateFails(t *testing.T) {
+	limiter := limiters.NewCostBudgetLimiter(100, time.Second, limiters.WithCostSmoothing(1))
+	reporter := limiter.(limiters.CostReporter)
+	if err := limiter.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	reporter.Report(20)
+	if got := reporter.CurrentLimit(); got != 100 {
+		t.Fatalf("expected the limit to stay at 100 while the rate cannot be set, got %d", got)
+	}
+}
+
+func TestCostBudgetLimiterInvalid(t *testing.T) {
+	for _, alpha := range []float64{0, -1, 1.5} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected a smoothing factor of %v to panic", alpha)
+				}
+			}()
+			limiters.NewCostBudgetLimiter(100, time.Second, limiters.WithCostSmoothing(alpha))
+		}()
+	}
+}
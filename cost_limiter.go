@@ -0,0 +1,57 @@
+package limiters
+
+import "context"
+
+// Key of the cost carried by a context.
+type costKey struct{}
+
+// Returns a copy of ctx carrying the number of tokens a call costs, read by
+// limiters created with NewCostLimiter.
+func ContextWithCost(ctx context.Context, cost int) context.Context {
+	return context.WithValue(ctx, costKey{}, cost)
+}
+
+// Returns the cost carried by ctx, 1 if it carries none or a cost that is not
+// positive, which would let a call through for free.
+func CostFromContext(ctx context.Context) int {
+	if cost, ok := ctx.Value(costKey{}).(int); ok && cost > 0 {
+		return cost
+	}
+	return 1
+}
+
+// Struct implementing the Limiter interface.
+type costLimiter struct {
+	limiter Limiter
+}
+
+// Creates a limiter charging each call to Limit the cost carried by its
+// context, set with ContextWithCost, defaulting to 1 token.
+//
+// An explicit LimitN takes precedence: it charges n tokens whatever the
+// context carries. TryLimit and Allow have no context and charge 1 token.
+func NewCostLimiter(l Limiter) Limiter {
+	return &costLimiter{limiter: l}
+}
+
+// Blocks until the tokens of the context cost are available or the context
+// is canceled.
+func (l *costLimiter) Limit(ctx context.Context) error {
+	return l.limiter.LimitN(ctx, CostFromContext(ctx))
+}
+
+// Blocks until n tokens are available or the context is canceled, ignoring
+// the context cost.
+func (l *costLimiter) LimitN(ctx context.Context, n int) error {
+	return l.limiter.LimitN(ctx, n)
+}
+
+// Consumes a token if one is immediately available, without blocking.
+func (l *costLimiter) TryLimit() bool {
+	return l.limiter.TryLimit()
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *costLimiter) Allow() bool {
+	return l.limiter.Allow()
+}
@@ -0,0 +1,57 @@
+package limiters_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestCostFromContextDefault(t *testing.T) {
+	if got := limiters.CostFromContext(context.Background()); got != 1 {
+		t.Fatalf("expected a default cost of 1, got %d", got)
+	}
+	ctx := limiters.ContextWithCost(context.Background(), 3)
+	if got := limiters.CostFromContext(ctx); got != 3 {
+		t.Fatalf("expected a cost of 3, got %d", got)
+	}
+}
+
+func TestCostFromContextNotPositive(t *testing.T) {
+	for _, cost := range []int{0, -2} {
+		ctx := limiters.ContextWithCost(context.Background(), cost)
+		if got := limiters.CostFromContext(ctx); got != 1 {
+			t.Errorf("expected a cost of %d to fall back to 1, got %d", cost, got)
+		}
+	}
+}
+
+func TestCostLimiter(t *testing.T) {
+	reservoir := limiters.NewReservoirLimiter(10, time.Second, limiters.WithClock(limiterstest.NewClock()))
+	counter := reservoir.(limiters.TokenCounter)
+	limiter := limiters.NewCostLimiter(reservoir)
+
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := counter.Available(); got != 9 {
+		t.Fatalf("expected a call without cost to take 1 token, %d left", got)
+	}
+
+	ctx := limiters.ContextWithCost(context.Background(), 4)
+	if err := limiter.Limit(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := counter.Available(); got != 5 {
+		t.Fatalf("expected the context cost to be charged, %d left", got)
+	}
+
+	if err := limiter.LimitN(ctx, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := counter.Available(); got != 3 {
+		t.Fatalf("expected LimitN to take precedence over the context cost, %d left", got)
+	}
+}
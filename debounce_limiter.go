@@ -0,0 +1,100 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Struct implementing the Limiter interface.
+type debounceLimiter struct {
+	minInterval time.Duration
+	clock       Clock
+	mutex       sync.Mutex
+	last        time.Time
+}
+
+// Creates a new limiter admitting at most one call every minInterval,
+// without any burst.
+//
+// The limiter only keeps track of the time of the last grant: a call is
+// admitted once minInterval has elapsed since then. Of the options, only
+// WithClock applies. Panics if minInterval is negative.
+func NewDebounceLimiter(minInterval time.Duration, opts ...Option) Limiter {
+	if minInterval < 0 {
+		panic(fmt.Errorf("%w: negative interval %v", ErrInvalidConfig, minInterval))
+	}
+	return &debounceLimiter{minInterval: minInterval, clock: newOptions(opts).clock}
+}
+
+// Blocks until minInterval has elapsed since the last grant or the context
+// is canceled.
+func (l *debounceLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until the call is admitted or the context is canceled, counting it
+// as n grants in a row: the next call waits n times minInterval.
+func (l *debounceLimiter) LimitN(ctx context.Context, n int) error {
+	for {
+		delay := l.admit(n)
+		if delay == 0 {
+			return nil
+		}
+		ticker := l.clock.NewTicker(delay)
+		select {
+		case <-ticker.C():
+			ticker.Stop()
+		case <-ctx.Done():
+			ticker.Stop()
+			return contextError(ctx)
+		}
+	}
+}
+
+// Admits the call if minInterval has elapsed since the last grant, without
+// blocking.
+func (l *debounceLimiter) TryLimit() bool {
+	return l.admit(1) == 0
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *debounceLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Returns the time until a call would be admitted, zero if it would be
+// admitted right away.
+func (l *debounceLimiter) EstimateDelay() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.delay(l.clock.Now())
+}
+
+// Records n grants if minInterval has elapsed since the last one.
+//
+// Returns zero on success and the time until the call is admitted otherwise.
+func (l *debounceLimiter) admit(n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	if delay := l.delay(now); delay > 0 {
+		return delay
+	}
+	l.last = now.Add(time.Duration(n-1) * l.minInterval)
+	return 0
+}
+
+// Returns the time until minInterval has elapsed since the last grant.
+//
+// Must be called with the mutex held.
+func (l *debounceLimiter) delay(now time.Time) time.Duration {
+	if l.last.IsZero() {
+		return 0
+	}
+	return max(l.last.Add(l.minInterval).Sub(now), 0)
+}
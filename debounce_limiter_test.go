@@ -0,0 +1,119 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestDebounceLimiterSpacing(t *testing.T) {
+	const (
+		minInterval = 10 * time.Millisecond
+		callers     = 4
+		calls       = 5
+	)
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewDebounceLimiter(minInterval, limiters.WithClock(clock))
+
+	grants := make(chan time.Time, callers*calls)
+	var running atomic.Int64
+	running.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			for j := 0; j < calls; j++ {
+				if err := limiter.Limit(context.Background()); err != nil {
+					t.Error(err)
+				}
+				if j == calls-1 {
+					running.Add(-1)
+				}
+				grants <- clock.Now()
+			}
+		}()
+	}
+	// Every caller left is blocked on the clock.
+	settled := func() bool { return clock.ActiveTickers() == int(running.Load()) }
+
+	last := <-grants
+	for i := 1; i < callers*calls; i++ {
+		eventually(t, settled)
+		clock.Advance(minInterval / 2)
+		eventually(t, settled)
+		select {
+		case at := <-grants:
+			t.Fatalf("grant %d: expected to wait %v, admitted after %v", i, minInterval, at.Sub(last))
+		default:
+		}
+		clock.Advance(minInterval / 2)
+		select {
+		case at := <-grants:
+			if gap := at.Sub(last); gap != minInterval {
+				t.Fatalf("grant %d: expected a gap of %v, got %v", i, minInterval, gap)
+			}
+			last = at
+		case <-time.After(time.Second):
+			t.Fatalf("grant %d: expected a call to be admitted after %v", i, minInterval)
+		}
+	}
+}
+
+func TestDebounceLimiterTryLimit(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewDebounceLimiter(time.Hour, limiters.WithClock(clock))
+	if !limiter.TryLimit() {
+		t.Fatal("expected the first call to be admitted")
+	}
+	clock.Advance(time.Minute)
+	if limiter.Allow() {
+		t.Fatal("expected the next call to be too close")
+	}
+	if delay := limiter.(limiters.DelayEstimator).EstimateDelay(); delay != 59*time.Minute {
+		t.Fatalf("expected to wait 59 minutes, got %v", delay)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := limiter.Limit(ctx); !errors.Is(err, limiters.ErrDeadlineExceeded) {
+		t.Fatalf("expected ErrDeadlineExceeded, got %v", err)
+	}
+	clock.Advance(59 * time.Minute)
+	if !limiter.TryLimit() {
+		t.Fatal("expected a call to be admitted an hour after the first")
+	}
+}
+
+func TestDebounceLimiterLimitN(t *testing.T) {
+	const minInterval = 10 * time.Millisecond
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewDebounceLimiter(minInterval, limiters.WithClock(clock))
+
+	if err := limiter.LimitN(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+	if got := limiter.(limiters.DelayEstimator).EstimateDelay(); got != 3*minInterval {
+		t.Fatalf("expected LimitN to count as 3 grants, next one after %v", got)
+	}
+	clock.Advance(3*minInterval - time.Nanosecond)
+	if limiter.TryLimit() {
+		t.Fatal("expected the next call to wait 3 intervals")
+	}
+	clock.Advance(time.Nanosecond)
+	if !limiter.TryLimit() {
+		t.Fatal("expected the next call to be admitted after 3 intervals")
+	}
+}
+
+func TestDebounceLimiterInvalid(t *testing.T) {
+	assertInvalidConfig(t, func() { limiters.NewDebounceLimiter(-time.Second) })
+}
+
+func TestDebounceLimiterConformance(t *testing.T) {
+	profile := limiterstest.Profile{Burst: 1, Interval: time.Second, Refill: 1, Oversized: true}
+	limiterstest.RunProfileConformanceTests(t, profile, func(clock limiters.Clock) limiters.Limiter {
+		return limiters.NewDebounceLimiter(time.Second, limiters.WithClock(clock))
+	})
+}
@@ -0,0 +1,55 @@
+package limiters
+
+import "context"
+
+// Adapter letting a function waiting for a single token be used as a
+// Limiter, like http.HandlerFunc.
+//
+// LimitN calls the function n times, stopping at the first error. TryLimit
+// calls it with a context that is already canceled, so the function must
+// return promptly when its context is done: a call is admitted only if it
+// returns nil.
+type LimiterFunc func(ctx context.Context) error
+
+// Blocks until the function returns.
+func (f LimiterFunc) Limit(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Calls the function n times, stopping at the first error.
+func (f LimiterFunc) LimitN(ctx context.Context, n int) error {
+	for i := 0; i < n; i++ {
+		if err := f(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Calls the function with a canceled context, reporting whether it returned
+// nil.
+func (f LimiterFunc) TryLimit() bool {
+	return f(canceledContext) == nil
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (f LimiterFunc) Allow() bool {
+	return f.TryLimit()
+}
+
+// Context canceled once and for all, passed to functions that must not
+// block.
+var canceledContext = func() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}()
+
+// Wraps a limiter in each of the given middleware, the first one being the
+// outermost: Decorate(l, a, b) is a(b(l)).
+func Decorate(l Limiter, middleware ...func(Limiter) Limiter) Limiter {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		l = middleware[i](l)
+	}
+	return l
+}
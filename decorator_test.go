@@ -0,0 +1,80 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+func TestLimiterFunc(t *testing.T) {
+	calls := 0
+	limiter := limiters.LimiterFunc(func(ctx context.Context) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		calls++
+		if calls > 3 {
+			return limiters.ErrLimiterClosed
+		}
+		return nil
+	})
+
+	if err := limiter.LimitN(context.Background(), 2); err != nil || calls != 2 {
+		t.Fatalf("expected 2 calls, got %d and %v", calls, err)
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected TryLimit to pass a canceled context")
+	}
+	if err := limiter.LimitN(context.Background(), 3); !errors.Is(err, limiters.ErrLimiterClosed) || calls != 4 {
+		t.Fatalf("expected LimitN to stop at the first error, got %d calls and %v", calls, err)
+	}
+	if !limiters.LimiterFunc(func(context.Context) error { return nil }).Allow() {
+		t.Fatal("expected a function returning nil to admit calls")
+	}
+}
+
+func TestDecorateOrder(t *testing.T) {
+	var order []string
+	named := func(name string) func(limiters.Limiter) limiters.Limiter {
+		return func(next limiters.Limiter) limiters.Limiter {
+			return limiters.LimiterFunc(func(ctx context.Context) error {
+				order = append(order, name)
+				return next.Limit(ctx)
+			})
+		}
+	}
+	limiter := limiters.Decorate(limiters.NewNoopLimiter(), named("outer"), named("inner"))
+
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(order) != "[outer inner]" {
+		t.Fatalf("expected the first middleware to be outermost, got %v", order)
+	}
+	if limiters.Decorate(limiters.NewNoopLimiter()) != limiters.NewNoopLimiter() {
+		t.Fatal("expected no middleware to return the limiter as is")
+	}
+}
+
+func ExampleDecorate() {
+	logged := func(next limiters.Limiter) limiters.Limiter {
+		return limiters.LimiterFunc(func(ctx context.Context) error {
+			err := next.Limit(ctx)
+			fmt.Println("admitted:", err == nil)
+			return err
+		})
+	}
+	limiter := limiters.Decorate(limiters.NewReservoirLimiter(1, time.Hour), logged)
+
+	limiter.Limit(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	limiter.Limit(ctx)
+	// Output:
+	// admitted: true
+	// admitted: false
+}
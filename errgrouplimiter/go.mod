@@ -0,0 +1,10 @@
+module github.com/p-nordmann/limiters/errgrouplimiter
+
+go 1.23.0
+
+require (
+	github.com/p-nordmann/limiters v0.0.0
+	golang.org/x/sync v0.10.0
+)
+
+replace github.com/p-nordmann/limiters => ../
@@ -0,0 +1,23 @@
+// Package errgrouplimiter paces the goroutines of an errgroup.Group with a
+// limiter.
+package errgrouplimiter
+
+import (
+	"context"
+
+	"github.com/p-nordmann/limiters"
+	"golang.org/x/sync/errgroup"
+)
+
+// Waits for the limiter to admit a call, then runs fn in the group.
+//
+// Go blocks the caller until a token is taken, so that a loop launching work
+// is paced by the limiter. If the limiter rejects the call, e.g. because ctx
+// is canceled, fn is not run and the error is returned to the group instead.
+func Go(ctx context.Context, l limiters.Limiter, g *errgroup.Group, fn func() error) {
+	if err := l.Limit(ctx); err != nil {
+		g.Go(func() error { return err })
+		return
+	}
+	g.Go(fn)
+}
@@ -0,0 +1,60 @@
+package errgrouplimiter_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/errgrouplimiter"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestGoPacesFanOut(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	limiter := limiters.NewReservoirLimiter(1, interval)
+	var (
+		g      errgroup.Group
+		mutex  sync.Mutex
+		starts []time.Time
+	)
+	for i := 0; i < 5; i++ {
+		errgrouplimiter.Go(context.Background(), limiter, &g, func() error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			starts = append(starts, time.Now())
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(starts) != 5 {
+		t.Fatalf("expected 5 tasks to run, got %d", len(starts))
+	}
+	if elapsed := starts[len(starts)-1].Sub(starts[0]); elapsed < 4*interval*3/4 {
+		t.Fatalf("expected tasks to be paced by the limiter, all ran within %v", elapsed)
+	}
+}
+
+func TestGoReturnsLimiterError(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var g errgroup.Group
+	ran := false
+	errgrouplimiter.Go(ctx, limiter, &g, func() error {
+		ran = true
+		return nil
+	})
+	if err := g.Wait(); !errors.Is(err, limiters.ErrCanceled) {
+		t.Fatalf("expected ErrCanceled, got %v", err)
+	}
+	if ran {
+		t.Fatal("expected the task not to run")
+	}
+}
@@ -0,0 +1,111 @@
+package limiters
+
+import (
+	"context"
+	"errors"
+)
+
+// Struct implementing the Limiter interface.
+type fallbackLimiter struct {
+	primary        Limiter
+	fallback       Limiter
+	shouldFallback func(error) bool
+	observer       Observer
+}
+
+// Creates a limiter passing calls on to the primary limiter, and retrying
+// them on the fallback limiter when the primary fails with an error for which
+// shouldFallback returns true, e.g. when its backend cannot be reached.
+//
+// If shouldFallback is nil, calls fall back on every error not caused by their
+// context. The observer set with WithObserver is notified of the calls
+// admitted and rejected; if it implements FallbackObserver, it is also told
+// which limiter admitted each call.
+//
+// TryLimit and Allow cannot tell a failure of the primary from a rejection:
+// they never fall back.
+func NewFallbackLimiter(primary, fallback Limiter, shouldFallback func(error) bool, opts ...Option) Limiter {
+	o := newOptions(opts)
+	if shouldFallback == nil {
+		shouldFallback = notContextError
+	}
+	return &fallbackLimiter{
+		primary:        primary,
+		fallback:       fallback,
+		shouldFallback: shouldFallback,
+		observer:       o.observer,
+	}
+}
+
+// Implemented by observers of a fallback limiter, to know which of its
+// limiters admitted each call.
+type FallbackObserver interface {
+	// Called when a call is admitted, before OnGrant, with fallback set if the
+	// fallback limiter admitted it.
+	OnServed(fallback bool)
+}
+
+// Blocks until a token is available or the context is canceled.
+func (l *fallbackLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until n tokens are available or the context is canceled, on the
+// fallback limiter if the primary failed.
+func (l *fallbackLimiter) LimitN(ctx context.Context, n int) error {
+	err := l.primary.LimitN(ctx, n)
+	if err == nil {
+		l.served(false)
+		return nil
+	}
+	if !l.shouldFallback(err) {
+		l.rejected()
+		return err
+	}
+	if err := l.fallback.LimitN(ctx, n); err != nil {
+		l.rejected()
+		return err
+	}
+	l.served(true)
+	return nil
+}
+
+// Consumes a token from the primary limiter if one is immediately available,
+// without blocking.
+func (l *fallbackLimiter) TryLimit() bool {
+	if !l.primary.TryLimit() {
+		l.rejected()
+		return false
+	}
+	l.served(false)
+	return true
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *fallbackLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Notifies the observer, if any, that a call was admitted.
+func (l *fallbackLimiter) served(fallback bool) {
+	if l.observer == nil {
+		return
+	}
+	if o, ok := l.observer.(FallbackObserver); ok {
+		o.OnServed(fallback)
+	}
+	l.observer.OnGrant()
+}
+
+// Notifies the observer, if any, that a call was rejected.
+func (l *fallbackLimiter) rejected() {
+	if l.observer != nil {
+		l.observer.OnReject()
+	}
+}
+
+// Reports whether err was not caused by a canceled context.
+func notContextError(err error) bool {
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) &&
+		!errors.Is(err, ErrCanceled) && !errors.Is(err, ErrDeadlineExceeded)
+}
@@ -0,0 +1,85 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+var errBackendDown = errors.New("backend down")
+
+// Observer recording which limiter admitted each call.
+type servedObserver struct {
+	countingObserver
+	served []bool
+}
+
+func (o *servedObserver) OnServed(fallback bool) { o.served = append(o.served, fallback) }
+
+func TestFallbackLimiterFailover(t *testing.T) {
+	primary := limiterstest.NewFakeLimiter()
+	primary.QueueResults(nil, errBackendDown, errBackendDown)
+	fallback := limiters.NewReservoirLimiter(1, time.Hour)
+	observer := &servedObserver{}
+	limiter := limiters.NewFallbackLimiter(primary, fallback, func(err error) bool {
+		return errors.Is(err, errBackendDown)
+	}, limiters.WithObserver(observer))
+
+	for i := 0; i < 2; i++ {
+		if err := limiter.Limit(context.Background()); err != nil {
+			t.Fatalf("expected call %d to be admitted, got %v", i, err)
+		}
+	}
+	if got := fallback.(limiters.TokenCounter).Available(); got != 0 {
+		t.Fatalf("expected the fallback to serve the second call, got %d tokens left", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Limit(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the fallback's deadline error, got %v", err)
+	}
+	if primary.Calls() != 3 {
+		t.Fatalf("expected every call to try the primary first, got %d calls", primary.Calls())
+	}
+	if len(observer.served) != 2 || observer.served[0] || !observer.served[1] {
+		t.Fatalf("expected the primary then the fallback to be reported, got %v", observer.served)
+	}
+	if observer.grants.Load() != 2 || observer.rejects.Load() != 1 {
+		t.Fatalf("expected 2 grants and 1 reject, got %d and %d", observer.grants.Load(), observer.rejects.Load())
+	}
+}
+
+func TestFallbackLimiterKeepsOtherErrors(t *testing.T) {
+	primary := limiterstest.NewFakeLimiter()
+	primary.QueueResults(limiters.ErrExceedsCapacity, errBackendDown)
+	fallback := limiterstest.NewFakeLimiter()
+	limiter := limiters.NewFallbackLimiter(primary, fallback, func(err error) bool {
+		return errors.Is(err, errBackendDown)
+	})
+
+	if err := limiter.Limit(context.Background()); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected the primary's error, got %v", err)
+	}
+	if fallback.Calls() != 0 {
+		t.Fatalf("expected the fallback not to be called, got %d calls", fallback.Calls())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	primary.QueueResults(context.Canceled)
+	limiter = limiters.NewFallbackLimiter(primary, fallback, nil)
+	if err := limiter.Limit(ctx); err != nil {
+		t.Fatalf("expected the backend error to fall back by default, got %v", err)
+	}
+	if err := limiter.Limit(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context errors not to fall back by default, got %v", err)
+	}
+	if fallback.Calls() != 1 {
+		t.Fatalf("expected 1 call to the fallback, got %d", fallback.Calls())
+	}
+}
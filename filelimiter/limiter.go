@@ -0,0 +1,206 @@
+// Package filelimiter provides a limiter sharing its budget between the
+// processes of a machine through a file.
+package filelimiter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+// Smallest delay between two attempts.
+const minBackoff = time.Millisecond
+
+// Struct implementing the Limiter interface.
+type fileLimiter struct {
+	path           string
+	maxTokens      int
+	refillDuration time.Duration
+	backoff        limiters.Backoff
+}
+
+// Configures a file limiter at construction.
+type Option func(*fileLimiter)
+
+// Sets the policy deciding how long to sleep between two attempts, when the
+// file is locked by another process or tokens are missing, the estimated wait
+// being a lower bound.
+//
+// By default, the sleep doubles from a millisecond up to the refill duration.
+func WithBackoff(b limiters.Backoff) Option {
+	return func(l *fileLimiter) {
+		l.backoff = b
+	}
+}
+
+// Creates a new limiter whose reservoir is stored in the file at path.
+//
+// All limiters sharing a path share the same budget, whichever process they
+// run in. The state is guarded by an OS lock on a sibling file, path with a
+// ".lock" suffix, which the OS releases when its holder exits, even if it
+// crashed: a lock is never left stale. The state is replaced atomically, so
+// that a crash leaves it whole, and a missing or unreadable state starts
+// full. Tokens are refilled on the wall clock of the machine.
+//
+// Locking files is only supported on Unix systems: elsewhere, calls fail with
+// errors.ErrUnsupported. Panics if maxTokens or refillDuration is not
+// positive.
+func NewFileLimiter(path string, maxTokens int, refillDuration time.Duration, opts ...Option) limiters.Limiter {
+	switch {
+	case maxTokens <= 0:
+		panic(fmt.Errorf("%w: max tokens %d is not positive", limiters.ErrInvalidConfig, maxTokens))
+	case refillDuration <= 0:
+		panic(fmt.Errorf("%w: refill duration %v is not positive", limiters.ErrInvalidConfig, refillDuration))
+	}
+	l := &fileLimiter{
+		path:           path,
+		maxTokens:      maxTokens,
+		refillDuration: refillDuration,
+		backoff:        limiters.ExponentialBackoff{Initial: minBackoff, Max: refillDuration},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Blocks until a token is available or the context is canceled.
+func (l *fileLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until n tokens are available or the context is canceled.
+//
+// The limiter polls the file, sleeping between attempts for the estimated
+// wait or the delay given by the backoff policy, whichever is longer. The
+// file is only locked while taking the tokens, not while waiting for them.
+func (l *fileLimiter) LimitN(ctx context.Context, n int) error {
+	if n > l.maxTokens {
+		return limiters.ErrExceedsCapacity
+	}
+	for attempt := 0; ; attempt++ {
+		wait, err := l.take(n)
+		if err != nil && !errors.Is(err, errLocked) {
+			return err
+		}
+		if err == nil && wait == 0 {
+			return nil
+		}
+		timer := time.NewTimer(max(wait, l.backoff.Next(attempt)))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return waitError(ctx)
+		}
+	}
+}
+
+// Takes a token if one is immediately available, without blocking.
+//
+// Returns false if no token is available, the file is locked by another
+// process, or it cannot be accessed.
+func (l *fileLimiter) TryLimit() bool {
+	wait, err := l.take(1)
+	return err == nil && wait == 0
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *fileLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Takes n tokens under the file lock, returning the estimated wait if there
+// are not enough of them.
+//
+// Fails with errLocked if another process holds the lock.
+func (l *fileLimiter) take(n int) (time.Duration, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	unlock, err := lockFile(l.path + ".lock")
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	now := time.Now()
+	state := l.readState(now)
+	if state.Tokens >= l.maxTokens {
+		state.Tokens = l.maxTokens
+		state.LastRefill = now
+	} else if refilled := int(now.Sub(state.LastRefill) / l.refillDuration); refilled > 0 {
+		state.Tokens = min(l.maxTokens, state.Tokens+refilled)
+		state.LastRefill = state.LastRefill.Add(time.Duration(refilled) * l.refillDuration)
+	}
+	if state.Tokens < n {
+		return max(time.Duration(n-state.Tokens)*l.refillDuration-now.Sub(state.LastRefill), 0), nil
+	}
+	state.Tokens -= n
+	return 0, l.writeState(state)
+}
+
+// Reads the state of the reservoir, full if the file is missing or
+// unreadable.
+//
+// Must be called with the file locked.
+func (l *fileLimiter) readState(now time.Time) limiters.LimiterState {
+	full := limiters.LimiterState{Tokens: l.maxTokens, LastRefill: now}
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return full
+	}
+	var state limiters.LimiterState
+	if json.Unmarshal(data, &state) != nil || state.Tokens < 0 {
+		return full
+	}
+	if state.LastRefill.After(now) {
+		// The clock went back: refill from now on.
+		state.LastRefill = now
+	}
+	return state
+}
+
+// Replaces the state of the reservoir at once, through a temporary file.
+//
+// Must be called with the file locked.
+func (l *fileLimiter) writeState(state limiters.LimiterState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("filelimiter: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("filelimiter: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("filelimiter: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("filelimiter: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return fmt.Errorf("filelimiter: %w", err)
+	}
+	return nil
+}
+
+// Returns the error of a canceled context, wrapped like the errors of the
+// limiters package.
+func waitError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", limiters.ErrDeadlineExceeded, ctx.Err())
+	}
+	return fmt.Errorf("%w: %w", limiters.ErrCanceled, ctx.Err())
+}
@@ -0,0 +1,237 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package filelimiter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/filelimiter"
+)
+
+// Environment variables running the test binary as a helper process.
+const (
+	helperEnv = "FILELIMITER_HELPER"
+	pathEnv   = "FILELIMITER_PATH"
+)
+
+func TestFileLimiterSharedBudget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.json")
+	first := filelimiter.NewFileLimiter(path, 3, time.Hour)
+	second := filelimiter.NewFileLimiter(path, 3, time.Hour)
+
+	if !first.TryLimit() || !second.TryLimit() || !first.TryLimit() {
+		t.Fatal("expected the first three calls to be admitted")
+	}
+	if second.TryLimit() {
+		t.Fatal("expected the shared budget to be exhausted")
+	}
+	other := filelimiter.NewFileLimiter(filepath.Join(t.TempDir(), "other.json"), 3, time.Hour)
+	if !other.TryLimit() {
+		t.Fatal("expected another file to have its own budget")
+	}
+}
+
+func TestFileLimiterInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.json")
+	for _, build := range []func(){
+		func() { filelimiter.NewFileLimiter(path, 0, time.Second) },
+		func() { filelimiter.NewFileLimiter(path, -1, time.Second) },
+		func() { filelimiter.NewFileLimiter(path, 1, 0) },
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, limiters.ErrInvalidConfig) {
+					t.Errorf("expected a panic with ErrInvalidConfig, got %v", err)
+				}
+			}()
+			build()
+		}()
+	}
+}
+
+func TestFileLimiterWaitsForRefill(t *testing.T) {
+	const refill = 30 * time.Millisecond
+	limiter := filelimiter.NewFileLimiter(filepath.Join(t.TempDir(), "api.json"), 1, refill)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Limit(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 2*refill-5*time.Millisecond {
+		t.Fatalf("expected to wait for two refills, waited %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := limiter.Limit(ctx); !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, limiters.ErrDeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+	if err := limiter.LimitN(context.Background(), 2); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity, got %v", err)
+	}
+}
+
+func TestFileLimiterUnreadableState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.json")
+	if err := os.WriteFile(path, []byte(`{"tokens": 1, "last_ref`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	limiter := filelimiter.NewFileLimiter(path, 2, time.Hour)
+	if !limiter.TryLimit() || !limiter.TryLimit() || limiter.TryLimit() {
+		t.Fatal("expected an unreadable state to start full")
+	}
+}
+
+// Fails the test unless some calls were admitted over elapsed, but no more
+// than the rate allows.
+func assertWithinRate(t *testing.T, granted, maxTokens int, refill, elapsed time.Duration) {
+	t.Helper()
+	if limit := maxTokens + int(elapsed/refill) + 1; granted == 0 || granted > limit {
+		t.Fatalf("expected between 1 and %d grants in %v, got %d", limit, elapsed, granted)
+	}
+}
+
+func TestFileLimiterGoroutines(t *testing.T) {
+	const (
+		maxTokens = 5
+		refill    = 20 * time.Millisecond
+	)
+	path := filepath.Join(t.TempDir(), "api.json")
+	var granted atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter := filelimiter.NewFileLimiter(path, maxTokens, refill)
+			for time.Since(start) < 200*time.Millisecond {
+				if limiter.TryLimit() {
+					granted.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assertWithinRate(t, int(granted.Load()), maxTokens, refill, time.Since(start))
+}
+
+// Runs the test binary as a helper process in the given mode, sharing path.
+func helperProcess(mode, path string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFileLimiterHelperProcess$")
+	cmd.Env = append(os.Environ(), helperEnv+"="+mode, pathEnv+"="+path)
+	return cmd
+}
+
+// Not a test: the body of the helper processes.
+func TestFileLimiterHelperProcess(t *testing.T) {
+	path := os.Getenv(pathEnv)
+	switch os.Getenv(helperEnv) {
+	case "count":
+		// Takes tokens for 300ms, printing how many.
+		limiter := filelimiter.NewFileLimiter(path, 5, 20*time.Millisecond)
+		granted := 0
+		for start := time.Now(); time.Since(start) < 300*time.Millisecond; {
+			if limiter.TryLimit() {
+				granted++
+			}
+		}
+		fmt.Println(granted)
+		os.Exit(0)
+	case "crash":
+		// Takes the lock, then dies holding it.
+		f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil || syscall.Flock(int(f.Fd()), syscall.LOCK_EX) != nil {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func TestFileLimiterSubprocesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.json")
+	var cmds []*exec.Cmd
+	var outputs []*strings.Builder
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		cmd := helperProcess("count", path)
+		out := &strings.Builder{}
+		cmd.Stdout = out
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+		outputs = append(outputs, out)
+	}
+	granted := 0
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("helper %d: %v", i, err)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(outputs[i].String()))
+		if err != nil {
+			t.Fatalf("helper %d: unexpected output %q", i, outputs[i].String())
+		}
+		granted += n
+	}
+	assertWithinRate(t, granted, 5, 20*time.Millisecond, time.Since(start))
+}
+
+func TestFileLimiterCrashedHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.json")
+	var exitErr *exec.ExitError
+	if err := helperProcess("crash", path).Run(); !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatalf("expected the helper to die holding the lock, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := filelimiter.NewFileLimiter(path, 1, time.Hour).Limit(ctx); err != nil {
+		t.Fatalf("expected the lock of the crashed holder to be released, got %v", err)
+	}
+}
+
+func TestFileLimiterWaitsForLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.json")
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		t.Fatal(err)
+	}
+	limiter := filelimiter.NewFileLimiter(path, 1, time.Hour)
+	if limiter.TryLimit() {
+		t.Fatal("expected TryLimit to fail while the file is locked")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- limiter.Limit(context.Background()) }()
+	time.Sleep(20 * time.Millisecond)
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the call to be admitted once the lock was released")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package filelimiter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned when another process holds the lock.
+var errLocked = errors.New("filelimiter: file locked by another process")
+
+// Fails: files cannot be locked on this system.
+func lockFile(path string) (unlock func(), err error) {
+	return nil, fmt.Errorf("filelimiter: locking %s: %w", path, errors.ErrUnsupported)
+}
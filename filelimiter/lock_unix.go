@@ -0,0 +1,33 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package filelimiter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Returned when another process holds the lock.
+var errLocked = errors.New("filelimiter: file locked by another process")
+
+// Takes an exclusive lock on the file at path, creating it if needed, without
+// blocking.
+//
+// The lock is released by the returned function, or by the OS if the process
+// exits first.
+func lockFile(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("filelimiter: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errLocked
+		}
+		return nil, fmt.Errorf("filelimiter: %w", err)
+	}
+	return func() { f.Close() }, nil
+}
@@ -0,0 +1,138 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Struct implementing the Limiter interface.
+type fixedWindowLimiter struct {
+	limit       int
+	window      time.Duration
+	clock       Clock
+	mutex       sync.Mutex
+	windowStart time.Time
+	count       int
+}
+
+// Creates a new fixed window limiter.
+//
+// At most limit calls are admitted per window. Windows follow each other
+// from the limiter's creation, or are aligned on the wall clock with
+// WithAlignedWindows. Panics if the limit is negative or the window is not
+// positive.
+func NewFixedWindowLimiter(limit int, window time.Duration, opts ...Option) Limiter {
+	switch {
+	case limit < 0:
+		panic(fmt.Errorf("%w: limit %d is negative", ErrInvalidConfig, limit))
+	case window <= 0:
+		panic(fmt.Errorf("%w: window %v is not positive", ErrInvalidConfig, window))
+	}
+	o := newOptions(opts)
+	now := o.clock.Now()
+	if o.alignWindows {
+		now = now.Truncate(window)
+	}
+	return &fixedWindowLimiter{
+		limit:       limit,
+		window:      window,
+		clock:       o.clock,
+		windowStart: now,
+	}
+}
+
+// Blocks until a call can be admitted or the context is canceled.
+func (l *fixedWindowLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until n calls can be admitted in the same window or the context is
+// canceled.
+func (l *fixedWindowLimiter) LimitN(ctx context.Context, n int) error {
+	if n > l.limit {
+		return ErrExceedsCapacity
+	}
+	for {
+		delay := l.admit(n)
+		if delay == 0 {
+			return nil
+		}
+		ticker := l.clock.NewTicker(delay)
+		select {
+		case <-ticker.C():
+			ticker.Stop()
+		case <-ctx.Done():
+			ticker.Stop()
+			return contextError(ctx)
+		}
+	}
+}
+
+// Admits a call if the current window allows it, without blocking.
+//
+// Returns false if the call would have to wait, in which case nothing was
+// recorded.
+func (l *fixedWindowLimiter) TryLimit() bool {
+	return l.admit(1) == 0
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *fixedWindowLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Returns the estimated time until a call would be admitted, zero if it
+// would be admitted right away.
+func (l *fixedWindowLimiter) EstimateDelay() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	l.roll(now)
+	if l.count < l.limit {
+		return 0
+	}
+	return l.windowStart.Add(l.window).Sub(now)
+}
+
+// Forgets n grants of the current window, which were not used.
+func (l *fixedWindowLimiter) ReturnTokens(n int) {
+	if n <= 0 {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.count = max(l.count-n, 0)
+}
+
+// Records n grants if the current window allows it.
+//
+// Returns zero on success and the time until the next window otherwise.
+func (l *fixedWindowLimiter) admit(n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	l.roll(now)
+	if l.count+n <= l.limit {
+		l.count += n
+		return 0
+	}
+	return l.windowStart.Add(l.window).Sub(now)
+}
+
+// Moves on to the window containing now, resetting the counter if the
+// current window is over.
+//
+// Must be called with the mutex held.
+func (l *fixedWindowLimiter) roll(now time.Time) {
+	elapsed := now.Sub(l.windowStart)
+	if elapsed < l.window {
+		return
+	}
+	l.windowStart = l.windowStart.Add(elapsed / l.window * l.window)
+	l.count = 0
+}
@@ -0,0 +1,102 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestFixedWindowLimiterReset(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewFixedWindowLimiter(3, time.Minute, limiters.WithClock(clock))
+
+	for i := 0; i < 3; i++ {
+		if !limiter.TryLimit() {
+			t.Fatalf("expected call %d to be admitted", i)
+		}
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected the window to be exhausted")
+	}
+	clock.Advance(time.Minute - time.Nanosecond)
+	if limiter.TryLimit() {
+		t.Fatal("expected the window to still be exhausted")
+	}
+	clock.Advance(time.Nanosecond)
+	for i := 0; i < 3; i++ {
+		if !limiter.TryLimit() {
+			t.Fatalf("expected call %d to be admitted in the new window", i)
+		}
+	}
+}
+
+func TestFixedWindowLimiterAligned(t *testing.T) {
+	clock := limiterstest.NewClock()
+	clock.Advance(40 * time.Second)
+	limiter := limiters.NewFixedWindowLimiter(1, time.Minute,
+		limiters.WithClock(clock),
+		limiters.WithAlignedWindows(),
+	)
+	estimator := limiter.(limiters.DelayEstimator)
+
+	if !limiter.TryLimit() {
+		t.Fatal("expected a call to be admitted")
+	}
+	if delay := estimator.EstimateDelay(); delay != 20*time.Second {
+		t.Fatalf("expected the window to end on the minute, got a delay of %v", delay)
+	}
+	clock.Advance(20 * time.Second)
+	if !limiter.TryLimit() {
+		t.Fatal("expected a call to be admitted on the minute")
+	}
+}
+
+func TestFixedWindowLimiterBlocksUntilNextWindow(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewFixedWindowLimiter(1, time.Minute, limiters.WithClock(clock))
+	if !limiter.TryLimit() {
+		t.Fatal("expected a call to be admitted")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- limiter.Limit(context.Background()) }()
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+	clock.Advance(time.Minute)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if active := clock.ActiveTickers(); active != 0 {
+		t.Fatalf("expected no active ticker, got %d", active)
+	}
+}
+
+func TestFixedWindowLimiterCancel(t *testing.T) {
+	limiter := limiters.NewFixedWindowLimiter(1, time.Hour)
+	if !limiter.TryLimit() {
+		t.Fatal("expected a call to be admitted")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Limit(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if err := limiter.LimitN(context.Background(), 2); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity, got %v", err)
+	}
+}
+
+func TestFixedWindowLimiterInvalid(t *testing.T) {
+	assertInvalidConfig(t, func() { limiters.NewFixedWindowLimiter(-1, time.Second) })
+	assertInvalidConfig(t, func() { limiters.NewFixedWindowLimiter(1, 0) })
+}
+
+func TestFixedWindowLimiterConformance(t *testing.T) {
+	profile := limiterstest.Profile{Burst: 3, Interval: time.Second, Refill: 3}
+	limiterstest.RunProfileConformanceTests(t, profile, func(clock limiters.Clock) limiters.Limiter {
+		return limiters.NewFixedWindowLimiter(3, time.Second, limiters.WithClock(clock))
+	})
+}
@@ -0,0 +1,123 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Struct implementing the Limiter interface.
+type gcraLimiter struct {
+	rate  time.Duration
+	burst int
+	clock Clock
+	mutex sync.Mutex
+	tat   time.Time
+}
+
+// Creates a new limiter based on the generic cell rate algorithm.
+//
+// Calls are admitted at one per rate on average, with bursts of up to burst
+// calls. The limiter only keeps track of the theoretical arrival time of the
+// next call. Of the options, only WithClock applies. Panics if the rate or the
+// burst is not positive.
+func NewGCRALimiter(rate time.Duration, burst int, opts ...Option) Limiter {
+	switch {
+	case rate <= 0:
+		panic(fmt.Errorf("%w: rate %v is not positive", ErrInvalidConfig, rate))
+	case burst <= 0:
+		panic(fmt.Errorf("%w: burst %d is not positive", ErrInvalidConfig, burst))
+	}
+	return &gcraLimiter{
+		rate:  rate,
+		burst: burst,
+		clock: newOptions(opts).clock,
+	}
+}
+
+// Blocks until the call conforms or the context is canceled.
+func (l *gcraLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until n calls conform at once or the context is canceled.
+func (l *gcraLimiter) LimitN(ctx context.Context, n int) error {
+	if n > l.burst {
+		return ErrExceedsCapacity
+	}
+	for {
+		delay := l.admit(n)
+		if delay == 0 {
+			return nil
+		}
+		ticker := l.clock.NewTicker(delay)
+		select {
+		case <-ticker.C():
+			ticker.Stop()
+		case <-ctx.Done():
+			ticker.Stop()
+			return contextError(ctx)
+		}
+	}
+}
+
+// Admits the call if it conforms, without blocking.
+//
+// Returns false if the call would have to wait, in which case nothing was
+// recorded.
+func (l *gcraLimiter) TryLimit() bool {
+	return l.admit(1) == 0
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *gcraLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Returns the estimated time until a call would conform, zero if it conforms
+// right away.
+func (l *gcraLimiter) EstimateDelay() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	tat := l.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	allowAt := tat.Add(time.Duration(1-l.burst) * l.rate)
+	return max(allowAt.Sub(now), 0)
+}
+
+// Moves the theoretical arrival time back by n unused calls.
+func (l *gcraLimiter) ReturnTokens(n int) {
+	if n <= 0 {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.tat = l.tat.Add(-time.Duration(n) * l.rate)
+}
+
+// Advances the theoretical arrival time by n calls if they conform.
+//
+// Returns zero on success and the time until they conform otherwise.
+func (l *gcraLimiter) admit(n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	tat := l.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	tat = tat.Add(time.Duration(n) * l.rate)
+	allowAt := tat.Add(-time.Duration(l.burst) * l.rate)
+	if allowAt.After(now) {
+		return allowAt.Sub(now)
+	}
+	l.tat = tat
+	return 0
+}
@@ -0,0 +1,65 @@
+package limiters_test
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestGCRALimiterThroughput(t *testing.T) {
+	const (
+		rate     = 2 * time.Millisecond
+		burst    = 5
+		duration = 300 * time.Millisecond
+	)
+	limiter := limiters.NewGCRALimiter(rate, burst)
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var (
+		granted atomic.Int64
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for limiter.Limit(ctx) == nil {
+				granted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	expected := float64(burst) + float64(duration/rate)
+	if deviation := math.Abs(float64(granted.Load())-expected) / expected; deviation > 0.05 {
+		t.Fatalf("granted %d calls, expected about %.0f", granted.Load(), expected)
+	}
+}
+
+func TestGCRALimiterTryLimit(t *testing.T) {
+	limiter := limiters.NewGCRALimiter(time.Hour, 2)
+	if !limiter.TryLimit() || !limiter.TryLimit() {
+		t.Fatal("expected the burst to be admitted")
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected the call after the burst to be rejected")
+	}
+}
+
+func TestGCRALimiterInvalid(t *testing.T) {
+	assertInvalidConfig(t, func() { limiters.NewGCRALimiter(0, 1) })
+	assertInvalidConfig(t, func() { limiters.NewGCRALimiter(time.Second, 0) })
+}
+
+func TestGCRALimiterConformance(t *testing.T) {
+	limiterstest.RunConformanceTests(t, func(clock limiters.Clock, burst int, interval time.Duration) limiters.Limiter {
+		return limiters.NewGCRALimiter(interval, burst, limiters.WithClock(clock))
+	})
+}
@@ -0,0 +1,34 @@
+// Package grpclimiter provides gRPC integration for limiters.
+package grpclimiter
+
+import (
+	"context"
+	"errors"
+
+	"github.com/p-nordmann/limiters"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Creates a unary server interceptor admitting calls through the limiter.
+//
+// Calls are rejected with codes.ResourceExhausted when the limiter does not
+// admit them before their deadline, and with codes.Canceled when the client
+// cancels them while waiting.
+func UnaryServerInterceptor(l limiters.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.Limit(ctx); err != nil {
+			return nil, rejection(err)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Converts a limiter error to a gRPC status.
+func rejection(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded: %v", err)
+}
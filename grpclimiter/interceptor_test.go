@@ -0,0 +1,66 @@
+package grpclimiter_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/grpclimiter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Limiter failing right away instead of waiting for a token.
+type failFastLimiter struct {
+	limiters.Limiter
+}
+
+func (l failFastLimiter) Limit(ctx context.Context) error {
+	if !l.TryLimit() {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	limiter := failFastLimiter{limiters.NewReservoirLimiter(2, time.Hour)}
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.UnaryInterceptor(grpclimiter.UnaryServerInterceptor(limiter)))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	check := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := check(); err != nil {
+			t.Fatalf("expected call %d to be admitted, got %v", i, err)
+		}
+	}
+	if err := check(); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected the call over the limit to be rejected, got %v", err)
+	}
+}
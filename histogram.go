@@ -0,0 +1,122 @@
+package limiters
+
+import (
+	"math/bits"
+	"time"
+)
+
+// Number of sub-buckets per power of two, as a power of two.
+const histogramPrecision = 2
+
+// Number of buckets, covering durations up to 2^40µs, about 12 days.
+const histogramBuckets = (40 + 1) << histogramPrecision
+
+// Histogram of durations in fixed, log-linear buckets of microseconds.
+//
+// Each power of two is split into 4 buckets, so that quantiles are estimated
+// within about 12% whatever the scale, in constant memory.
+type histogram struct {
+	counts [histogramBuckets]uint64
+	total  uint64
+}
+
+// Counts a duration.
+func (h *histogram) record(d time.Duration) {
+	h.counts[bucketOf(d)]++
+	h.total++
+}
+
+// Adds the counts of another histogram.
+func (h *histogram) merge(other *histogram) {
+	for i, count := range other.counts {
+		h.counts[i] += count
+	}
+	h.total += other.total
+}
+
+// Returns the estimated q-quantile of the recorded durations, zero if none
+// were recorded.
+func (h *histogram) quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := min(max(q, 0), 1) * float64(h.total)
+	seen := 0.0
+	for i, count := range h.counts {
+		if count == 0 {
+			continue
+		}
+		if seen+float64(count) >= rank {
+			lower, upper := bucketBounds(i)
+			fraction := (rank - seen) / float64(count)
+			return time.Duration(float64(lower)+fraction*float64(upper-lower)) * time.Microsecond
+		}
+		seen += float64(count)
+	}
+	_, upper := bucketBounds(histogramBuckets - 1)
+	return time.Duration(upper) * time.Microsecond
+}
+
+// Returns the bucket of a duration.
+func bucketOf(d time.Duration) int {
+	v := uint64(max(d/time.Microsecond, 0))
+	if v < 1<<histogramPrecision {
+		return int(v)
+	}
+	e := bits.Len64(v) - 1
+	sub := int(v>>(e-histogramPrecision)) & (1<<histogramPrecision - 1)
+	return min((e-histogramPrecision+1)<<histogramPrecision+sub, histogramBuckets-1)
+}
+
+// Returns the bounds of a bucket in microseconds, lower inclusive and upper
+// exclusive.
+func bucketBounds(i int) (lower, upper uint64) {
+	if i < 1<<histogramPrecision {
+		return uint64(i), uint64(i) + 1
+	}
+	shift := i>>histogramPrecision - 1
+	sub := uint64(i & (1<<histogramPrecision - 1))
+	lower = (1<<histogramPrecision + sub) << shift
+	return lower, lower + 1<<shift
+}
+
+// Period after which a rolling histogram starts a new generation.
+const histogramWindow = time.Minute
+
+// Histogram forgetting old durations: it covers the current and the previous
+// generation, each lasting histogramWindow.
+type rollingHistogram struct {
+	current  histogram
+	previous histogram
+	start    time.Time
+}
+
+// Counts a duration observed at now.
+func (r *rollingHistogram) record(d time.Duration, now time.Time) {
+	r.rotate(now)
+	r.current.record(d)
+}
+
+// Returns the estimated q-quantile of the durations of the last one to two
+// windows.
+func (r *rollingHistogram) quantile(q float64, now time.Time) time.Duration {
+	r.rotate(now)
+	merged := r.current
+	merged.merge(&r.previous)
+	return merged.quantile(q)
+}
+
+// Starts new generations as windows elapse.
+func (r *rollingHistogram) rotate(now time.Time) {
+	elapsed := now.Sub(r.start)
+	if elapsed < histogramWindow {
+		return
+	}
+	if elapsed < 2*histogramWindow {
+		r.previous = r.current
+	} else {
+		r.previous = histogram{}
+	}
+	r.current = histogram{}
+	r.start = now
+}
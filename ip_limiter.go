@@ -0,0 +1,112 @@
+package limiters
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter applying an independent reservoir limiter per client IP.
+//
+// Limiters of clients idle for longer than the TTL are evicted as requests
+// come in, without a background goroutine.
+type IPLimiter struct {
+	keyed        *KeyedLimiter
+	ttl          time.Duration
+	forwardedFor bool
+	clock        Clock
+	mutex        sync.Mutex
+	lastEviction time.Time
+}
+
+// Creates a new IP limiter, giving each client a reservoir of maxTokens
+// tokens refilled every refillDuration, forgotten once idle for ttl.
+//
+// The options apply to the limiter of each client. Panics if they or the ttl
+// are invalid.
+func NewIPLimiter(maxTokens int, refillDuration, ttl time.Duration, opts ...Option) *IPLimiter {
+	o := newOptions(opts)
+	if err := checkReservoirConfig(maxTokens, refillDuration, o); err != nil {
+		panic(err)
+	}
+	if ttl <= 0 {
+		panic(fmt.Errorf("%w: ttl %v is not positive", ErrInvalidConfig, ttl))
+	}
+	return &IPLimiter{
+		keyed: NewKeyedLimiter(func(string) Limiter {
+			return NewReservoirLimiter(maxTokens, refillDuration, opts...)
+		}, opts...),
+		ttl:          ttl,
+		forwardedFor: o.forwardedFor,
+		clock:        o.clock,
+		lastEviction: o.clock.Now(),
+	}
+}
+
+// Blocks until the limiter of the client admits the request or the request
+// context is canceled.
+func (l *IPLimiter) LimitRequest(r *http.Request) error {
+	l.evictIdle()
+	return l.keyed.LimitKey(r.Context(), l.clientIP(r))
+}
+
+// Consumes a token from the limiter of the client if one is immediately
+// available, without blocking.
+func (l *IPLimiter) TryLimitRequest(r *http.Request) bool {
+	l.evictIdle()
+	return l.keyed.TryLimitKey(l.clientIP(r))
+}
+
+// Returns the number of clients currently tracked.
+func (l *IPLimiter) Len() int {
+	return l.keyed.Len()
+}
+
+// Evicts idle clients, at most once per TTL.
+func (l *IPLimiter) evictIdle() {
+	l.mutex.Lock()
+	now := l.clock.Now()
+	due := now.Sub(l.lastEviction) >= l.ttl
+	if due {
+		l.lastEviction = now
+	}
+	l.mutex.Unlock()
+	if due {
+		l.keyed.EvictIdle(l.ttl)
+	}
+}
+
+// Returns the IP of the client sending the request.
+//
+// With X-Forwarded-For, the last valid address is used: it was added by the
+// closest proxy, while the others may be forged by the client. Falls back to
+// the remote address of the request.
+func (l *IPLimiter) clientIP(r *http.Request) string {
+	if l.forwardedFor {
+		hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			if addr, err := netip.ParseAddr(strings.TrimSpace(hops[i])); err == nil {
+				return addr.Unmap().WithZone("").String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return normalizeIP(host)
+}
+
+// Returns the canonical form of an IP, so that the different spellings of an
+// address share a limiter. Invalid IPs are returned as is.
+func normalizeIP(ip string) string {
+	addr, err := netip.ParseAddr(strings.Trim(ip, "[]"))
+	if err != nil {
+		return ip
+	}
+	return addr.Unmap().WithZone("").String()
+}
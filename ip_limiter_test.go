@@ -0,0 +1,137 @@
+package limiters_test
+
+import (
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestIPLimiterPerClient(t *testing.T) {
+	limiter := limiters.NewIPLimiter(1, time.Hour, time.Hour, limiters.WithClock(limiterstest.NewClock()))
+	request := func(remoteAddr string) bool {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remoteAddr
+		return limiter.TryLimitRequest(r)
+	}
+
+	if !request("198.51.100.1:1234") {
+		t.Fatal("expected the first request of a client to be admitted")
+	}
+	if request("198.51.100.1:5678") {
+		t.Fatal("expected the client to be limited whatever its port")
+	}
+	if err := limiter.LimitRequest(httptest.NewRequest("GET", "/", nil)); err != nil {
+		t.Fatalf("expected another client to be admitted, got %v", err)
+	}
+	if limiter.Len() != 2 {
+		t.Fatalf("expected 2 clients, got %d", limiter.Len())
+	}
+}
+
+func TestIPLimiterClientIP(t *testing.T) {
+	tests := []struct {
+		name          string
+		forwardedFor  bool
+		remoteAddr    string
+		header        []string
+		sameAsAddr    string
+		sameAsHeader  []string
+		differentAddr string
+	}{
+		{name: "ipv6 ports", remoteAddr: "[2001:db8::1]:1234", sameAsAddr: "[2001:db8:0::1]:80", differentAddr: "[2001:db8::2]:1234"},
+		{name: "ipv6 zone", remoteAddr: "[fe80::1%eth0]:1234", sameAsAddr: "[fe80::1%eth1]:80", differentAddr: "[fe80::2]:80"},
+		{name: "ipv4 mapped", remoteAddr: "[::ffff:192.0.2.1]:1234", sameAsAddr: "192.0.2.1:80", differentAddr: "192.0.2.2:80"},
+		{name: "no port", remoteAddr: "192.0.2.1", sameAsAddr: "192.0.2.1:80", differentAddr: "192.0.2.2"},
+		{name: "header ignored", remoteAddr: "192.0.2.1:1", header: []string{"198.51.100.1"}, sameAsAddr: "192.0.2.1:2", differentAddr: "198.51.100.1:1"},
+		{name: "closest proxy", forwardedFor: true, remoteAddr: "10.0.0.1:1", header: []string{"203.0.113.9, 198.51.100.1"}, sameAsHeader: []string{"198.51.100.1"}, differentAddr: "10.0.0.1:1"},
+		{name: "several headers", forwardedFor: true, remoteAddr: "10.0.0.1:1", header: []string{"203.0.113.9", "2001:db8::1"}, sameAsHeader: []string{"2001:db8:0::1"}, differentAddr: "10.0.0.1:1"},
+		{name: "invalid header", forwardedFor: true, remoteAddr: "10.0.0.1:1", header: []string{"unknown, garbage"}, sameAsAddr: "10.0.0.1:2", differentAddr: "10.0.0.2:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []limiters.Option
+			if tt.forwardedFor {
+				opts = append(opts, limiters.WithForwardedFor())
+			}
+			limiter := limiters.NewIPLimiter(1, time.Hour, time.Hour, opts...)
+			request := func(remoteAddr string, header []string) bool {
+				r := httptest.NewRequest("GET", "/", nil)
+				r.RemoteAddr = remoteAddr
+				for _, value := range header {
+					r.Header.Add("X-Forwarded-For", value)
+				}
+				return limiter.TryLimitRequest(r)
+			}
+
+			if !request(tt.remoteAddr, tt.header) {
+				t.Fatal("expected the first request to be admitted")
+			}
+			if tt.sameAsAddr != "" && request(tt.sameAsAddr, nil) {
+				t.Fatalf("expected %s to be the same client", tt.sameAsAddr)
+			}
+			if tt.sameAsHeader != nil && request("10.0.0.3:1", tt.sameAsHeader) {
+				t.Fatalf("expected %v to be the same client", tt.sameAsHeader)
+			}
+			if !request(tt.differentAddr, nil) {
+				t.Fatalf("expected %s to be another client", tt.differentAddr)
+			}
+		})
+	}
+}
+
+func TestIPLimiterEviction(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewIPLimiter(1, time.Hour, time.Minute, limiters.WithClock(clock))
+	request := func(remoteAddr string) bool {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remoteAddr
+		return limiter.TryLimitRequest(r)
+	}
+
+	request("192.0.2.1:1")
+	clock.Advance(30 * time.Second)
+	request("192.0.2.2:1")
+	clock.Advance(30 * time.Second)
+	request("192.0.2.3:1")
+	if limiter.Len() != 2 {
+		t.Fatalf("expected the idle client to be evicted, got %d clients", limiter.Len())
+	}
+	if !request("192.0.2.1:1") {
+		t.Fatal("expected an evicted client to start afresh")
+	}
+	if request("192.0.2.2:1") {
+		t.Fatal("expected a recent client to be kept")
+	}
+}
+
+func TestIPLimiterInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	limiters.NewIPLimiter(1, 0, time.Minute)
+}
+
+func TestIPLimiterInvalidTTL(t *testing.T) {
+	assertInvalidConfig(t, func() { limiters.NewIPLimiter(1, time.Second, 0) })
+	assertInvalidConfig(t, func() { limiters.NewIPLimiter(1, time.Second, -time.Minute) })
+}
+
+func TestIPLimiterCreatesNoLimiterUpfront(t *testing.T) {
+	registry := &limiters.Registry{}
+	baseline := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		limiters.NewIPLimiter(1, time.Second, time.Minute, limiters.WithRegistry(registry), limiters.WithAlwaysOn())
+	}
+	if got := registry.List(); len(got) != 0 {
+		t.Fatalf("expected no limiter before the first request, got %d registered", len(got))
+	}
+	if extra := runtime.NumGoroutine() - baseline; extra > 0 {
+		t.Fatalf("expected no refill goroutine before the first request, got %d", extra)
+	}
+}
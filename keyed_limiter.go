@@ -0,0 +1,104 @@
+package limiters
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter applying independent limits per key.
+//
+// Limiters are created lazily by a factory the first time a key is used, and
+// can be evicted once idle with EvictIdle.
+type KeyedLimiter struct {
+	factory func(key string) Limiter
+	clock   Clock
+	mutex   sync.Mutex
+	entries map[string]*keyedEntry
+}
+
+// Limiter of a single key.
+type keyedEntry struct {
+	limiter  Limiter
+	active   int
+	lastUsed time.Time
+}
+
+// Creates a new keyed limiter, building the limiter of each key with factory.
+func NewKeyedLimiter(factory func(key string) Limiter, opts ...Option) *KeyedLimiter {
+	o := newOptions(opts)
+	return &KeyedLimiter{
+		factory: factory,
+		clock:   o.clock,
+		entries: make(map[string]*keyedEntry),
+	}
+}
+
+// Blocks until the limiter of the key admits the call or the context is
+// canceled.
+func (k *KeyedLimiter) LimitKey(ctx context.Context, key string) error {
+	entry := k.acquire(key)
+	defer k.release(entry)
+	return entry.limiter.Limit(ctx)
+}
+
+// Consumes a token from the limiter of the key if one is immediately
+// available, without blocking.
+func (k *KeyedLimiter) TryLimitKey(key string) bool {
+	entry := k.acquire(key)
+	defer k.release(entry)
+	return entry.limiter.TryLimit()
+}
+
+// Removes the limiters of keys unused for at least d, closing those
+// implementing io.Closer.
+//
+// Keys with pending calls are never evicted. Returns the number of evicted
+// keys.
+func (k *KeyedLimiter) EvictIdle(d time.Duration) int {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	now := k.clock.Now()
+	evicted := 0
+	for key, entry := range k.entries {
+		if entry.active > 0 || now.Sub(entry.lastUsed) < d {
+			continue
+		}
+		delete(k.entries, key)
+		if closer, ok := entry.limiter.(io.Closer); ok {
+			closer.Close()
+		}
+		evicted++
+	}
+	return evicted
+}
+
+// Returns the number of keys currently tracked.
+func (k *KeyedLimiter) Len() int {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return len(k.entries)
+}
+
+// Returns the entry of the key, creating it if needed, and marks it in use.
+func (k *KeyedLimiter) acquire(key string) *keyedEntry {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	entry, ok := k.entries[key]
+	if !ok {
+		entry = &keyedEntry{limiter: k.factory(key)}
+		k.entries[key] = entry
+	}
+	entry.active++
+	entry.lastUsed = k.clock.Now()
+	return entry
+}
+
+// Marks the entry as no longer in use.
+func (k *KeyedLimiter) release(entry *keyedEntry) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	entry.active--
+	entry.lastUsed = k.clock.Now()
+}
@@ -0,0 +1,92 @@
+package limiters_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestKeyedLimiterIndependentKeys(t *testing.T) {
+	keyed := limiters.NewKeyedLimiter(func(string) limiters.Limiter {
+		return limiters.NewReservoirLimiter(1, time.Hour)
+	})
+	if !keyed.TryLimitKey("a") {
+		t.Fatal("expected a token for key a")
+	}
+	if keyed.TryLimitKey("a") {
+		t.Fatal("expected key a to be exhausted")
+	}
+	if err := keyed.LimitKey(context.Background(), "b"); err != nil {
+		t.Fatalf("expected key b to be unaffected by key a, got %v", err)
+	}
+}
+
+func TestKeyedLimiterEvictIdle(t *testing.T) {
+	clock := limiterstest.NewClock()
+	created := 0
+	keyed := limiters.NewKeyedLimiter(func(string) limiters.Limiter {
+		created++
+		return limiters.NewReservoirLimiter(1, time.Hour)
+	}, limiters.WithClock(clock))
+
+	keyed.TryLimitKey("old")
+	clock.Advance(time.Minute)
+	keyed.TryLimitKey("recent")
+
+	if evicted := keyed.EvictIdle(time.Minute); evicted != 1 {
+		t.Fatalf("expected 1 evicted key, got %d", evicted)
+	}
+	if keyed.Len() != 1 {
+		t.Fatalf("expected 1 remaining key, got %d", keyed.Len())
+	}
+	if !keyed.TryLimitKey("old") {
+		t.Fatal("expected a fresh limiter for an evicted key")
+	}
+	if created != 3 {
+		t.Fatalf("expected 3 limiters to be created, got %d", created)
+	}
+}
+
+func TestKeyedLimiterKeepsActiveKeys(t *testing.T) {
+	clock := limiterstest.NewClock()
+	keyed := limiters.NewKeyedLimiter(func(string) limiters.Limiter {
+		return limiters.NewReservoirLimiter(1, time.Hour)
+	}, limiters.WithClock(clock))
+	keyed.TryLimitKey("busy")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- keyed.LimitKey(ctx, "busy") }()
+	time.Sleep(10 * time.Millisecond)
+
+	clock.Advance(time.Minute)
+	if evicted := keyed.EvictIdle(time.Second); evicted != 0 {
+		t.Fatalf("expected the busy key to be kept, got %d evictions", evicted)
+	}
+	cancel()
+	<-done
+}
+
+func TestKeyedLimiterConcurrent(t *testing.T) {
+	keyed := limiters.NewKeyedLimiter(func(string) limiters.Limiter {
+		return limiters.NewReservoirLimiter(100, time.Millisecond)
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprint(i % 5)
+			if err := keyed.LimitKey(context.Background(), key); err != nil {
+				t.Error(err)
+			}
+			keyed.EvictIdle(0)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,165 @@
+package limiters
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Struct implementing the Limiter interface.
+type leakyBucketLimiter struct {
+	rate     time.Duration
+	clock    Clock
+	slots    chan struct{}
+	mutex    sync.Mutex
+	queue    list.List
+	nextLeak time.Time
+	leaking  bool
+}
+
+// Creates a new leaky bucket limiter.
+//
+// Calls are released one every rate, without bursts. At most capacity calls
+// can be queued at once: further calls block until there is room. Of the
+// options, only WithClock applies. Panics if the rate or the capacity is not
+// positive.
+func NewLeakyBucketLimiter(rate time.Duration, capacity int, opts ...Option) Limiter {
+	switch {
+	case rate <= 0:
+		panic(fmt.Errorf("%w: rate %v is not positive", ErrInvalidConfig, rate))
+	case capacity <= 0:
+		panic(fmt.Errorf("%w: capacity %d is not positive", ErrInvalidConfig, capacity))
+	}
+	return &leakyBucketLimiter{
+		rate:  rate,
+		clock: newOptions(opts).clock,
+		slots: make(chan struct{}, capacity),
+	}
+}
+
+// Blocks until the call leaks out of the bucket or the context is canceled.
+func (l *leakyBucketLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until the call leaks out of the bucket or the context is canceled.
+//
+// The call accounts for n releases: the next one happens n intervals later.
+func (l *leakyBucketLimiter) LimitN(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if err := contextError(ctx); err != nil {
+		return err
+	}
+	if l.TryLimitN(n) {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return contextError(ctx)
+	}
+	w := &waiter{n: n, ready: make(chan struct{})}
+	l.mutex.Lock()
+	elem := l.queue.PushBack(w)
+	if !l.leaking {
+		l.leaking = true
+		go l.leak()
+	}
+	l.mutex.Unlock()
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		select {
+		case <-w.ready:
+			// Released concurrently with the cancellation.
+		default:
+			l.queue.Remove(elem)
+			<-l.slots
+		}
+		return contextError(ctx)
+	}
+}
+
+// Releases the call if the bucket can leak right away, without blocking.
+//
+// Returns false if the call would have to wait.
+func (l *leakyBucketLimiter) TryLimit() bool {
+	return l.TryLimitN(1)
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *leakyBucketLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Releases a call accounting for n releases if the bucket can leak right
+// away, without blocking.
+func (l *leakyBucketLimiter) TryLimitN(n int) bool {
+	if n <= 0 {
+		return true
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	if l.queue.Len() > 0 || now.Before(l.nextLeak) {
+		return false
+	}
+	l.nextLeak = now.Add(time.Duration(n) * l.rate)
+	return true
+}
+
+// Returns the estimated time until a new call would leak out of the bucket,
+// zero if it would leak right away.
+func (l *leakyBucketLimiter) EstimateDelay() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	queued := 0
+	for elem := l.queue.Front(); elem != nil; elem = elem.Next() {
+		queued += elem.Value.(*waiter).n
+	}
+	leakAt := l.nextLeak.Add(time.Duration(queued) * l.rate)
+	return max(leakAt.Sub(l.clock.Now()), 0)
+}
+
+// Moves the next leak back by n unused releases.
+func (l *leakyBucketLimiter) ReturnTokens(n int) {
+	if n <= 0 {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.nextLeak = l.nextLeak.Add(-time.Duration(n) * l.rate)
+}
+
+// Releases queued calls at a steady pace until the queue is empty.
+func (l *leakyBucketLimiter) leak() {
+	l.mutex.Lock()
+	for l.queue.Len() > 0 {
+		delay := l.nextLeak.Sub(l.clock.Now())
+		l.mutex.Unlock()
+		if delay > 0 {
+			ticker := l.clock.NewTicker(delay)
+			<-ticker.C()
+			ticker.Stop()
+		}
+		l.mutex.Lock()
+		elem := l.queue.Front()
+		if elem == nil {
+			// The last waiter canceled while sleeping.
+			break
+		}
+		w := l.queue.Remove(elem).(*waiter)
+		l.nextLeak = l.clock.Now().Add(time.Duration(w.n) * l.rate)
+		close(w.ready)
+		<-l.slots
+	}
+	l.leaking = false
+	l.mutex.Unlock()
+}
@@ -0,0 +1,85 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestLeakyBucketLimiterSpacing(t *testing.T) {
+	const rate = 20 * time.Millisecond
+	limiter := limiters.NewLeakyBucketLimiter(rate, 10)
+
+	var (
+		mutex    sync.Mutex
+		releases []time.Time
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Limit(context.Background()); err != nil {
+				t.Error(err)
+				return
+			}
+			mutex.Lock()
+			releases = append(releases, time.Now())
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	slices.SortFunc(releases, func(a, b time.Time) int { return a.Compare(b) })
+	for i := 1; i < len(releases); i++ {
+		gap := releases[i].Sub(releases[i-1])
+		if gap < rate-2*time.Millisecond || gap > rate+15*time.Millisecond {
+			t.Errorf("release %d came %v after the previous one, expected about %v", i, gap, rate)
+		}
+	}
+}
+
+func TestLeakyBucketLimiterFullQueue(t *testing.T) {
+	limiter := limiters.NewLeakyBucketLimiter(time.Hour, 1)
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected TryLimit to fail before the next leak")
+	}
+
+	queued := make(chan error)
+	go func() { queued <- limiter.Limit(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Limit(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded with a full queue, got %v", err)
+	}
+	select {
+	case err := <-queued:
+		t.Fatalf("queued call returned early: %v", err)
+	default:
+	}
+}
+
+func TestLeakyBucketLimiterInvalid(t *testing.T) {
+	assertInvalidConfig(t, func() { limiters.NewLeakyBucketLimiter(0, 1) })
+	assertInvalidConfig(t, func() { limiters.NewLeakyBucketLimiter(time.Second, 0) })
+}
+
+func TestLeakyBucketLimiterConformance(t *testing.T) {
+	// Calls are released one at a time, without bursts, and a call taking
+	// several releases delays the next ones.
+	profile := limiterstest.Profile{Burst: 1, Interval: time.Second, Refill: 1, Oversized: true}
+	limiterstest.RunProfileConformanceTests(t, profile, func(clock limiters.Clock) limiters.Limiter {
+		return limiters.NewLeakyBucketLimiter(time.Second, 10, limiters.WithClock(clock))
+	})
+}
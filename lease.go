@@ -0,0 +1,65 @@
+package limiters
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Batch of tokens taken from a limiter at once, to be spent locally without
+// coordinating with the limiter on each call.
+//
+// A lease is safe for concurrent use. Once depleted, take a new one.
+type Lease struct {
+	limiter Limiter
+	// Whether the tokens were handed out without being taken from the
+	// limiter, e.g. while it was paused: they are not given back.
+	free      bool
+	remaining atomic.Int64
+}
+
+// Spends a leased token, without touching the limiter.
+//
+// Returns false once the lease is depleted or closed.
+func (l *Lease) TryUse() bool {
+	for {
+		remaining := l.remaining.Load()
+		if remaining <= 0 {
+			return false
+		}
+		if l.remaining.CompareAndSwap(remaining, remaining-1) {
+			return true
+		}
+	}
+}
+
+// Returns the number of leased tokens not spent yet.
+func (l *Lease) Remaining() int {
+	return int(l.remaining.Load())
+}
+
+// Gives the tokens not spent yet back to the limiter, if it implements
+// TokenReturner and they were taken from it, and depletes the lease.
+//
+// Close is idempotent.
+func (l *Lease) Close() {
+	if remaining := l.remaining.Swap(0); remaining > 0 && !l.free {
+		returnTokens([]Limiter{l.limiter}, int(remaining))
+	}
+}
+
+// Blocks until size tokens are available or the context is canceled, and
+// returns them as a lease to be spent locally.
+//
+// Leasing trades a little burstiness for less contention on the reservoir:
+// tokens spent from the lease are taken when it is granted, and those left are
+// given back when it is closed. A lease granted while the limiter is paused
+// holds free tokens, which are not given back.
+func (l *reservoirLimiter) Lease(ctx context.Context, size int) (*Lease, error) {
+	_, _, free, err := l.acquire(ctx, size, PriorityNormal, false, "")
+	if err != nil {
+		return nil, err
+	}
+	lease := &Lease{limiter: l, free: free}
+	lease.remaining.Store(int64(size))
+	return lease, nil
+}
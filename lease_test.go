@@ -0,0 +1,114 @@
+package limiters_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestLeaseSpendsLocally(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(10, time.Hour)
+	lease, err := limiter.(limiters.Leaser).Lease(context.Background(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if !lease.TryUse() {
+			t.Fatalf("expected leased token %d to be spent", i)
+		}
+	}
+	if got := limiter.(limiters.TokenCounter).Available(); got != 6 {
+		t.Fatalf("expected the lease to be taken at once, got %d tokens", got)
+	}
+
+	lease.Close()
+	lease.Close()
+	if lease.TryUse() {
+		t.Fatal("expected a closed lease to be depleted")
+	}
+	if got := limiter.(limiters.TokenCounter).Available(); got != 7 {
+		t.Fatalf("expected the unused token to be given back once, got %d tokens", got)
+	}
+}
+
+func TestLeaseGrantsWithinRate(t *testing.T) {
+	const (
+		maxTokens = 10
+		refill    = 100 * time.Millisecond
+		steps     = 50
+	)
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(maxTokens, refill, limiters.WithClock(clock))
+	leaser := limiter.(limiters.Leaser)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var granted atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				lease, err := leaser.Lease(ctx, 3)
+				if err != nil {
+					return
+				}
+				// Some workers stop early, giving the rest of their lease back.
+				for spent := 0; spent <= (w+i)%3 && lease.TryUse(); spent++ {
+					granted.Add(1)
+				}
+				lease.Close()
+			}
+		}()
+	}
+	for step := 0; step < steps; step++ {
+		time.Sleep(time.Millisecond)
+		clock.Advance(refill)
+	}
+	cancel()
+	wg.Wait()
+
+	if got, limit := granted.Load(), int64(maxTokens+steps); got == 0 || got > limit {
+		t.Fatalf("expected between 1 and %d grants over the window, got %d", limit, got)
+	}
+}
+
+func TestLeaseWhilePaused(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(10, time.Hour, limiters.WithInitialTokens(0))
+	limiter.(limiters.Pauser).Pause()
+	lease, err := limiter.(limiters.Leaser).Lease(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter.(limiters.Pauser).Resume()
+	lease.Close()
+	if got := limiter.(limiters.TokenCounter).Available(); got != 0 {
+		t.Fatalf("expected a lease granted while paused to give no token back, got %d tokens", got)
+	}
+}
+
+func TestLeaseReleasedByPause(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(10, time.Hour, limiters.WithInitialTokens(0))
+	reporter := limiter.(limiters.StatsReporter)
+	leases := make(chan *limiters.Lease)
+	go func() {
+		lease, err := limiter.(limiters.Leaser).Lease(context.Background(), 10)
+		if err != nil {
+			t.Error(err)
+		}
+		leases <- lease
+	}()
+	eventually(t, func() bool { return reporter.Stats().Waiting == 1 })
+	limiter.(limiters.Pauser).Pause()
+	lease := <-leases
+	limiter.(limiters.Pauser).Resume()
+	lease.Close()
+	if got := limiter.(limiters.TokenCounter).Available(); got != 0 {
+		t.Fatalf("expected a lease released by Pause to give no token back, got %d tokens", got)
+	}
+}
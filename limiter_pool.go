@@ -0,0 +1,193 @@
+package limiters
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Group of limiters sharing a single goroutine to refill their tokens.
+//
+// A reservoir limiter refills its waiters from a ticker of its own, running
+// in its own goroutine. Limiters created from a pool are refilled by the
+// pool instead, from one goroutine using a heap of next refill times. This
+// saves resources when many limiters have waiters at once, e.g. with one
+// limiter per key.
+type LimiterPool struct {
+	mutex   sync.Mutex
+	tasks   taskHeap
+	wake    chan struct{}
+	running bool
+}
+
+// Creates a new, empty limiter pool.
+func NewLimiterPool() *LimiterPool {
+	return &LimiterPool{wake: make(chan struct{}, 1)}
+}
+
+// Creates a new reservoir limiter refilled by the pool.
+//
+// The limiter reads the time from the time package: a clock set in the
+// options is ignored. Panics if the options are invalid.
+func (p *LimiterPool) NewReservoirLimiter(maxTokens int, refillDuration time.Duration, opts ...Option) Limiter {
+	return NewReservoirLimiter(maxTokens, refillDuration, append(opts[:len(opts):len(opts)], WithClock(poolClock{pool: p}))...)
+}
+
+// Returns the number of limiters or tickers currently refilled by the pool.
+func (p *LimiterPool) Active() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.tasks)
+}
+
+// Runs f after first, then every period, from the pool's goroutine, until
+// the returned function is called.
+func (p *LimiterPool) schedule(first, period time.Duration, f func()) (stop func()) {
+	t := &task{period: period, next: time.Now().Add(first), fire: f}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	heap.Push(&p.tasks, t)
+	if !p.running {
+		p.running = true
+		go p.run()
+	}
+	p.notify()
+	return func() { p.remove(t) }
+}
+
+// Removes a task from the pool.
+func (p *LimiterPool) remove(t *task) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if t.index < 0 {
+		return
+	}
+	heap.Remove(&p.tasks, t.index)
+	p.notify()
+}
+
+// Wakes the pool's goroutine up to look at the next task again.
+//
+// Must be called with the mutex held.
+func (p *LimiterPool) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Runs the tasks as they are due, until there are none left.
+func (p *LimiterPool) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	var due []func()
+	for {
+		p.mutex.Lock()
+		now := time.Now()
+		due = due[:0]
+		for len(p.tasks) > 0 && !p.tasks[0].next.After(now) {
+			t := p.tasks[0]
+			due = append(due, t.fire)
+			for !t.next.After(now) {
+				// Late ticks are dropped, like with time.Ticker.
+				t.next = t.next.Add(t.period)
+			}
+			heap.Fix(&p.tasks, 0)
+		}
+		if len(due) > 0 {
+			// Tasks may stop themselves, which takes the mutex.
+			p.mutex.Unlock()
+			for _, fire := range due {
+				fire()
+			}
+			continue
+		}
+		if len(p.tasks) == 0 {
+			p.running = false
+			p.mutex.Unlock()
+			return
+		}
+		timer.Reset(time.Until(p.tasks[0].next))
+		p.mutex.Unlock()
+		select {
+		case <-timer.C:
+		case <-p.wake:
+		}
+	}
+}
+
+// Clock scheduling its tickers on a pool.
+type poolClock struct {
+	pool *LimiterPool
+}
+
+func (c poolClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c poolClock) NewTicker(d time.Duration) Ticker {
+	t := &poolTicker{c: make(chan time.Time, 1)}
+	t.stop = c.pool.schedule(d, d, t.tick)
+	return t
+}
+
+func (c poolClock) schedule(first, period time.Duration, f func()) (stop func()) {
+	return c.pool.schedule(first, period, f)
+}
+
+// Ticker driven by a pool.
+type poolTicker struct {
+	c    chan time.Time
+	stop func()
+}
+
+func (t *poolTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *poolTicker) Stop() {
+	t.stop()
+}
+
+// Sends a tick, dropping it if the previous one was not received yet.
+func (t *poolTicker) tick() {
+	select {
+	case t.c <- time.Now():
+	default:
+	}
+}
+
+// Function run periodically by a pool.
+type task struct {
+	period time.Duration
+	next   time.Time
+	fire   func()
+	index  int
+}
+
+// Heap of tasks ordered by next run time, implementing heap.Interface.
+type taskHeap []*task
+
+func (h taskHeap) Len() int           { return len(h) }
+func (h taskHeap) Less(i, j int) bool { return h[i].next.Before(h[j].next) }
+
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *taskHeap) Push(x any) {
+	t := x.(*task)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*h = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,93 @@
+package limiters_test
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+func TestLimiterPool(t *testing.T) {
+	const n = 100
+	pool := limiters.NewLimiterPool()
+	baseline := runtime.NumGoroutine()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		limiter := pool.NewReservoirLimiter(1, 20*time.Millisecond, limiters.WithInitialTokens(0))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Limit(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	eventually(t, func() bool { return pool.Active() == n })
+	if extra := runtime.NumGoroutine() - baseline - n; extra > 1 {
+		t.Errorf("expected a single refill goroutine, got %d", extra)
+	}
+	wg.Wait()
+
+	eventually(t, func() bool { return pool.Active() == 0 })
+	eventually(t, func() bool { return runtime.NumGoroutine() <= baseline })
+}
+
+func TestLimiterPoolTicker(t *testing.T) {
+	pool := limiters.NewLimiterPool()
+	limiter := pool.NewReservoirLimiter(1, time.Millisecond, limiters.WithInitialTokens(0))
+	for i := 0; i < 10; i++ {
+		if err := limiter.Limit(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLimiterPoolWaiters(b *testing.B) {
+	const keys = 10000
+	builders := map[string]func() limiters.Limiter{
+		"standalone": func() limiters.Limiter {
+			return limiters.NewReservoirLimiter(1, time.Hour, limiters.WithInitialTokens(0))
+		},
+	}
+	pool := limiters.NewLimiterPool()
+	builders["pooled"] = func() limiters.Limiter {
+		return pool.NewReservoirLimiter(1, time.Hour, limiters.WithInitialTokens(0))
+	}
+	for name, build := range builders {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				baseline := runtime.NumGoroutine()
+				ctx, cancel := context.WithCancel(context.Background())
+				var wg sync.WaitGroup
+				reporters := make([]limiters.StatsReporter, keys)
+				for k := range reporters {
+					limiter := build()
+					reporters[k] = limiter.(limiters.StatsReporter)
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						limiter.Limit(ctx)
+					}()
+				}
+				for _, reporter := range reporters {
+					for reporter.Stats().Waiting == 0 {
+						runtime.Gosched()
+					}
+				}
+				b.ReportMetric(float64(runtime.NumGoroutine()-baseline-keys), "refill-goroutines")
+				cancel()
+				wg.Wait()
+				// The refill goroutines exit once no one waits, before the next
+				// baseline is taken.
+				for runtime.NumGoroutine() > baseline {
+					runtime.Gosched()
+				}
+			}
+		})
+	}
+}
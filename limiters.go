@@ -1,9 +1,377 @@
 package limiters
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// Returned when more tokens are requested than a limiter can ever hold.
+	ErrExceedsCapacity = errors.New("limiters: requested tokens exceed capacity")
+
+	// Returned when a limiter is built from invalid settings.
+	ErrInvalidConfig = errors.New("limiters: invalid configuration")
+
+	// Returned by limiters that have been closed.
+	ErrLimiterClosed = errors.New("limiters: limiter closed")
+
+	// Returned by limiters that no longer admit new calls.
+	ErrLimiterDraining = errors.New("limiters: limiter draining")
+
+	// Returned, along with context.Canceled, when a call gives up waiting
+	// because its context was canceled.
+	ErrCanceled = errors.New("limiters: wait canceled")
+
+	// Returned, along with context.DeadlineExceeded, when a call gives up
+	// waiting because its context deadline passed.
+	ErrDeadlineExceeded = errors.New("limiters: wait deadline exceeded")
+
+	// Returned when no token could be obtained within the allotted time.
+	ErrTimeout = errors.New("limiters: timed out waiting for a token")
+
+	// Returned, along with ErrTimeout, when a call waited longer than the
+	// default wait timeout of the limiter.
+	ErrWaitTimeout = fmt.Errorf("%w: default wait timeout elapsed", ErrTimeout)
+
+	// Returned when too many reservations are watching their context.
+	ErrTooManyReservations = errors.New("limiters: too many reservations watching their context")
+
+	// Returned when no token could be obtained within the allotted attempts.
+	ErrExhausted = errors.New("limiters: attempts exhausted")
+)
+
+// Errors of the standard contexts, wrapped once to avoid allocating on each
+// canceled call.
+var (
+	errCanceled         = fmt.Errorf("%w: %w", ErrCanceled, context.Canceled)
+	errDeadlineExceeded = fmt.Errorf("%w: %w", ErrDeadlineExceeded, context.DeadlineExceeded)
+)
+
+// Returns the error of a canceled context, wrapped with ErrCanceled or
+// ErrDeadlineExceeded.
+func contextError(ctx context.Context) error {
+	err := ctx.Err()
+	switch {
+	case err == nil:
+		return nil
+	case err == context.Canceled:
+		return errCanceled
+	case err == context.DeadlineExceeded:
+		return errDeadlineExceeded
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", ErrDeadlineExceeded, err)
+	default:
+		return fmt.Errorf("%w: %w", ErrCanceled, err)
+	}
+}
+
+// Priority of a call: waiting calls with a higher priority are handed tokens
+// first.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
 
 type Limiter interface {
 	Limit(ctx context.Context) error
+	LimitN(ctx context.Context, n int) error
+	TryLimit() bool
+	// Same as TryLimit: never blocks and consumes a token on success.
+	Allow() bool
+}
+
+// Implemented by limiters able to report how many tokens they currently hold.
+type TokenCounter interface {
+	Available() int
+}
+
+// Implemented by limiters keeping counters of their activity.
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// Implemented by limiters counting the non-blocking calls they admit and
+// drop.
+type DropReporter interface {
+	Admitted() uint64
+	Dropped() uint64
+	DropRate() float64
+}
+
+// Counters of a limiter's activity.
+type Stats struct {
+	// Calls admitted, including non-blocking ones.
+	Granted uint64
+	// Blocking calls that gave up, because their context was canceled, the
+	// limiter was closed or they asked for more tokens than it can hold.
+	Canceled uint64
+	// Calls currently waiting for tokens.
+	Waiting int64
+}
+
+// State of a limiter, which can be saved to restore it later, e.g. across
+// restarts.
+type LimiterState struct {
+	// Tokens available in the limiter.
+	Tokens int `json:"tokens"`
+	// Time of the last refill, from which the next tokens are refilled.
+	LastRefill time.Time `json:"last_refill"`
+}
+
+// Receives the events of a limiter.
+//
+// Callbacks are invoked synchronously from the calling goroutine, outside of
+// the limiter's locks. They must be safe for concurrent use and should return
+// quickly. A panicking callback does not reach the caller: the panic is
+// logged with the logger set with WithLogger, if any.
+type Observer interface {
+	// Called when a call is admitted.
+	OnGrant()
+	// Called when a call is not admitted, be it a failed non-blocking call or
+	// a blocking call that gave up.
+	OnReject()
+	// Called when a call starts waiting for tokens.
+	OnWaitStart()
+	// Called when a call stops waiting for tokens, before OnGrant or OnReject.
+	OnWaitEnd()
+}
+
+// Implemented by limiters identified by a name, e.g. set with WithName.
+type Namer interface {
+	Name() string
+}
+
+// Implemented by limiters able to hand out fewer tokens than requested rather
+// than waiting for all of them.
+type PartialLimiter interface {
+	LimitUpTo(ctx context.Context, n int) (granted int, err error)
+}
+
+// Implemented by limiters able to take all their available tokens at once,
+// e.g. for a coordinated burst.
+type AvailableDrainer interface {
+	DrainAvailable() int
+}
+
+// Implemented by limiters able to lease batches of tokens, spent locally by
+// the caller.
+type Leaser interface {
+	Lease(ctx context.Context, size int) (*Lease, error)
+}
+
+// Implemented by limiters able to admit calls on credit, charging them only
+// once their operation succeeded.
+type OptimisticLimiter interface {
+	LimitOptimistic(ctx context.Context) (Settlement, error)
+}
+
+// Outcome of a call admitted by an OptimisticLimiter, to be settled once its
+// operation is known to have succeeded or failed.
+type Settlement interface {
+	// Keeps the token if success is set, and gives it back otherwise.
+	Settle(success bool)
+}
+
+// Implemented by limiters able to take several tokens all at once, holding
+// none of them while waiting.
+type AtomicLimiter interface {
+	LimitNAtomic(ctx context.Context, n int) error
+}
+
+// Implemented by limiters able to take several tokens at once without
+// blocking, as a single call.
+type BatchTryLimiter interface {
+	TryLimitN(n int) bool
+}
+
+// Implemented by limiters able to let calls for the same work share a single
+// token.
+type SharedLimiter interface {
+	LimitShared(ctx context.Context, key string) (leader bool, err error)
+}
+
+// Implemented by limiters able to serve some calls before others.
+type PriorityLimiter interface {
+	LimitPriority(ctx context.Context, p Priority) error
+}
+
+// Limiter sharing its tokens across classes of calls, e.g. the plans of
+// customers, in proportion to the weights of the classes.
+type WeightedFairLimiter interface {
+	Limiter
+	LimitClass(ctx context.Context, class string) error
+}
+
+// Implemented by limiters able to report the distribution of wait times.
+type WaitQuantiler interface {
+	WaitQuantile(q float64) time.Duration
+}
+
+// Implemented by limiters able to report how many calls they admit per
+// second, over a trailing window.
+type ThroughputReporter interface {
+	Rate() float64
+}
+
+// Implemented by limiters whose state can be saved and restored.
+type StateSnapshotter interface {
+	Snapshot() LimiterState
+	RestoreState(state LimiterState) error
+}
+
+// Implemented by limiters able to estimate how long a call would wait.
+type DelayEstimator interface {
+	EstimateDelay() time.Duration
+}
+
+// Implemented by limiters able to report their configured rate.
+type RateReporter interface {
+	CurrentRate() (maxTokens int, refillDuration time.Duration)
+}
+
+// Implemented by limiters able to report their settings, as last set.
+type Configurable interface {
+	MaxTokens() int
+	RefillDuration() time.Duration
+}
+
+// Implemented by limiters whose rate can be changed at runtime.
+type RateSetter interface {
+	SetRate(maxTokens int, refillDuration time.Duration) error
+}
+
+// Implemented by limiters able to take back tokens that were not used.
+type TokenReturner interface {
+	ReturnTokens(n int)
+}
+
+// Implemented by limiters able to reserve tokens ahead of time.
+type Reserver interface {
+	Reserve(ctx context.Context) (Reservation, error)
+}
+
+// Implemented by limiters able to report how long a call waited.
+type TimedLimiter interface {
+	LimitTimed(ctx context.Context) (time.Duration, error)
+}
+
+// Implemented by limiters able to explain why a call was not admitted.
+type Admitter interface {
+	Admit(ctx context.Context) Decision
+}
+
+// Outcome of a call that either takes a token right away or is rejected.
+type Decision struct {
+	// Whether a token was taken.
+	Allowed bool
+	// Estimated time until a token is available, zero when allowed or when no
+	// token will ever be.
+	RetryAfter time.Duration
+	// Why the call was rejected, empty when allowed.
+	Reason string
+}
+
+// Implemented by limiters able to report whether a call had to wait.
+type ReportingLimiter interface {
+	LimitReport(ctx context.Context) (blocked bool, waited time.Duration, err error)
+}
+
+// Implemented by limiters able to poll for a token for a short while before
+// blocking.
+type SpinLimiter interface {
+	LimitOrSpin(ctx context.Context, d time.Duration) error
+}
+
+// Implemented by limiters able to wait for a token for a bounded time.
+type BoundedLimiter interface {
+	LimitWithin(d time.Duration) error
+}
+
+// Implemented by limiters adjusting their limit to the outcome of the
+// operations they protect.
+type Adaptive interface {
+	Feedback(success bool)
+	CurrentLimit() int
+}
+
+// Implemented by limiters adjusting their limit to the reported cost of the
+// operations they protect.
+type CostReporter interface {
+	Report(cost float64)
+	AverageCost() float64
+	CurrentLimit() int
+}
+
+// Implemented by limiters adjusting their limit to the reported latency of
+// the operations they protect.
+type LatencyRecorder interface {
+	Record(latency time.Duration)
+	CurrentLimit() int
+}
+
+// Implemented by limiters able to get back to their initial, full state.
+type Resetter interface {
+	Reset()
+}
+
+// Implemented by limiters able to stop throttling for a while, e.g. during an
+// incident.
+type Pauser interface {
+	Pause()
+	Resume()
+}
+
+// Implemented by limiters able to stop admitting new calls while serving
+// those already waiting.
+type Drainer interface {
+	Drain()
+}
+
+// Implemented by limiters able to signal available tokens on a channel, for
+// use in select statements.
+type ReadySignaler interface {
+	Ready() <-chan struct{}
+}
+
+// Implemented by limiters able to hand out tokens on a channel, for use in
+// range loops.
+type TokenStreamer interface {
+	Tokens(ctx context.Context) <-chan struct{}
+}
+
+// Implemented by limiters able to wait for a token to be available without
+// taking it.
+type ReadyWaiter interface {
+	WaitReady(ctx context.Context) error
+}
+
+// Implemented by limiters able to reserve tokens given back when the context
+// is done before they are used.
+type ContextReserver interface {
+	ReserveContext(ctx context.Context) (ContextReservation, error)
+}
+
+// Reservation given back to its limiter if its context is done before Use is
+// called.
+type ContextReservation interface {
+	Reservation
+	// Marks the token as used: it is no longer given back when the context is
+	// done.
+	Use()
+}
+
+// Token reserved from a limiter.
+//
+// The token may only be used once Delay has elapsed. Cancel gives it back to
+// the limiter if it will not be used.
+type Reservation interface {
+	Delay() time.Duration
+	Cancel()
 }
 
 type Scheduler interface {
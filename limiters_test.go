@@ -0,0 +1,32 @@
+package limiters_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+// Polls cond until it holds, failing the test after a second.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Fails the test unless build panics with an error wrapping ErrInvalidConfig.
+func assertInvalidConfig(t *testing.T, build func()) {
+	t.Helper()
+	defer func() {
+		if err, ok := recover().(error); !ok || !errors.Is(err, limiters.ErrInvalidConfig) {
+			t.Fatalf("expected a panic with ErrInvalidConfig, got %v", err)
+		}
+	}()
+	build()
+}
@@ -0,0 +1,178 @@
+// Package limiterstest provides tools to test limiters without waiting for
+// real time to pass.
+package limiterstest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+// Clock whose time only moves when advanced manually.
+//
+// It implements limiters.Clock and limiters.TimerClock, to be injected with
+// limiters.WithClock.
+type Clock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	tickers []*ticker
+	timers  []*timer
+}
+
+// Ticker driven by a Clock.
+type ticker struct {
+	period  time.Duration
+	next    time.Time
+	c       chan time.Time
+	stopped bool
+}
+
+// Timer driven by a Clock.
+type timer struct {
+	at   time.Time
+	f    func()
+	done bool
+}
+
+// Creates a new clock, starting at the Unix epoch.
+func NewClock() *Clock {
+	return &Clock{now: time.Unix(0, 0)}
+}
+
+// Returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Creates a new ticker, firing as the clock is advanced.
+func (c *Clock) NewTicker(d time.Duration) limiters.Ticker {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &ticker{period: d, next: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.tickers = append(c.tickers, t)
+	return &tickerHandle{clock: c, ticker: t}
+}
+
+// Creates a new timer, calling f once the clock is advanced past d.
+func (c *Clock) AfterFunc(d time.Duration, f func()) limiters.Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &timer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return &timerHandle{clock: c, timer: t}
+}
+
+// Moves the time forward, firing the tickers and timers that are due.
+//
+// Like with time.Ticker, ticks are dropped if the previous one was not
+// received yet. The functions of due timers are called once the time moved,
+// one at a time in order of due time, from the goroutine calling Advance:
+// they may use the clock, and the timers they create run too if they are due.
+func (c *Clock) Advance(d time.Duration) {
+	c.advance(d)
+	for f := c.nextDue(); f != nil; f = c.nextDue() {
+		f()
+	}
+}
+
+// Moves the time forward, firing the tickers that are due.
+func (c *Clock) advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		for !t.stopped && !t.next.After(c.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+// Returns the function of the earliest due timer, marking it as done, or nil
+// if no timer is due.
+func (c *Clock) nextDue() func() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var due *timer
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.done {
+			continue
+		}
+		pending = append(pending, t)
+		if !t.at.After(c.now) && (due == nil || t.at.Before(due.at)) {
+			due = t
+		}
+	}
+	clear(c.timers[len(pending):])
+	c.timers = pending
+	if due == nil {
+		return nil
+	}
+	due.done = true
+	return due.f
+}
+
+// Returns the number of tickers that were not stopped.
+func (c *Clock) ActiveTickers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	count := 0
+	for _, t := range c.tickers {
+		if !t.stopped {
+			count++
+		}
+	}
+	return count
+}
+
+// Returns the number of timers that neither fired nor were stopped.
+func (c *Clock) PendingTimers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	count := 0
+	for _, t := range c.timers {
+		if !t.done {
+			count++
+		}
+	}
+	return count
+}
+
+// Handle implementing limiters.Ticker for a ticker.
+type tickerHandle struct {
+	clock  *Clock
+	ticker *ticker
+}
+
+func (h *tickerHandle) C() <-chan time.Time {
+	return h.ticker.c
+}
+
+func (h *tickerHandle) Stop() {
+	h.clock.mutex.Lock()
+	defer h.clock.mutex.Unlock()
+	h.ticker.stopped = true
+}
+
+// Handle implementing limiters.Timer for a timer.
+type timerHandle struct {
+	clock *Clock
+	timer *timer
+}
+
+func (h *timerHandle) Stop() bool {
+	h.clock.mutex.Lock()
+	defer h.clock.mutex.Unlock()
+	if h.timer.done {
+		return false
+	}
+	h.timer.done = true
+	return true
+}
@@ -0,0 +1,149 @@
+package limiterstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+// Longest time a blocked call may take to return once its context is
+// canceled, leaving room for the scheduling of goroutines, e.g. under the race
+// detector.
+const MaxCancellationLatency = 50 * time.Millisecond
+
+// Builds a limiter admitting burst calls at once, then one call every
+// interval, reading the time from clock.
+type Factory func(clock limiters.Clock, burst int, interval time.Duration) limiters.Limiter
+
+// Describes how a limiter admits calls, for RunProfileConformanceTests.
+type Profile struct {
+	// Calls admitted at once by a new limiter, which is also the most a single
+	// call may take.
+	Burst int
+	// Once a limiter admitted all the calls it could, it admits Refill more
+	// after each Interval.
+	Interval time.Duration
+	Refill   int
+	// Whether calls taking more than Burst tokens are admitted anyway, e.g.
+	// paying for them with a longer wait, rather than failing with
+	// limiters.ErrExceedsCapacity.
+	Oversized bool
+}
+
+// Runs tests checking that the limiters built by factory behave as expected
+// from any limiter: bursts, sustained rate, blocking and cancellation, which
+// must not wait for tokens.
+//
+// Blocking calls must wait on a ticker or timer of the clock they are given.
+func RunConformanceTests(t *testing.T, factory Factory) {
+	const (
+		burst    = 3
+		interval = time.Second
+	)
+	RunProfileConformanceTests(t, Profile{Burst: burst, Interval: interval, Refill: 1}, func(clock limiters.Clock) limiters.Limiter {
+		return factory(clock, burst, interval)
+	})
+}
+
+// Same as RunConformanceTests, for limiters admitting calls as described by
+// the profile, e.g. a window of calls at a time.
+func RunProfileConformanceTests(t *testing.T, p Profile, build func(clock limiters.Clock) limiters.Limiter) {
+	burst, interval := p.Burst, p.Interval
+	newHarness := func(t *testing.T) *Harness {
+		return NewHarness(t, build)
+	}
+
+	t.Run("Burst", func(t *testing.T) {
+		h := newHarness(t)
+		h.AssertGranted(burst)
+	})
+
+	t.Run("SustainedRate", func(t *testing.T) {
+		h := newHarness(t)
+		h.AssertGranted(burst)
+		for i := 0; i < 10; i++ {
+			h.AdvanceTime(interval / 2)
+			h.AssertGranted(0)
+			h.AdvanceTime(interval / 2)
+			h.AssertGranted(p.Refill)
+		}
+	})
+
+	t.Run("Blocking", func(t *testing.T) {
+		h := newHarness(t)
+		h.AssertGranted(burst)
+		done := make(chan error, 1)
+		go func() { done <- h.Limiter.Limit(context.Background()) }()
+		h.AwaitBlocked()
+		select {
+		case err := <-done:
+			t.Fatalf("expected the call to block, got %v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+		h.AdvanceTime(interval)
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the call to be admitted after an interval")
+		}
+	})
+
+	t.Run("Cancellation", func(t *testing.T) {
+		h := newHarness(t)
+		h.AssertGranted(burst)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := h.Limiter.Limit(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := h.Limiter.Limit(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+		// Canceled calls do not consume tokens.
+		h.AdvanceTime(interval)
+		h.AssertGranted(p.Refill)
+	})
+
+	t.Run("CancellationLatency", func(t *testing.T) {
+		h := newHarness(t)
+		h.AssertGranted(burst)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- h.Limiter.Limit(ctx) }()
+		h.AwaitBlocked()
+		// The call returns without waiting for a token.
+		start := time.Now()
+		cancel()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected context.Canceled, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the canceled call to return")
+		}
+		if latency := time.Since(start); latency > MaxCancellationLatency {
+			t.Fatalf("expected the canceled call to return within %v, took %v", MaxCancellationLatency, latency)
+		}
+	})
+
+	t.Run("ExceedsCapacity", func(t *testing.T) {
+		h := newHarness(t)
+		if !p.Oversized {
+			if err := h.Limiter.LimitN(context.Background(), burst+1); !errors.Is(err, limiters.ErrExceedsCapacity) {
+				t.Fatalf("expected ErrExceedsCapacity, got %v", err)
+			}
+		}
+		if err := h.Limiter.LimitN(context.Background(), burst); err != nil {
+			t.Fatalf("expected a full burst to be admitted, got %v", err)
+		}
+	})
+}
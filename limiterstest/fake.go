@@ -0,0 +1,131 @@
+package limiterstest
+
+import (
+	"context"
+	"sync"
+)
+
+// Limiter recording its calls and returning preset results, to test code
+// depending on a limiters.Limiter.
+//
+// Calls return the queued results in order, then nil once the queue is
+// empty. When blocking, calls to Limit and LimitN wait for ReleaseNext
+// before returning their result. It is safe for concurrent use.
+type FakeLimiter struct {
+	mutex    sync.Mutex
+	calls    int
+	results  []error
+	blocking bool
+	blocked  []chan struct{}
+}
+
+// Creates a new fake limiter, admitting every call without blocking.
+func NewFakeLimiter() *FakeLimiter {
+	return &FakeLimiter{}
+}
+
+// Queues results to be returned by the next calls, in order.
+//
+// TryLimit and Allow report whether their result is nil.
+func (f *FakeLimiter) QueueResults(results ...error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.results = append(f.results, results...)
+}
+
+// Sets whether calls to Limit and LimitN block until released.
+func (f *FakeLimiter) SetBlocking(blocking bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.blocking = blocking
+}
+
+// Releases the oldest blocked call, and reports whether there was one.
+func (f *FakeLimiter) ReleaseNext() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if len(f.blocked) == 0 {
+		return false
+	}
+	close(f.blocked[0])
+	f.blocked = f.blocked[1:]
+	return true
+}
+
+// Returns the number of calls made so far, blocking or not.
+func (f *FakeLimiter) Calls() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.calls
+}
+
+// Returns the number of calls currently blocked.
+func (f *FakeLimiter) Blocked() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.blocked)
+}
+
+// Returns the next result, blocking until released if set to.
+//
+// A blocked call whose context is canceled returns the context error.
+func (f *FakeLimiter) Limit(ctx context.Context) error {
+	f.mutex.Lock()
+	result := f.next()
+	if !f.blocking {
+		f.mutex.Unlock()
+		return result
+	}
+	release := make(chan struct{})
+	f.blocked = append(f.blocked, release)
+	f.mutex.Unlock()
+	select {
+	case <-release:
+		return result
+	case <-ctx.Done():
+		f.forget(release)
+		return ctx.Err()
+	}
+}
+
+// Same as Limit, whatever n.
+func (f *FakeLimiter) LimitN(ctx context.Context, n int) error {
+	return f.Limit(ctx)
+}
+
+// Reports whether the next result is nil, without blocking.
+func (f *FakeLimiter) TryLimit() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.next() == nil
+}
+
+// Same as TryLimit.
+func (f *FakeLimiter) Allow() bool {
+	return f.TryLimit()
+}
+
+// Counts a call and pops its result.
+//
+// Must be called with the mutex held.
+func (f *FakeLimiter) next() error {
+	f.calls++
+	if len(f.results) == 0 {
+		return nil
+	}
+	result := f.results[0]
+	f.results = f.results[1:]
+	return result
+}
+
+// Removes a blocked call that gave up.
+func (f *FakeLimiter) forget(release chan struct{}) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for i, c := range f.blocked {
+		if c == release {
+			f.blocked = append(f.blocked[:i], f.blocked[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package limiterstest_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+var _ limiters.Limiter = limiterstest.NewFakeLimiter()
+
+func TestFakeLimiterResults(t *testing.T) {
+	fake := limiterstest.NewFakeLimiter()
+	fake.QueueResults(limiters.ErrLimiterClosed, nil)
+	fake.QueueResults(errors.New("rejected"))
+
+	if err := fake.Limit(context.Background()); !errors.Is(err, limiters.ErrLimiterClosed) {
+		t.Fatalf("expected the first queued result, got %v", err)
+	}
+	if !fake.TryLimit() {
+		t.Fatal("expected the second queued result")
+	}
+	if fake.Allow() {
+		t.Fatal("expected the third queued result")
+	}
+	if err := fake.LimitN(context.Background(), 3); err != nil {
+		t.Fatalf("expected nil once the queue is empty, got %v", err)
+	}
+	if got := fake.Calls(); got != 4 {
+		t.Fatalf("expected 4 calls, got %d", got)
+	}
+}
+
+func TestFakeLimiterBlocking(t *testing.T) {
+	fake := limiterstest.NewFakeLimiter()
+	fake.SetBlocking(true)
+	if fake.ReleaseNext() {
+		t.Fatal("expected no call to release")
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- fake.Limit(context.Background())
+		}()
+	}
+	waitFor(t, func() bool { return fake.Blocked() == 3 })
+	for i := 0; i < 3; i++ {
+		if !fake.ReleaseNext() {
+			t.Fatalf("expected call %d to be released", i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- fake.Limit(ctx) }()
+	waitFor(t, func() bool { return fake.Blocked() == 1 })
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if got := fake.Blocked(); got != 0 {
+		t.Fatalf("expected the canceled call to be forgotten, got %d blocked", got)
+	}
+}
+
+// Polls cond until it holds, failing the test after a second.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
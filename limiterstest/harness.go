@@ -0,0 +1,52 @@
+package limiterstest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+// Limiter under test, driven by a manual clock.
+type Harness struct {
+	t       testing.TB
+	Clock   *Clock
+	Limiter limiters.Limiter
+}
+
+// Creates a new harness for the limiter built by build with a manual clock.
+func NewHarness(t testing.TB, build func(clock limiters.Clock) limiters.Limiter) *Harness {
+	clock := NewClock()
+	return &Harness{t: t, Clock: clock, Limiter: build(clock)}
+}
+
+// Moves the clock forward by d.
+func (h *Harness) AdvanceTime(d time.Duration) {
+	h.Clock.Advance(d)
+}
+
+// Checks that exactly n calls are admitted right away, consuming their
+// tokens.
+func (h *Harness) AssertGranted(n int) {
+	h.t.Helper()
+	granted := 0
+	for granted <= n && h.Limiter.TryLimit() {
+		granted++
+	}
+	if granted != n {
+		h.t.Errorf("expected %d calls to be admitted, got %d", n, granted)
+	}
+}
+
+// Waits until a call blocks on a ticker or timer of the clock, failing the
+// test after a second.
+func (h *Harness) AwaitBlocked() {
+	h.t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for h.Clock.ActiveTickers() == 0 && h.Clock.PendingTimers() == 0 {
+		if time.Now().After(deadline) {
+			h.t.Fatal("no call blocked on the clock in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
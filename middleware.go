@@ -0,0 +1,147 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Configures the HTTP middleware.
+type MiddlewareOption func(*middlewareOptions)
+
+// Settings collected from middleware options.
+type middlewareOptions struct {
+	maxWait  time.Duration
+	reject   http.Handler
+	labelTTL time.Duration
+}
+
+// Default time after which the limiter of an unused label is evicted.
+const defaultLabelTTL = 10 * time.Minute
+
+// Sets how long a request may wait for a token before being rejected.
+//
+// By default, requests are rejected as soon as no token is available.
+func WithMaxWait(d time.Duration) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.maxWait = d
+	}
+}
+
+// Sets the handler writing the response of rejected requests.
+//
+// The Retry-After header, if known, is set before the handler is called. By
+// default, rejected requests get a plain 429 Too Many Requests response.
+func WithRejectHandler(h http.Handler) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.reject = h
+	}
+}
+
+// Sets how long the limiter of a label may go unused before LimitByLabel
+// evicts it.
+//
+// By default, limiters are evicted after 10 minutes without requests.
+// LimitByLabel panics if the TTL is not positive.
+func WithLabelTTL(d time.Duration) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.labelTTL = d
+	}
+}
+
+// Collects the settings from the given middleware options.
+func newMiddlewareOptions(opts []MiddlewareOption) middlewareOptions {
+	o := middlewareOptions{reject: http.HandlerFunc(tooManyRequests), labelTTL: defaultLabelTTL}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Creates an HTTP middleware admitting requests through the limiter.
+//
+// Rejected requests get a 429 Too Many Requests response, with a Retry-After
+// header when the limiter implements DelayEstimator.
+func Middleware(l Limiter, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	o := newMiddlewareOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.serve(w, r, l) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// Creates an HTTP middleware admitting requests through a limiter per label,
+// derived from each request, e.g. its route.
+//
+// The limiter of a label is created by factory on its first request, and
+// evicted once unused for the label TTL, see WithLabelTTL. Requests are
+// rejected like with Middleware.
+//
+// Panics if the label TTL is not positive.
+func LimitByLabel(get func(*http.Request) string, factory func(label string) Limiter, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	o := newMiddlewareOptions(opts)
+	if o.labelTTL <= 0 {
+		panic(fmt.Errorf("%w: label TTL %v is not positive", ErrInvalidConfig, o.labelTTL))
+	}
+	keyed := NewKeyedLimiter(factory)
+	var (
+		mutex     sync.Mutex
+		lastEvict = time.Now()
+	)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			if now := time.Now(); now.Sub(lastEvict) >= o.labelTTL {
+				lastEvict = now
+				keyed.EvictIdle(o.labelTTL)
+			}
+			mutex.Unlock()
+			entry := keyed.acquire(get(r))
+			admitted := o.serve(w, r, entry.limiter)
+			keyed.release(entry)
+			if admitted {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// Takes a token for the request, or writes the response of a rejected
+// request. Reports whether the request was admitted.
+func (o middlewareOptions) serve(w http.ResponseWriter, r *http.Request, l Limiter) bool {
+	if admit(r.Context(), l, o.maxWait) {
+		return true
+	}
+	if estimator, ok := l.(DelayEstimator); ok {
+		w.Header().Set("Retry-After", retryAfter(estimator.EstimateDelay()))
+	}
+	o.reject.ServeHTTP(w, r)
+	return false
+}
+
+// Takes a token, waiting at most maxWait for it.
+func admit(ctx context.Context, l Limiter, maxWait time.Duration) bool {
+	if maxWait <= 0 {
+		return l.TryLimit()
+	}
+	ctx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+	return l.Limit(ctx) == nil
+}
+
+// Formats a delay as a Retry-After value, in whole seconds rounded up.
+func retryAfter(delay time.Duration) string {
+	seconds := max((delay+time.Second-1)/time.Second, 1)
+	return strconv.FormatInt(int64(seconds), 10)
+}
+
+// Writes the default response of rejected requests.
+func tooManyRequests(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
@@ -0,0 +1,117 @@
+package limiters_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestMiddlewarePassThroughAndReject(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Minute)
+	handler := limiters.Middleware(limiter)(okHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the first request to pass, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the second request to be rejected, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Fatalf("expected Retry-After 60, got %q", got)
+	}
+}
+
+func TestMiddlewareMaxWait(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, 20*time.Millisecond)
+	handler := limiters.Middleware(limiter, limiters.WithMaxWait(time.Second))(okHandler())
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected request %d to wait for a token, got %d", i, rec.Code)
+		}
+	}
+}
+
+func TestMiddlewareRejectHandler(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Minute, limiters.WithInitialTokens(0))
+	reject := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	handler := limiters.Middleware(limiter, limiters.WithRejectHandler(reject))(okHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the custom rejection, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After to be set before the custom handler")
+	}
+}
+
+func TestLimitByLabel(t *testing.T) {
+	limits := map[string]int{"/search": 1, "/items": 3}
+	created := map[string]int{}
+	factory := func(label string) limiters.Limiter {
+		created[label]++
+		return limiters.NewReservoirLimiter(limits[label], time.Hour)
+	}
+	route := func(r *http.Request) string { return r.URL.Path }
+	mux := http.NewServeMux()
+	mux.Handle("/search", okHandler())
+	mux.Handle("/items", okHandler())
+	server := httptest.NewServer(limiters.LimitByLabel(route, factory, limiters.WithLabelTTL(50*time.Millisecond))(mux))
+	defer server.Close()
+
+	admitted := func(path string) int {
+		count := 0
+		for i := 0; i < 5; i++ {
+			resp, err := http.Get(server.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				count++
+			}
+		}
+		return count
+	}
+	for path, limit := range limits {
+		if got := admitted(path); got != limit {
+			t.Errorf("%s: expected %d requests to pass, got %d", path, limit, got)
+		}
+	}
+
+	// Stale labels get a fresh limiter.
+	time.Sleep(100 * time.Millisecond)
+	if got := admitted("/search"); got != 1 {
+		t.Errorf("expected the evicted limiter to be created anew, got %d requests passing", got)
+	}
+	if created["/search"] != 2 || created["/items"] != 1 {
+		t.Errorf("expected /search to be evicted once, got %v limiters created", created)
+	}
+}
+
+func TestLimitByLabelInvalidTTL(t *testing.T) {
+	route := func(r *http.Request) string { return r.URL.Path }
+	factory := func(string) limiters.Limiter { return limiters.NewReservoirLimiter(1, time.Hour) }
+	assertInvalidConfig(t, func() { limiters.LimitByLabel(route, factory, limiters.WithLabelTTL(0)) })
+	assertInvalidConfig(t, func() { limiters.LimitByLabel(route, factory, limiters.WithLabelTTL(-time.Second)) })
+}
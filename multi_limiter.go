@@ -0,0 +1,96 @@
+package limiters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Error reporting which limiter rejected a call.
+type LimitExceededError struct {
+	// Name of the limiter, or its position among the limiters of a
+	// MultiLimiter if it does not implement Namer.
+	LimiterName string
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("limiters: limit %q exceeded", e.LimiterName)
+}
+
+// Limiter admitting calls only when all its limiters do, and reporting which
+// of them rejected a call.
+//
+// Unlike a chain limiter, it never blocks: every limiter is checked without
+// waiting, so that all those rejecting a call are known.
+type MultiLimiter struct {
+	limiters []Limiter
+}
+
+// Creates a new multi limiter over the given limiters.
+func NewMultiLimiter(limiters ...Limiter) *MultiLimiter {
+	return &MultiLimiter{limiters: limiters}
+}
+
+// Consumes a token from every limiter if all of them have one immediately
+// available, without blocking.
+//
+// See LimitN for the returned errors.
+func (m *MultiLimiter) Limit(ctx context.Context) error {
+	return m.LimitN(ctx, 1)
+}
+
+// Consumes n tokens from every limiter if all of them have enough tokens
+// immediately available, without blocking.
+//
+// Otherwise, no token is consumed from the limiters implementing
+// TokenReturner, and a *LimitExceededError is returned for each limiter
+// lacking tokens, joined with errors.Join if there are several of them.
+func (m *MultiLimiter) LimitN(ctx context.Context, n int) error {
+	if err := contextError(ctx); err != nil {
+		return err
+	}
+	var (
+		admitted []Limiter
+		errs     []error
+	)
+	for i, limiter := range m.limiters {
+		if tryLimitN(limiter, n) {
+			admitted = append(admitted, limiter)
+			continue
+		}
+		errs = append(errs, &LimitExceededError{LimiterName: limiterName(limiter, i)})
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	returnTokens(admitted, n)
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// Consumes a token from every limiter if all of them have one immediately
+// available, without blocking.
+func (m *MultiLimiter) TryLimit() bool {
+	return m.LimitN(context.Background(), 1) == nil
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (m *MultiLimiter) Allow() bool {
+	return m.TryLimit()
+}
+
+// Gives n unused tokens back to every limiter.
+func (m *MultiLimiter) ReturnTokens(n int) {
+	returnTokens(m.limiters, n)
+}
+
+// Returns the name of the limiter at position i.
+func limiterName(l Limiter, i int) string {
+	if namer, ok := l.(Namer); ok && namer.Name() != "" {
+		return namer.Name()
+	}
+	return strconv.Itoa(i)
+}
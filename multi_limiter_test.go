@@ -0,0 +1,76 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+// Returns the names of the limiters reported in err.
+func exceededNames(err error) []string {
+	var names []string
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			names = append(names, exceededNames(err)...)
+		}
+		return names
+	}
+	var exceeded *limiters.LimitExceededError
+	if errors.As(err, &exceeded) {
+		names = append(names, exceeded.LimiterName)
+	}
+	return names
+}
+
+func TestMultiLimiter(t *testing.T) {
+	clock := limiterstest.NewClock()
+	perSecond := limiters.NewReservoirLimiter(2, time.Second/2,
+		limiters.WithClock(clock),
+		limiters.WithName("per-second"),
+	)
+	perMinute := limiters.NewReservoirLimiter(3, 20*time.Second,
+		limiters.WithClock(clock),
+		limiters.WithName("per-minute"),
+	)
+	multi := limiters.NewMultiLimiter(perSecond, perMinute)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := multi.Limit(ctx); err != nil {
+			t.Fatalf("expected call %d to be admitted, got %v", i, err)
+		}
+	}
+	err := multi.Limit(ctx)
+	if names := exceededNames(err); len(names) != 1 || names[0] != "per-second" {
+		t.Fatalf("expected per-second to reject the call, got %v", err)
+	}
+
+	// The per-minute token was given back.
+	clock.Advance(time.Second)
+	if err := multi.Limit(ctx); err != nil {
+		t.Fatalf("expected the call to be admitted, got %v", err)
+	}
+	err = multi.Limit(ctx)
+	if names := exceededNames(err); len(names) != 1 || names[0] != "per-minute" {
+		t.Fatalf("expected per-minute to reject the call, got %v", err)
+	}
+
+	// Both limiters are exhausted.
+	perSecond.TryLimit()
+	perSecond.TryLimit()
+	err = multi.Limit(ctx)
+	if names := exceededNames(err); len(names) != 2 || names[0] != "per-second" || names[1] != "per-minute" {
+		t.Fatalf("expected both limiters to reject the call, got %v", err)
+	}
+}
+
+func TestMultiLimiterUnnamed(t *testing.T) {
+	multi := limiters.NewMultiLimiter(limiters.NewNoopLimiter(), limiters.NewBlockingLimiter())
+	if names := exceededNames(multi.Limit(context.Background())); len(names) != 1 || names[0] != "1" {
+		t.Fatalf("expected the second limiter to reject the call, got %v", names)
+	}
+}
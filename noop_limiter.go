@@ -0,0 +1,35 @@
+package limiters
+
+import "context"
+
+// Struct implementing the Limiter interface.
+type noopLimiter struct{}
+
+// Creates a limiter admitting every call right away, e.g. when rate limiting
+// is disabled.
+func NewNoopLimiter() Limiter {
+	return noopLimiter{}
+}
+
+// Returns right away, with an error only if the context is already done.
+func (noopLimiter) Limit(ctx context.Context) error {
+	return contextError(ctx)
+}
+
+// Returns right away, with an error only if the context is already done.
+func (noopLimiter) LimitN(ctx context.Context, n int) error {
+	return contextError(ctx)
+}
+
+// Always admits the call.
+func (noopLimiter) TryLimit() bool {
+	return true
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (noopLimiter) Allow() bool {
+	return true
+}
+
+// Does nothing, as no tokens are ever consumed.
+func (noopLimiter) ReturnTokens(n int) {}
@@ -0,0 +1,34 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/p-nordmann/limiters"
+)
+
+func TestNoopLimiter(t *testing.T) {
+	limiter := limiters.NewNoopLimiter()
+	ctx := context.Background()
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := limiter.Limit(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := limiter.LimitN(ctx, 1000); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !limiter.TryLimit() {
+			t.Fatal("expected the call to be admitted")
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocation, got %v", allocs)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := limiter.Limit(canceled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,447 @@
+package limiters
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// Configures a limiter at construction.
+type Option func(*options)
+
+// Settings collected from options.
+type options struct {
+	initialTokens *int
+	name          string
+	clock         Clock
+	observer      Observer
+	logger        *slog.Logger
+	waitHistogram bool
+	onTransition  func(empty bool)
+	burst         *int
+	refillBatch   *int
+	rateWindow    time.Duration
+	registry      *Registry
+	jitter        float64
+	startupDelay  time.Duration
+	alwaysOn      bool
+	scheduled     bool
+	callerCap     int
+	waitTimeout   time.Duration
+	shareWindow   time.Duration
+	stallAfter    time.Duration
+	onStall       func()
+	maxWatchers   int
+	overProvision int
+	warmup        time.Duration
+	warmupCurve   func(progress float64) float64
+	random        func() float64
+	fair          bool
+	alignWindows  bool
+	aging         time.Duration
+	edf           bool
+	increase      int
+	decrease      float64
+	costAlpha     float64
+	sloIncrease   float64
+	sloDecrease   float64
+	forwardedFor  bool
+}
+
+// Collects the settings from the given options.
+func newOptions(opts []Option) options {
+	o := options{clock: realClock{}, random: rand.Float64, warmupCurve: linearWarmup, fair: true, rateWindow: defaultRateWindow, maxWatchers: defaultMaxWatchers, increase: 1, decrease: 0.5, costAlpha: defaultCostAlpha, sloIncrease: defaultSLOIncrease, sloDecrease: defaultSLODecrease}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Sets the number of tokens the limiter starts with.
+//
+// By default, the reservoir starts full. The value must be between zero and
+// the maximum number of tokens.
+func WithInitialTokens(n int) Option {
+	return func(o *options) {
+		o.initialTokens = &n
+	}
+}
+
+// Sets a name identifying the limiter, e.g. in logs or metrics.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// Sets the clock used by the limiter, e.g. to control time in tests.
+//
+// By default, the limiter uses the time package.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// Registers the limiter in the registry, e.g. DefaultRegistry, until it is
+// closed.
+//
+// By default, limiters are not registered.
+func WithRegistry(r *Registry) Option {
+	return func(o *options) {
+		o.registry = r
+	}
+}
+
+// Sets an observer notified of the limiter's events.
+func WithObserver(observer Observer) Option {
+	return func(o *options) {
+		o.observer = observer
+	}
+}
+
+// Sets a logger receiving debug logs of the limiter's events: tokens granted,
+// reservoir emptied and refilled, and waits canceled.
+//
+// Some events are logged while holding the limiter's lock, so the logger's
+// handler must not call back into the limiter. By default, nothing is
+// logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// Makes the limiter keep a histogram of how long calls wait, to report wait
+// quantiles.
+//
+// The histogram uses constant memory, about 2.6 KiB per limiter. By default,
+// wait times are not tracked.
+func WithWaitHistogram() Option {
+	return func(o *options) {
+		o.waitHistogram = true
+	}
+}
+
+// Sets a function called when the limiter starts throttling, with empty set
+// to true, and when it recovers, with empty set to false.
+//
+// The limiter throttles as soon as a call has to wait for tokens. It recovers
+// once no call is waiting and tokens are available, and no call had to wait
+// for a whole refill duration, so that flapping is not reported. Transitions
+// alternate, starting with empty set to true.
+//
+// The function is called outside of the limiter's lock, one call at a time,
+// from a goroutine that just started waiting or from the refill goroutine: a
+// slow function delays that caller or the next refills, but cannot deadlock
+// the limiter. Like for observers, its panics are recovered and logged.
+func WithTransitionCallback(f func(empty bool)) Option {
+	return func(o *options) {
+		o.onTransition = f
+	}
+}
+
+// Sets the trailing window over which the observed rate of admitted calls is
+// measured.
+//
+// By default, the window is 10 seconds. It must be positive.
+func WithRateWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.rateWindow = d
+	}
+}
+
+// Refills tokens n at a time, every n refill durations, keeping the same
+// average rate.
+//
+// Coarser refills wake up the refill ticker n times less often, at the cost
+// of burstier grants and of waits up to n refill durations. By default,
+// tokens are refilled one at a time. The value must be positive.
+func WithRefillBatch(n int) Option {
+	return func(o *options) {
+		o.refillBatch = &n
+	}
+}
+
+// Caps the number of tokens handed out per refill period, however many tokens
+// the reservoir holds.
+//
+// This allows a large standing reservoir while bounding short-term bursts. By
+// default, there is no cap besides the reservoir capacity. The value must be
+// positive.
+func WithBurst(n int) Option {
+	return func(o *options) {
+		o.burst = &n
+	}
+}
+
+// Randomizes each refill interval within plus or minus the given fraction of
+// the refill duration.
+//
+// This keeps limiters created together from refilling in lockstep. On
+// average, the refill rate is unchanged. The fraction must be in [0, 1).
+func WithJitter(fraction float64) Option {
+	return func(o *options) {
+		o.jitter = fraction
+	}
+}
+
+// Starts the reservoir empty and refills its first token after a random
+// delay in [0, max], drawn from the source set with WithRandSource.
+//
+// This spreads the initial traffic of replicas starting together. Combined
+// with WithInitialTokens, the reservoir starts with those tokens instead. The
+// maximum delay must not be negative.
+func WithStartupDelay(max time.Duration) Option {
+	return func(o *options) {
+		o.startupDelay = max
+	}
+}
+
+// Ramps the refill rate of a reservoir limiter up to the configured one over
+// the given duration from its creation, e.g. to let caches populate after a
+// deploy.
+//
+// By default, the rate grows linearly from a tenth of the configured one, and
+// the reservoir starts with as many tokens, out of the maximum. The duration
+// must not be negative.
+func WithWarmup(d time.Duration) Option {
+	return func(o *options) {
+		o.warmup = d
+	}
+}
+
+// Sets the curve of the warmup set with WithWarmup, giving the fraction of
+// the configured rate at a progress between 0 and 1.
+//
+// Fractions are clamped to [0.01, 1], so that tokens keep being refilled.
+func WithWarmupCurve(curve func(progress float64) float64) Option {
+	return func(o *options) {
+		o.warmupCurve = curve
+	}
+}
+
+// Makes a reservoir limiter watch for stalls: calls kept waiting while no
+// token was handed out to them for longer than threshold, e.g. because the
+// refill goroutine died. The stall is logged at warning level, with the
+// logger set with WithLogger, and f is called if not nil, once per stall.
+//
+// A zero threshold defaults to ten refill periods. Waiters held back by
+// their caller cap or the burst window may set it off too. The limiter is
+// checked every threshold, with the clock of the limiter, while calls wait.
+// Like for observers, the panics of f are recovered and logged. The threshold
+// must not be negative.
+func WithStallDetector(threshold time.Duration, f func()) Option {
+	return func(o *options) {
+		o.stallAfter = threshold
+		o.onStall = f
+		if f == nil {
+			o.onStall = func() {}
+		}
+	}
+}
+
+// Sets how long calls of LimitShared share the token of the leader for their
+// key once it was admitted.
+//
+// By default, the token is shared for a refill duration. The window must not
+// be negative.
+func WithSharedWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.shareWindow = d
+	}
+}
+
+// Caps how long blocking calls of a reservoir limiter wait for tokens, unless
+// their context has a nearer deadline.
+//
+// Calls waiting longer fail with ErrWaitTimeout, telling the backpressure of
+// the limiter apart from the cancellation of the context. The timeout is
+// measured with the clock of the limiter. By default, calls wait as long as
+// their context allows. The timeout must not be negative.
+func WithDefaultWaitTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.waitTimeout = d
+	}
+}
+
+// Sets how many reservations made with ReserveContext may watch their context
+// at once, until they are used or canceled.
+//
+// Beyond it, ReserveContext fails with ErrTooManyReservations. By default, up
+// to 1024 reservations watch their context. The number must be positive.
+func WithMaxReservationWatchers(n int) Option {
+	return func(o *options) {
+		o.maxWatchers = n
+	}
+}
+
+// Sets how many tokens LimitOptimistic may hand out beyond those of the
+// reservoir, to calls whose operation has not settled yet.
+//
+// A call settled as successful is charged a token, possibly one refilled
+// later: the reservoir then pays the overdraft first. By default, no token is
+// handed out on credit. The number must not be negative.
+func WithOverProvision(n int) Option {
+	return func(o *options) {
+		o.overProvision = n
+	}
+}
+
+// Caps the tokens a single caller, identified by the key set with
+// ContextWithCaller, may get from a reservoir limiter within the time to
+// refill the whole reservoir, e.g. so that a caller in a tight loop does not
+// starve the others.
+//
+// A caller over its cap waits even if tokens are available, while the others
+// go ahead. Calls without a caller key, including those of TryLimit and
+// Allow, are not capped. The cap must not be negative, zero meaning no cap.
+func WithPerCallerCap(n int) Option {
+	return func(o *options) {
+		o.callerCap = n
+	}
+}
+
+// Keeps the refill ticker of a reservoir limiter running from its creation
+// until it is closed.
+//
+// By default, the ticker only runs while calls are waiting, and each call
+// blocking on an idle limiter starts it again. Keeping it running costs a
+// wakeup per refill.
+func WithAlwaysOn() Option {
+	return func(o *options) {
+		o.alwaysOn = true
+	}
+}
+
+// Makes a reservoir limiter refill tokens with a single timer, armed for when
+// the next waiting call can be served, rather than with a ticker firing on
+// each refill.
+//
+// This saves wakeups for sparse rates and calls taking several tokens: the
+// timer fires about once per grant. It is armed again when a call starts
+// waiting, in case that call can be served sooner. The timer uses AfterFunc
+// if the clock implements TimerClock.
+func WithScheduledRefill() Option {
+	return func(o *options) {
+		o.scheduled = true
+	}
+}
+
+// Sets the source of randomness used by the limiter, e.g. to get reproducible
+// jitter in tests.
+//
+// The source is only used while holding the limiter's lock, so it does not
+// need to be safe for concurrent use.
+func WithRandSource(src rand.Source) Option {
+	return func(o *options) {
+		o.random = rand.New(src).Float64
+	}
+}
+
+// Sets whether tokens are handed out strictly in order of arrival.
+//
+// Fairness is enabled by default: a call asking for more tokens than
+// available holds back the calls arriving after it, so that it cannot be
+// starved. Disabling it lets those calls go first when enough tokens are
+// available for them, which improves utilization at the risk of starving
+// large requests.
+func WithFairness(enabled bool) Option {
+	return func(o *options) {
+		o.fair = enabled
+	}
+}
+
+// Hands tokens to the waiting calls whose context deadlines are the nearest
+// first, so that fewer of them time out. Calls without a deadline are served
+// after those with one.
+//
+// Priorities still take precedence over deadlines, and calls with the same
+// deadline are served in order of arrival. By default, waiting calls are
+// served in order of arrival.
+func WithEDF() Option {
+	return func(o *options) {
+		o.edf = true
+	}
+}
+
+// Raises the priority of waiting calls by one for each period d they spend
+// waiting, so that calls with a low priority are not starved by a steady
+// stream of calls with a higher one.
+//
+// By default, priorities never change. The period must not be negative.
+func WithPriorityAging(d time.Duration) Option {
+	return func(o *options) {
+		o.aging = d
+	}
+}
+
+// Aligns the windows of a fixed window limiter on multiples of the window
+// duration since the zero time, e.g. on the start of each minute.
+//
+// Alignment is computed in UTC: windows of a day start at midnight UTC. By
+// default, the first window starts when the limiter is created.
+func WithAlignedWindows() Option {
+	return func(o *options) {
+		o.alignWindows = true
+	}
+}
+
+// Sets how an adaptive limiter reacts to feedback: each success adds increase
+// to the limit, and each failure multiplies it by decrease.
+//
+// By default, the limit grows by 1 and is halved. The increase must be
+// positive and the decrease in (0, 1).
+func WithAIMD(increase int, decrease float64) Option {
+	return func(o *options) {
+		o.increase = increase
+		o.decrease = decrease
+	}
+}
+
+// Default weight of each report in the average cost of a cost budget
+// limiter.
+const defaultCostAlpha = 0.2
+
+// Sets the weight alpha of each reported cost in the moving average of a cost
+// budget limiter: the average moves by alpha times the difference between the
+// reported cost and the average.
+//
+// A higher alpha follows changes of cost faster, and a lower one smooths out
+// outliers better. By default, alpha is 0.2. It must be in (0, 1].
+func WithCostSmoothing(alpha float64) Option {
+	return func(o *options) {
+		o.costAlpha = alpha
+	}
+}
+
+// Default gains of an SLO limiter.
+const (
+	defaultSLOIncrease = 0.1
+	defaultSLODecrease = 0.5
+)
+
+// Sets the gains of an SLO limiter: how far the limit moves, relative to
+// itself, per unit of relative latency error. The limit grows by up to
+// increase while latency is under target, and shrinks by up to decrease while
+// it is over.
+//
+// Higher gains react faster, and lower ones oscillate less around the target.
+// By default, the limit grows by up to 10% and shrinks by up to 50% per
+// operation. The increase must be positive, and the decrease in (0, 1).
+func WithSLOGains(increase, decrease float64) Option {
+	return func(o *options) {
+		o.sloIncrease = increase
+		o.sloDecrease = decrease
+	}
+}
+
+// Makes an IP limiter identify clients from the X-Forwarded-For header, when
+// present, rather than from the address of the connection.
+//
+// Only use it behind a proxy setting the header: clients can send any value.
+func WithForwardedFor() Option {
+	return func(o *options) {
+		o.forwardedFor = true
+	}
+}
@@ -0,0 +1,62 @@
+// Package otellimiter provides OpenTelemetry tracing for limiters.
+package otellimiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name of the spans around limiter waits.
+const spanName = "limiter.wait"
+
+// Struct implementing the Limiter interface.
+type tracedLimiter struct {
+	limiter limiters.Limiter
+	tracer  trace.Tracer
+}
+
+// Creates a limiter tracing the blocking calls of the given limiter.
+//
+// Each call to Limit or LimitN runs in a span named "limiter.wait", whose
+// attributes record the wait duration and whether the call was granted.
+// Non-blocking calls are not traced.
+func NewTracedLimiter(l limiters.Limiter, tracer trace.Tracer) limiters.Limiter {
+	return &tracedLimiter{limiter: l, tracer: tracer}
+}
+
+// Blocks until a token is available or the context is canceled.
+func (l *tracedLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until n tokens are available or the context is canceled.
+func (l *tracedLimiter) LimitN(ctx context.Context, n int) error {
+	ctx, span := l.tracer.Start(ctx, spanName, trace.WithAttributes(attribute.Int("limiter.tokens", n)))
+	defer span.End()
+	start := time.Now()
+	err := l.limiter.LimitN(ctx, n)
+	span.SetAttributes(
+		attribute.Float64("limiter.wait_seconds", time.Since(start).Seconds()),
+		attribute.Bool("limiter.granted", err == nil),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "not granted")
+	}
+	return err
+}
+
+// Consumes a token if one is immediately available, without blocking.
+func (l *tracedLimiter) TryLimit() bool {
+	return l.limiter.TryLimit()
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *tracedLimiter) Allow() bool {
+	return l.limiter.Allow()
+}
@@ -0,0 +1,64 @@
+package otellimiter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/otellimiter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Returns the value of the attribute of the span with the given key.
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracedLimiter(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	inner := limiters.NewReservoirLimiter(1, time.Hour)
+	limiter := otellimiter.NewTracedLimiter(inner, provider.Tracer("test"))
+
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Limit(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for i, want := range []bool{true, false} {
+		span := spans[i]
+		if span.Name() != "limiter.wait" {
+			t.Errorf("span %d: expected the name limiter.wait, got %q", i, span.Name())
+		}
+		if granted, ok := attributeValue(span, "limiter.granted"); !ok || granted.AsBool() != want {
+			t.Errorf("span %d: expected limiter.granted to be %v, got %v", i, want, granted.Emit())
+		}
+		if _, ok := attributeValue(span, "limiter.wait_seconds"); !ok {
+			t.Errorf("span %d: expected the wait duration to be recorded", i)
+		}
+	}
+	if wait, _ := attributeValue(spans[1], "limiter.wait_seconds"); wait.AsFloat64() < 0.015 {
+		t.Errorf("expected the canceled wait to last until the deadline, got %vs", wait.AsFloat64())
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("expected the canceled wait to be an error, got %v", spans[1].Status())
+	}
+}
@@ -0,0 +1,65 @@
+package promlimiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Struct implementing the Limiter interface.
+type timedLimiter struct {
+	limiter  limiters.Limiter
+	observer *PrometheusObserver
+}
+
+// Creates a limiter recording the wait of the blocking calls of the given
+// limiter in the limiter_wait_seconds histogram of the observer.
+//
+// Each call to Limit or LimitN is observed, whether it was granted or not.
+// When the context passed to the call carries an OpenTelemetry span, the
+// observation holds an exemplar with its trace_id and span_id, linking the
+// histogram to the trace. Non-blocking calls are not observed.
+func NewTimedLimiter(l limiters.Limiter, o *PrometheusObserver) limiters.Limiter {
+	return &timedLimiter{limiter: l, observer: o}
+}
+
+// Blocks until a token is available or the context is canceled.
+func (l *timedLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until n tokens are available or the context is canceled.
+func (l *timedLimiter) LimitN(ctx context.Context, n int) error {
+	start := time.Now()
+	err := l.limiter.LimitN(ctx, n)
+	l.observer.observeWait(ctx, time.Since(start))
+	return err
+}
+
+// Consumes a token if one is immediately available, without blocking.
+func (l *timedLimiter) TryLimit() bool {
+	return l.limiter.TryLimit()
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *timedLimiter) Allow() bool {
+	return l.limiter.Allow()
+}
+
+// Records a wait, with the span of the context as an exemplar if there is
+// one.
+func (o *PrometheusObserver) observeWait(ctx context.Context, wait time.Duration) {
+	span := trace.SpanContextFromContext(ctx)
+	observer, ok := o.waitSeconds.(prometheus.ExemplarObserver)
+	if !ok || !span.IsValid() {
+		o.waitSeconds.Observe(wait.Seconds())
+		return
+	}
+	observer.ObserveWithExemplar(wait.Seconds(), prometheus.Labels{
+		"trace_id": span.TraceID().String(),
+		"span_id":  span.SpanID().String(),
+	})
+}
@@ -0,0 +1,93 @@
+package promlimiter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/promlimiter"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Returns the wait histogram gathered from the registry.
+func gatherWaits(t *testing.T, registry *prometheus.Registry) *dto.Histogram {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() == "limiter_wait_seconds" {
+			return family.GetMetric()[0].GetHistogram()
+		}
+	}
+	t.Fatal("missing metric limiter_wait_seconds")
+	return nil
+}
+
+// Returns the exemplars attached to the buckets of the histogram.
+func exemplars(histogram *dto.Histogram) []*dto.Exemplar {
+	var found []*dto.Exemplar
+	for _, bucket := range histogram.GetBucket() {
+		if exemplar := bucket.GetExemplar(); exemplar != nil {
+			found = append(found, exemplar)
+		}
+	}
+	return found
+}
+
+func TestTimedLimiterExemplar(t *testing.T) {
+	observer := promlimiter.NewPrometheusObserver("api")
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(observer)
+	limiter := promlimiter.NewTimedLimiter(limiters.NewReservoirLimiter(10, time.Hour), observer)
+
+	span := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	if err := limiter.Limit(trace.ContextWithSpanContext(context.Background(), span)); err != nil {
+		t.Fatal(err)
+	}
+
+	histogram := gatherWaits(t, registry)
+	if got := histogram.GetSampleCount(); got != 1 {
+		t.Fatalf("expected a single observation, got %d", got)
+	}
+	found := exemplars(histogram)
+	if len(found) != 1 {
+		t.Fatalf("expected a single exemplar, got %d", len(found))
+	}
+	labels := map[string]string{}
+	for _, label := range found[0].GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	if labels["trace_id"] != span.TraceID().String() || labels["span_id"] != span.SpanID().String() {
+		t.Fatalf("expected the exemplar to carry the span, got %v", labels)
+	}
+}
+
+func TestTimedLimiterWithoutSpan(t *testing.T) {
+	observer := promlimiter.NewPrometheusObserver("api")
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(observer)
+	limiter := promlimiter.NewTimedLimiter(limiters.NewReservoirLimiter(1, time.Hour), observer)
+
+	limiter.Limit(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	limiter.Limit(ctx)
+	limiter.TryLimit()
+
+	histogram := gatherWaits(t, registry)
+	if got := histogram.GetSampleCount(); got != 2 {
+		t.Fatalf("expected the two blocking calls to be observed, got %d", got)
+	}
+	if found := exemplars(histogram); len(found) != 0 {
+		t.Fatalf("expected no exemplar without a span, got %d", len(found))
+	}
+}
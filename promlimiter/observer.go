@@ -0,0 +1,79 @@
+// Package promlimiter exposes limiter metrics to Prometheus.
+package promlimiter
+
+import (
+	"github.com/p-nordmann/limiters"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Observer exporting a limiter's events as Prometheus metrics.
+//
+// It implements both limiters.Observer, to be passed to a limiter with
+// limiters.WithObserver, and prometheus.Collector, to be registered.
+type PrometheusObserver struct {
+	granted  prometheus.Counter
+	rejected prometheus.Counter
+	waiting  prometheus.Gauge
+	// Filled by the limiters returned by NewTimedLimiter.
+	waitSeconds prometheus.Histogram
+}
+
+var _ limiters.Observer = (*PrometheusObserver)(nil)
+
+// Creates a new observer whose metrics are labeled with the limiter name.
+func NewPrometheusObserver(name string) *PrometheusObserver {
+	labels := prometheus.Labels{"limiter": name}
+	return &PrometheusObserver{
+		granted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "limiter_granted_total",
+			Help:        "Number of calls admitted by the limiter.",
+			ConstLabels: labels,
+		}),
+		rejected: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "limiter_rejected_total",
+			Help:        "Number of calls not admitted by the limiter.",
+			ConstLabels: labels,
+		}),
+		waiting: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "limiter_waiting",
+			Help:        "Number of calls currently waiting for tokens.",
+			ConstLabels: labels,
+		}),
+		waitSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "limiter_wait_seconds",
+			Help:        "Time blocking calls spent in the limiter.",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}),
+	}
+}
+
+func (o *PrometheusObserver) OnGrant() {
+	o.granted.Inc()
+}
+
+func (o *PrometheusObserver) OnReject() {
+	o.rejected.Inc()
+}
+
+func (o *PrometheusObserver) OnWaitStart() {
+	o.waiting.Inc()
+}
+
+func (o *PrometheusObserver) OnWaitEnd() {
+	o.waiting.Dec()
+}
+
+func (o *PrometheusObserver) Describe(ch chan<- *prometheus.Desc) {
+	o.granted.Describe(ch)
+	o.rejected.Describe(ch)
+	o.waiting.Describe(ch)
+	o.waitSeconds.Describe(ch)
+}
+
+func (o *PrometheusObserver) Collect(ch chan<- prometheus.Metric) {
+	o.granted.Collect(ch)
+	o.rejected.Collect(ch)
+	o.waiting.Collect(ch)
+	o.waitSeconds.Collect(ch)
+}
@@ -0,0 +1,62 @@
+package promlimiter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/promlimiter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPrometheusObserver(t *testing.T) {
+	observer := promlimiter.NewPrometheusObserver("api")
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(observer)
+
+	limiter := limiters.NewReservoirLimiter(2, time.Hour, limiters.WithName("api"), limiters.WithObserver(observer))
+	for i := 0; i < 3; i++ {
+		limiter.TryLimit()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	limiter.Limit(ctx)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{
+		"limiter_granted_total":  2,
+		"limiter_rejected_total": 2,
+		"limiter_waiting":        0,
+		"limiter_wait_seconds":   0,
+	}
+	for _, family := range families {
+		expected, ok := want[family.GetName()]
+		if !ok {
+			t.Errorf("unexpected metric %s", family.GetName())
+			continue
+		}
+		delete(want, family.GetName())
+		metric := family.GetMetric()[0]
+		if label := metric.GetLabel()[0]; label.GetName() != "limiter" || label.GetValue() != "api" {
+			t.Errorf("unexpected label %s=%s on %s", label.GetName(), label.GetValue(), family.GetName())
+		}
+		var got float64
+		if counter := metric.GetCounter(); counter != nil {
+			got = counter.GetValue()
+		} else if histogram := metric.GetHistogram(); histogram != nil {
+			got = float64(histogram.GetSampleCount())
+		} else {
+			got = metric.GetGauge().GetValue()
+		}
+		if got != expected {
+			t.Errorf("expected %s to be %v, got %v", family.GetName(), expected, got)
+		}
+	}
+	for name := range want {
+		t.Errorf("missing metric %s", name)
+	}
+}
@@ -0,0 +1,54 @@
+package limiters
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter bounding both the rate of calls and the number of calls in flight.
+type RateAndConcurrencyLimiter struct {
+	rate        Limiter
+	concurrency *ConcurrencyLimiter
+}
+
+// Creates a limiter admitting calls at the rate of a reservoir limiter of
+// maxTokens tokens refilled every refillDuration, with at most maxConcurrent
+// of them in flight.
+//
+// The options are passed to the reservoir limiter.
+func NewRateAndConcurrencyLimiter(maxTokens int, refillDuration time.Duration, maxConcurrent int, opts ...Option) *RateAndConcurrencyLimiter {
+	return &RateAndConcurrencyLimiter{
+		rate:        NewReservoirLimiter(maxTokens, refillDuration, opts...),
+		concurrency: NewConcurrencyLimiter(maxConcurrent),
+	}
+}
+
+// Blocks until a call is admitted by both limits or the context is canceled.
+//
+// The concurrency slot is taken first, then the rate token. On success, the
+// caller must call release once its work is done to free the slot; extra
+// calls to release do nothing.
+func (l *RateAndConcurrencyLimiter) Begin(ctx context.Context) (release func(), err error) {
+	if err := l.concurrency.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	if err := l.rate.Limit(ctx); err != nil {
+		l.concurrency.Release()
+		return nil, err
+	}
+	var once sync.Once
+	return func() { once.Do(l.concurrency.Release) }, nil
+}
+
+// Returns the number of calls currently in flight.
+func (l *RateAndConcurrencyLimiter) InFlight() int {
+	return l.concurrency.InFlight()
+}
+
+// Closes the reservoir limiter: calls to Begin fail with ErrLimiterClosed
+// from then on, while calls in flight may still release their slot.
+func (l *RateAndConcurrencyLimiter) Close() error {
+	return l.rate.(io.Closer).Close()
+}
@@ -0,0 +1,75 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestRateAndConcurrencyLimiter(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewRateAndConcurrencyLimiter(3, time.Second, 2, limiters.WithClock(clock))
+	ctx := context.Background()
+
+	// The concurrency limit applies first.
+	first, err := limiter.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := limiter.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Begin(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the concurrency limit to apply, got %v", err)
+	}
+
+	// Then the rate limit, once slots are free.
+	first()
+	first()
+	second()
+	third, err := limiter.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	third()
+	timeout, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Begin(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the rate limit to apply, got %v", err)
+	}
+	if got := limiter.InFlight(); got != 0 {
+		t.Fatalf("expected the slot to be freed after a rate rejection, got %d in flight", got)
+	}
+
+	clock.Advance(time.Second)
+	release, err := limiter.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected the refilled token to be granted, got %v", err)
+	}
+	release()
+}
+
+func TestRateAndConcurrencyLimiterClose(t *testing.T) {
+	limiter := limiters.NewRateAndConcurrencyLimiter(3, time.Second, 2)
+	release, err := limiter.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := limiter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.Begin(context.Background()); !errors.Is(err, limiters.ErrLimiterClosed) {
+		t.Fatalf("expected ErrLimiterClosed, got %v", err)
+	}
+	release()
+	if got := limiter.InFlight(); got != 0 {
+		t.Fatalf("expected the slot to be released, got %d in flight", got)
+	}
+}
@@ -0,0 +1,62 @@
+package limiters
+
+import "time"
+
+// Number of slots the window of a rate meter is split into.
+const rateSlots = 10
+
+// Default window over which the observed rate is measured.
+const defaultRateWindow = 10 * time.Second
+
+// Counter of events over a trailing window, split into slots of equal
+// duration so that the window slides one slot at a time.
+//
+// A rate meter is not safe for concurrent use: the limiter owning it guards
+// it with its own mutex.
+type rateMeter struct {
+	origin  time.Time
+	slot    time.Duration
+	current int64
+	counts  [rateSlots]uint64
+}
+
+// Creates a rate meter measuring over the given window, from now on.
+func newRateMeter(window time.Duration, now time.Time) *rateMeter {
+	return &rateMeter{origin: now, slot: max(window/rateSlots, 1)}
+}
+
+// Counts an event.
+func (m *rateMeter) record(now time.Time) {
+	m.advance(now)
+	m.counts[m.current%rateSlots]++
+}
+
+// Returns the number of events per second over the window, or since the
+// creation of the meter if it is more recent.
+func (m *rateMeter) rate(now time.Time) float64 {
+	m.advance(now)
+	total := uint64(0)
+	for _, count := range m.counts {
+		total += count
+	}
+	covered := now.Sub(m.origin)
+	if full := now.Sub(m.origin.Add(time.Duration(m.current-rateSlots+1) * m.slot)); covered > full {
+		covered = full
+	}
+	if covered <= 0 {
+		return 0
+	}
+	return float64(total) / covered.Seconds()
+}
+
+// Clears the slots that fell out of the window.
+func (m *rateMeter) advance(now time.Time) {
+	n := int64(now.Sub(m.origin) / m.slot)
+	if n <= m.current {
+		return
+	}
+	for i := m.current + 1; i <= n && i <= m.current+rateSlots; i++ {
+		m.counts[i%rateSlots] = 0
+	}
+	m.current = n
+}
@@ -0,0 +1,184 @@
+// Package redislimiter provides a limiter sharing its budget through Redis.
+package redislimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+// Minimal Redis client needed by the limiter.
+//
+// With go-redis, it can be implemented as:
+//
+//	func (c adapter) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+//		return c.client.Eval(ctx, script, keys, args...).Result()
+//	}
+type RedisClient interface {
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// Token bucket taking tokens atomically, using the server time so that all
+// clients agree on the refill.
+//
+// Returns zero if the tokens were taken, and the estimated wait in
+// microseconds otherwise.
+const takeScript = `
+local max_tokens = tonumber(ARGV[1])
+local refill_us = tonumber(ARGV[2])
+local requested = tonumber(ARGV[3])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil then
+	tokens = max_tokens
+end
+if tokens >= max_tokens then
+	tokens = max_tokens
+	last = now
+else
+	local refilled = math.floor((now - last) / refill_us)
+	if refilled > 0 then
+		tokens = math.min(max_tokens, tokens + refilled)
+		last = last + refilled * refill_us
+	end
+end
+local wait = 0
+if tokens >= requested then
+	tokens = tokens - requested
+else
+	wait = (requested - tokens) * refill_us - (now - last)
+end
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'last', last)
+redis.call('PEXPIRE', KEYS[1], math.ceil(max_tokens * refill_us / 1000) + 1000)
+return wait
+`
+
+// Smallest delay between two attempts.
+const minBackoff = time.Millisecond
+
+// Struct implementing the Limiter interface.
+type redisLimiter struct {
+	client         RedisClient
+	key            string
+	maxTokens      int
+	refillDuration time.Duration
+	backoff        limiters.Backoff
+}
+
+// Configures a Redis limiter at construction.
+type Option func(*redisLimiter)
+
+// Sets the policy deciding how long to sleep between two attempts, the
+// estimated wait being a lower bound.
+//
+// By default, the sleep doubles from a millisecond up to the refill duration.
+func WithBackoff(b limiters.Backoff) Option {
+	return func(l *redisLimiter) {
+		l.backoff = b
+	}
+}
+
+// Creates a new limiter whose reservoir is stored in Redis under key.
+//
+// All limiters sharing a key share the same budget. Tokens are refilled on
+// the Redis server clock, which counts in microseconds.
+//
+// Panics if the client is nil, maxTokens is not positive or refillDuration is
+// under a microsecond.
+func NewRedisLimiter(client RedisClient, key string, maxTokens int, refillDuration time.Duration, opts ...Option) limiters.Limiter {
+	switch {
+	case client == nil:
+		panic(fmt.Errorf("%w: nil Redis client", limiters.ErrInvalidConfig))
+	case maxTokens <= 0:
+		panic(fmt.Errorf("%w: max tokens %d is not positive", limiters.ErrInvalidConfig, maxTokens))
+	case refillDuration < time.Microsecond:
+		panic(fmt.Errorf("%w: refill duration %v is under a microsecond", limiters.ErrInvalidConfig, refillDuration))
+	}
+	l := &redisLimiter{
+		client:         client,
+		key:            key,
+		maxTokens:      maxTokens,
+		refillDuration: refillDuration,
+		backoff:        limiters.ExponentialBackoff{Initial: minBackoff, Max: refillDuration},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Blocks until a token is available or the context is canceled.
+func (l *redisLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until n tokens are available or the context is canceled.
+//
+// The limiter polls Redis, sleeping between attempts for the estimated wait
+// or the delay given by the backoff policy, whichever is longer.
+func (l *redisLimiter) LimitN(ctx context.Context, n int) error {
+	if n > l.maxTokens {
+		return limiters.ErrExceedsCapacity
+	}
+	for attempt := 0; ; attempt++ {
+		wait, err := l.take(ctx, n)
+		if err != nil {
+			return err
+		}
+		if wait == 0 {
+			return nil
+		}
+		timer := time.NewTimer(max(wait, l.backoff.Next(attempt)))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return waitError(ctx)
+		}
+	}
+}
+
+// Takes a token if one is immediately available, without blocking.
+//
+// Returns false if no token is available or Redis cannot be reached.
+func (l *redisLimiter) TryLimit() bool {
+	wait, err := l.take(context.Background(), 1)
+	return err == nil && wait == 0
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *redisLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Runs the token bucket script, returning the estimated wait.
+func (l *redisLimiter) take(ctx context.Context, n int) (time.Duration, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	reply, err := l.client.Eval(ctx, takeScript, []string{l.key},
+		l.maxTokens, l.refillDuration.Microseconds(), n)
+	if err != nil {
+		return 0, err
+	}
+	wait, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redislimiter: unexpected reply %v", reply)
+	}
+	return max(time.Duration(wait)*time.Microsecond, 0), nil
+}
+
+// Returns the error of a canceled context, wrapped like the errors of the
+// limiters package.
+func waitError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", limiters.ErrDeadlineExceeded, ctx.Err())
+	}
+	return fmt.Errorf("%w: %w", limiters.ErrCanceled, ctx.Err())
+}
@@ -0,0 +1,129 @@
+package redislimiter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/redislimiter"
+	"github.com/redis/go-redis/v9"
+)
+
+// Adapter implementing RedisClient with go-redis.
+type goRedisClient struct {
+	client *redis.Client
+}
+
+func (c goRedisClient) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	return c.client.Eval(ctx, script, keys, args...).Result()
+}
+
+func newClient(t *testing.T) redislimiter.RedisClient {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return goRedisClient{client: client}
+}
+
+func TestRedisLimiterSharedBudget(t *testing.T) {
+	client := newClient(t)
+	first := redislimiter.NewRedisLimiter(client, "api", 3, time.Hour)
+	second := redislimiter.NewRedisLimiter(client, "api", 3, time.Hour)
+
+	if !first.TryLimit() || !second.TryLimit() || !first.TryLimit() {
+		t.Fatal("expected the first three calls to be admitted")
+	}
+	if second.TryLimit() {
+		t.Fatal("expected the shared budget to be exhausted")
+	}
+	other := redislimiter.NewRedisLimiter(client, "other", 3, time.Hour)
+	if !other.TryLimit() {
+		t.Fatal("expected another key to have its own budget")
+	}
+}
+
+func TestRedisLimiterInvalid(t *testing.T) {
+	client := newClient(t)
+	for _, build := range []func(){
+		func() { redislimiter.NewRedisLimiter(nil, "api", 1, time.Second) },
+		func() { redislimiter.NewRedisLimiter(client, "api", 0, time.Second) },
+		func() { redislimiter.NewRedisLimiter(client, "api", 1, time.Nanosecond) },
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, limiters.ErrInvalidConfig) {
+					t.Errorf("expected a panic with ErrInvalidConfig, got %v", err)
+				}
+			}()
+			build()
+		}()
+	}
+}
+
+func TestRedisLimiterWaitsForRefill(t *testing.T) {
+	client := newClient(t)
+	const refill = 30 * time.Millisecond
+	limiter := redislimiter.NewRedisLimiter(client, "api", 1, refill)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Limit(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 2*refill-5*time.Millisecond {
+		t.Fatalf("expected to wait for two refills, waited %v", elapsed)
+	}
+}
+
+func TestRedisLimiterCancellation(t *testing.T) {
+	client := newClient(t)
+	limiter := redislimiter.NewRedisLimiter(client, "api", 1, time.Hour)
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Limit(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if err := limiter.LimitN(context.Background(), 2); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity, got %v", err)
+	}
+}
+
+// Backoff recording the attempts it is asked about.
+type recordingBackoff struct {
+	delay    time.Duration
+	attempts []int
+}
+
+func (b *recordingBackoff) Next(attempt int) time.Duration {
+	b.attempts = append(b.attempts, attempt)
+	return b.delay
+}
+
+func TestRedisLimiterBackoff(t *testing.T) {
+	client := newClient(t)
+	backoff := &recordingBackoff{delay: 50 * time.Millisecond}
+	limiter := redislimiter.NewRedisLimiter(client, "api", 1, 10*time.Millisecond,
+		redislimiter.WithBackoff(backoff))
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < backoff.delay {
+		t.Fatalf("expected to sleep for the backoff delay, waited %v", elapsed)
+	}
+	if len(backoff.attempts) != 1 || backoff.attempts[0] != 0 {
+		t.Fatalf("expected a single retry, got attempts %v", backoff.attempts)
+	}
+}
@@ -0,0 +1,95 @@
+package limiters
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Registry listing limiters, e.g. for an admin or debug endpoint.
+//
+// Registration is explicit, with Register or WithRegistry, so that limiters
+// no longer in use can be garbage collected once unregistered. It is safe
+// for concurrent use.
+type Registry struct {
+	mutex   sync.Mutex
+	entries map[*registryEntry]struct{}
+}
+
+// Limiter held by a registry.
+type registryEntry struct {
+	limiter Limiter
+}
+
+// Snapshot of a registered limiter.
+//
+// Fields the limiter cannot report are left to their zero value.
+type LimiterInfo struct {
+	// Name set with WithName.
+	Name string
+	// Configured capacity and refill duration.
+	MaxTokens      int
+	RefillDuration time.Duration
+	// Tokens currently available.
+	Available int
+	// Calls currently waiting for tokens.
+	Waiting int64
+}
+
+// Registry shared by the whole program, e.g. for limiters created
+// WithRegistry(DefaultRegistry).
+var DefaultRegistry = &Registry{}
+
+// Adds a limiter to the registry, and returns a function removing it.
+func (r *Registry) Register(l Limiter) (unregister func()) {
+	entry := &registryEntry{limiter: l}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.entries == nil {
+		r.entries = make(map[*registryEntry]struct{})
+	}
+	r.entries[entry] = struct{}{}
+	return func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		delete(r.entries, entry)
+	}
+}
+
+// Returns a snapshot of the registered limiters, sorted by name.
+func (r *Registry) List() []LimiterInfo {
+	r.mutex.Lock()
+	registered := make([]Limiter, 0, len(r.entries))
+	for entry := range r.entries {
+		registered = append(registered, entry.limiter)
+	}
+	r.mutex.Unlock()
+
+	infos := make([]LimiterInfo, 0, len(registered))
+	for _, l := range registered {
+		infos = append(infos, limiterInfo(l))
+	}
+	slices.SortStableFunc(infos, func(a, b LimiterInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return infos
+}
+
+// Returns what the limiter reports through its optional interfaces.
+func limiterInfo(l Limiter) LimiterInfo {
+	var info LimiterInfo
+	if namer, ok := l.(Namer); ok {
+		info.Name = namer.Name()
+	}
+	if reporter, ok := l.(RateReporter); ok {
+		info.MaxTokens, info.RefillDuration = reporter.CurrentRate()
+	}
+	if counter, ok := l.(TokenCounter); ok {
+		info.Available = counter.Available()
+	}
+	if reporter, ok := l.(StatsReporter); ok {
+		info.Waiting = reporter.Stats().Waiting
+	}
+	return info
+}
@@ -0,0 +1,75 @@
+package limiters_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestRegistryList(t *testing.T) {
+	registry := &limiters.Registry{}
+	clock := limiterstest.NewClock()
+	api := limiters.NewReservoirLimiter(10, time.Second,
+		limiters.WithName("api"), limiters.WithClock(clock), limiters.WithRegistry(registry))
+	limiters.NewReservoirLimiter(5, time.Minute, limiters.WithName("db"), limiters.WithRegistry(registry))
+	unregister := registry.Register(limiters.NewNoopLimiter())
+	api.LimitN(context.Background(), 3)
+
+	infos := registry.List()
+	if len(infos) != 3 {
+		t.Fatalf("expected 3 limiters, got %v", infos)
+	}
+	if infos[0] != (limiters.LimiterInfo{}) {
+		t.Fatalf("expected a limiter reporting nothing first, got %+v", infos[0])
+	}
+	want := limiters.LimiterInfo{Name: "api", MaxTokens: 10, RefillDuration: time.Second, Available: 7}
+	if infos[1] != want {
+		t.Fatalf("expected %+v, got %+v", want, infos[1])
+	}
+	if infos[2].Name != "db" || infos[2].Available != 5 {
+		t.Fatalf("expected the db limiter, got %+v", infos[2])
+	}
+
+	unregister()
+	api.(io.Closer).Close()
+	if infos := registry.List(); len(infos) != 1 || infos[0].Name != "db" {
+		t.Fatalf("expected closed and unregistered limiters to be removed, got %v", infos)
+	}
+}
+
+func TestRegistryNotRegisteredByDefault(t *testing.T) {
+	before := len(limiters.DefaultRegistry.List())
+	limiters.NewReservoirLimiter(1, time.Second, limiters.WithName("unregistered"))
+	if after := len(limiters.DefaultRegistry.List()); after != before {
+		t.Fatalf("expected limiters not to be registered by default, got %d more", after-before)
+	}
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	registry := &limiters.Registry{}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			l := limiters.NewReservoirLimiter(1, time.Second, limiters.WithName(fmt.Sprint(i)), limiters.WithRegistry(registry))
+			if i%2 == 0 {
+				l.(io.Closer).Close()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			registry.List()
+		}()
+	}
+	wg.Wait()
+	if got := len(registry.List()); got != 10 {
+		t.Fatalf("expected 10 limiters left, got %d", got)
+	}
+}
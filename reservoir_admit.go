@@ -0,0 +1,78 @@
+package limiters
+
+import "context"
+
+// Reasons of the decisions of a reservoir limiter.
+const (
+	reasonCanceled       = "context done"
+	reasonClosed         = "limiter closed"
+	reasonDraining       = "limiter draining"
+	reasonWaiters        = "calls waiting"
+	reasonEmpty          = "reservoir empty"
+	reasonBurstExhausted = "burst exhausted"
+	reasonCallerCap      = "caller cap reached"
+	reasonNoCapacity     = "no capacity"
+)
+
+// Takes a token if one is available right away, or explains why not and when
+// to retry.
+//
+// RetryAfter accounts for the calls already waiting, for the burst window when
+// the burst is exhausted, and for the window of the caller when it reached the
+// cap set with WithPerCallerCap. It is zero for a limiter without capacity,
+// which will never have a token.
+func (l *reservoirLimiter) Admit(ctx context.Context) Decision {
+	if contextError(ctx) != nil {
+		return l.reject(Decision{Reason: reasonCanceled})
+	}
+	l.mutex.Lock()
+	if l.closed {
+		l.mutex.Unlock()
+		return l.reject(Decision{Reason: reasonClosed})
+	}
+	if l.draining {
+		l.mutex.Unlock()
+		return l.reject(Decision{Reason: reasonDraining})
+	}
+	caller := ""
+	if l.callerCap > 0 {
+		caller, _ = CallerFromContext(ctx)
+	}
+	now := l.clock.Now()
+	l.refill(now)
+	var decision Decision
+	switch {
+	case l.paused:
+		decision = Decision{Allowed: true}
+	case l.maxTokens < 1:
+		decision = Decision{Reason: reasonNoCapacity}
+	case l.fair && l.waiters.Len() > 0:
+		decision = Decision{Reason: reasonWaiters, RetryAfter: l.nextTokenTime().Sub(now)}
+	case !l.underCallerCap(caller, 1, now):
+		decision = Decision{Reason: reasonCallerCap, RetryAfter: l.callers[caller].start.Add(l.callerWindow()).Sub(now)}
+	case l.tokenCount == 0:
+		decision = Decision{Reason: reasonEmpty, RetryAfter: l.nextTokenTime().Sub(now)}
+	case l.burstAllowance(now) == 0:
+		decision = Decision{Reason: reasonBurstExhausted, RetryAfter: l.windowStart.Add(l.refillDuration).Sub(now)}
+	default:
+		l.take(1, now)
+		l.chargeCaller(caller, 1, now)
+		l.throughput.record(now)
+		decision = Decision{Allowed: true}
+	}
+	l.mutex.Unlock()
+	if !decision.Allowed {
+		decision.RetryAfter = max(decision.RetryAfter, 0)
+		return l.reject(decision)
+	}
+	l.admitted.Add(1)
+	l.recordGrant()
+	return decision
+}
+
+// Notifies the observer of a rejected decision, and returns it.
+func (l *reservoirLimiter) reject(decision Decision) Decision {
+	l.dropped.Add(1)
+	l.observe(Observer.OnReject)
+	return decision
+}
@@ -0,0 +1,87 @@
+package limiters_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestReservoirLimiterAdmit(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(2, time.Second, limiters.WithClock(clock))
+	admitter := limiter.(limiters.Admitter)
+
+	for i := 0; i < 2; i++ {
+		if d := admitter.Admit(context.Background()); d != (limiters.Decision{Allowed: true}) {
+			t.Fatalf("expected call %d to be allowed, got %+v", i, d)
+		}
+	}
+	clock.Advance(300 * time.Millisecond)
+	d := admitter.Admit(context.Background())
+	if d.Allowed || d.Reason != "reservoir empty" || d.RetryAfter != 700*time.Millisecond {
+		t.Fatalf("expected to retry after 700ms with the reservoir empty, got %+v", d)
+	}
+
+	clock.Advance(d.RetryAfter)
+	if d := admitter.Admit(context.Background()); !d.Allowed {
+		t.Fatalf("expected the call to be allowed after RetryAfter, got %+v", d)
+	}
+	if got := limiter.(limiters.StatsReporter).Stats().Granted; got != 3 {
+		t.Fatalf("expected 3 grants, got %d", got)
+	}
+}
+
+func TestReservoirLimiterAdmitReasons(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(5, time.Second, limiters.WithClock(clock), limiters.WithBurst(1))
+	admitter := limiter.(limiters.Admitter)
+
+	admitter.Admit(context.Background())
+	clock.Advance(400 * time.Millisecond)
+	if d := admitter.Admit(context.Background()); d.Reason != "burst exhausted" || d.RetryAfter != 600*time.Millisecond {
+		t.Fatalf("expected to retry at the next burst window, got %+v", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if d := admitter.Admit(ctx); d.Allowed || d.Reason != "context done" {
+		t.Fatalf("expected the context to be done, got %+v", d)
+	}
+
+	limiter.(io.Closer).Close()
+	if d := admitter.Admit(context.Background()); d.Allowed || d.Reason != "limiter closed" || d.RetryAfter != 0 {
+		t.Fatalf("expected the limiter to be closed, got %+v", d)
+	}
+}
+
+func TestReservoirLimiterAdmitCallerCap(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(4, time.Second, limiters.WithClock(clock), limiters.WithPerCallerCap(2))
+	admitter := limiter.(limiters.Admitter)
+	worker := limiters.ContextWithCaller(context.Background(), "worker")
+
+	for i := 0; i < 2; i++ {
+		if d := admitter.Admit(worker); !d.Allowed {
+			t.Fatalf("expected call %d to be allowed, got %+v", i, d)
+		}
+	}
+	clock.Advance(time.Second)
+	if d := admitter.Admit(worker); d.Allowed || d.Reason != "caller cap reached" || d.RetryAfter != 3*time.Second {
+		t.Fatalf("expected to retry at the end of the caller window, got %+v", d)
+	}
+	other := limiters.ContextWithCaller(context.Background(), "other")
+	if d := admitter.Admit(other); !d.Allowed {
+		t.Fatalf("expected another caller to be allowed, got %+v", d)
+	}
+}
+
+func TestReservoirLimiterAdmitNoCapacity(t *testing.T) {
+	admitter := limiters.NewReservoirLimiter(0, time.Second).(limiters.Admitter)
+	if d := admitter.Admit(context.Background()); d.Allowed || d.Reason != "no capacity" || d.RetryAfter != 0 {
+		t.Fatalf("expected no retry without capacity, got %+v", d)
+	}
+}
@@ -0,0 +1,33 @@
+package limiters
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Notifies the observer, if any, of an event, recovering from its panics.
+func (l *reservoirLimiter) observe(event func(Observer)) {
+	if l.observer == nil {
+		return
+	}
+	defer l.recoverCallback("observer")
+	event(l.observer)
+}
+
+// Calls the transition function, recovering from its panics.
+func (l *reservoirLimiter) transition(empty bool) {
+	defer l.recoverCallback("transition")
+	l.onTransition(empty)
+}
+
+// Recovers from the panic of a user-supplied callback, so that a buggy
+// callback cannot take the limiter down, and logs it at error level if a
+// logger is set.
+//
+// Must be deferred.
+func (l *reservoirLimiter) recoverCallback(callback string) {
+	if r := recover(); r != nil && l.logger != nil {
+		l.logger.LogAttrs(context.Background(), slog.LevelError, "limiters: callback panicked",
+			slog.String("callback", callback), slog.Any("panic", r), slog.String("limiter", l.name))
+	}
+}
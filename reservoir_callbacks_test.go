@@ -0,0 +1,71 @@
+package limiters_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+// Observer panicking on every event.
+type panickingObserver struct{}
+
+func (panickingObserver) OnGrant()     { panic("grant") }
+func (panickingObserver) OnReject()    { panic("reject") }
+func (panickingObserver) OnWaitStart() { panic("wait start") }
+func (panickingObserver) OnWaitEnd()   { panic("wait end") }
+
+// Buffer safe for concurrent writes of a logger.
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+func TestReservoirLimiterPanickingCallbacks(t *testing.T) {
+	clock := limiterstest.NewClock()
+	var logs syncBuffer
+	limiter := limiters.NewReservoirLimiter(1, time.Second,
+		limiters.WithClock(clock),
+		limiters.WithObserver(panickingObserver{}),
+		limiters.WithTransitionCallback(func(bool) { panic("transition") }),
+		limiters.WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelError}))),
+	)
+	// Transitions are reported from the waiting goroutine and the refill one.
+	for i := 0; i < 3; i++ {
+		if !limiter.TryLimit() {
+			t.Fatal("expected a token despite the panicking callbacks")
+		}
+		done := make(chan error)
+		go func() { done <- limiter.Limit(context.Background()) }()
+		eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+		clock.Advance(time.Second)
+		if err := <-done; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		clock.Advance(2 * time.Second)
+		eventually(t, func() bool { return clock.ActiveTickers() == 0 })
+	}
+	for _, callback := range []string{"observer", "transition"} {
+		if !strings.Contains(logs.String(), "callback="+callback) {
+			t.Errorf("expected the panics of the %s to be logged, got %q", callback, logs.String())
+		}
+	}
+}
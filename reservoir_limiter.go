@@ -1,99 +1,1311 @@
 package limiters
 
 import (
+	"container/list"
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-type token struct{}
+// Caller blocked until it has been handed its tokens.
+type waiter struct {
+	n        int
+	got      int
+	err      error
+	ready    chan struct{}
+	priority Priority
+	since    time.Time
+	deadline time.Time
+	// Only waits for a token to be available, without taking it.
+	peek bool
+	// Takes its tokens all at once, rather than accumulating them.
+	whole bool
+	// Class sharing the tokens with the others by weight, nil if the limiter
+	// has no weights.
+	class *trafficClass
+	// Key of the caller whose tokens are capped, empty if there is none.
+	caller string
+	// Released by Pause without taking any token.
+	free bool
+}
 
 // Struct implementing the Limiter interface.
+//
+// Tokens are refilled lazily from the time elapsed since the last refill. A
+// ticker only runs while callers are waiting, to hand them tokens as soon as
+// they are refilled.
 type reservoirLimiter struct {
+	name           string
 	maxTokens      int
 	refillDuration time.Duration
-	in             chan token
-	out            chan token
-	numConcurrent  int
+	burst          int
+	batch          int
+	jitter         float64
+	fair           bool
+	aging          time.Duration
+	edf            bool
+	alwaysOn       bool
+	scheduled      bool
+	refillTimer    Timer
+	refillGen      uint64
+	random         func() float64
+	clock          Clock
 	mutex          sync.Mutex
+	tokenCount     int
+	lastRefill     time.Time
+	nextInterval   time.Duration
+	warmup         time.Duration
+	warmupEnd      time.Time
+	warmupCurve    func(progress float64) float64
+	windowStart    time.Time
+	windowGrants   int
+	waiters        list.List
+	prioritized    int
+	stopRefill     func()
+	ready          chan struct{}
+	done           chan struct{}
+	closed         bool
+	draining       bool
+	paused         bool
+	observer       Observer
+	logger         *slog.Logger
+	waits          *rollingHistogram
+	throughput     *rateMeter
+	unregister     func()
+	weights        map[string]int
+	classes        map[string]*trafficClass
+	callerCap      int
+	waitTimeout    time.Duration
+	stallAfter     time.Duration
+	onStall        func()
+	stopStallWatch func()
+	lastProgress   time.Time
+	stallReported  bool
+	maxWatchers    int
+	watchers       int
+	overProvision  int
+	credits        int
+	overdraft      int
+	callers        map[string]*callerUsage
+	callersSwept   time.Time
+	shareWindow    time.Duration
+	shared         map[string]*sharedCall
+	sharedSwept    time.Time
+	virtualTime    float64
+	onTransition   func(empty bool)
+	throttled      bool
+	lastThrottled  time.Time
+	transitions    []bool
+	notifying      sync.Mutex
+	granted        atomic.Uint64
+	canceled       atomic.Uint64
+	admitted       atomic.Uint64
+	dropped        atomic.Uint64
+	waiting        atomic.Int64
 }
 
 // Creates a new reservoir limiter.
-func NewReservoirLimiter(maxTokens int, refillDuration time.Duration) Limiter {
-	return &reservoirLimiter{
+//
+// A limiter of zero tokens admits no call: blocking calls fail right away
+// with ErrExceedsCapacity rather than waiting forever, until SetRate gives it
+// some capacity. Panics if maxTokens is negative or the options are invalid.
+func NewReservoirLimiter(maxTokens int, refillDuration time.Duration, opts ...Option) Limiter {
+	l, err := NewReservoirLimiterWithError(maxTokens, refillDuration, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// Creates a new reservoir limiter, or returns an error wrapping
+// ErrInvalidConfig if maxTokens is negative or the options are invalid.
+func NewReservoirLimiterWithError(maxTokens int, refillDuration time.Duration, opts ...Option) (Limiter, error) {
+	o := newOptions(opts)
+	if err := checkReservoirConfig(maxTokens, refillDuration, o); err != nil {
+		return nil, err
+	}
+	tokenCount := maxTokens
+	if o.initialTokens != nil {
+		tokenCount = *o.initialTokens
+	} else if o.startupDelay > 0 {
+		tokenCount = 0
+	}
+	burst := 0
+	if o.burst != nil {
+		burst = *o.burst
+	}
+	batch := 1
+	if o.refillBatch != nil {
+		batch = *o.refillBatch
+	}
+	l := &reservoirLimiter{
+		name:           o.name,
 		maxTokens:      maxTokens,
 		refillDuration: refillDuration,
-		in:             make(chan token),
-		out:            make(chan token),
+		burst:          burst,
+		batch:          batch,
+		jitter:         o.jitter,
+		fair:           o.fair,
+		aging:          o.aging,
+		edf:            o.edf,
+		alwaysOn:       o.alwaysOn,
+		scheduled:      o.scheduled,
+		random:         o.random,
+		clock:          o.clock,
+		tokenCount:     tokenCount,
+		lastRefill:     o.clock.Now(),
+		observer:       o.observer,
+		logger:         o.logger,
+		onTransition:   o.onTransition,
+		warmup:         o.warmup,
+		warmupCurve:    o.warmupCurve,
+		callerCap:      o.callerCap,
+		waitTimeout:    o.waitTimeout,
+		shareWindow:    o.shareWindow,
+		onStall:        o.onStall,
+		maxWatchers:    o.maxWatchers,
+		overProvision:  o.overProvision,
+		done:           make(chan struct{}),
+	}
+	if l.callerCap > 0 {
+		l.callers = map[string]*callerUsage{}
+	}
+	if l.warmup > 0 {
+		l.warmupEnd = l.lastRefill.Add(l.warmup)
+		if o.initialTokens == nil && o.startupDelay == 0 {
+			// Start with the burst of the initial rate.
+			l.tokenCount = int(math.Ceil(float64(maxTokens) * l.warmupFraction(0)))
+		}
+	}
+	l.throughput = newRateMeter(o.rateWindow, l.lastRefill)
+	if o.waitHistogram {
+		l.waits = &rollingHistogram{start: l.lastRefill}
+	}
+	l.nextInterval = l.refillInterval()
+	if l.onStall != nil {
+		l.stallAfter = o.stallAfter
+		if l.stallAfter == 0 {
+			l.stallAfter = 10 * l.refillPeriod()
+		}
+	}
+	if o.startupDelay > 0 {
+		// Shift the refill period so that the first token comes after the delay.
+		delay := time.Duration(l.random() * float64(o.startupDelay))
+		l.lastRefill = l.lastRefill.Add(delay - l.nextInterval)
+	}
+	if o.registry != nil {
+		l.unregister = o.registry.Register(l)
+	}
+	if l.alwaysOn {
+		l.startRefillTicker()
+	}
+	return l, nil
+}
+
+// Returns an error wrapping ErrInvalidConfig if a reservoir limiter cannot be
+// created with the given settings.
+func checkReservoirConfig(maxTokens int, refillDuration time.Duration, o options) error {
+	if maxTokens < 0 {
+		return fmt.Errorf("%w: max tokens %d is negative", ErrInvalidConfig, maxTokens)
+	}
+	if refillDuration <= 0 {
+		return fmt.Errorf("%w: refill duration %v is not positive", ErrInvalidConfig, refillDuration)
+	}
+	if o.initialTokens != nil && (*o.initialTokens < 0 || *o.initialTokens > maxTokens) {
+		return fmt.Errorf("%w: initial tokens %d out of range [0, %d]", ErrInvalidConfig, *o.initialTokens, maxTokens)
+	}
+	if o.burst != nil && *o.burst <= 0 {
+		return fmt.Errorf("%w: burst %d is not positive", ErrInvalidConfig, *o.burst)
+	}
+	if o.refillBatch != nil && *o.refillBatch <= 0 {
+		return fmt.Errorf("%w: refill batch %d is not positive", ErrInvalidConfig, *o.refillBatch)
+	}
+	if o.aging < 0 {
+		return fmt.Errorf("%w: negative priority aging %v", ErrInvalidConfig, o.aging)
+	}
+	if o.rateWindow <= 0 {
+		return fmt.Errorf("%w: rate window %v is not positive", ErrInvalidConfig, o.rateWindow)
+	}
+	if o.jitter < 0 || o.jitter >= 1 {
+		return fmt.Errorf("%w: jitter %v out of range [0, 1)", ErrInvalidConfig, o.jitter)
+	}
+	if o.startupDelay < 0 {
+		return fmt.Errorf("%w: negative startup delay %v", ErrInvalidConfig, o.startupDelay)
+	}
+	if o.callerCap < 0 {
+		return fmt.Errorf("%w: per-caller cap %d is negative", ErrInvalidConfig, o.callerCap)
+	}
+	if o.maxWatchers <= 0 {
+		return fmt.Errorf("%w: %d reservation watchers is not positive", ErrInvalidConfig, o.maxWatchers)
+	}
+	if o.overProvision < 0 {
+		return fmt.Errorf("%w: over-provision %d is negative", ErrInvalidConfig, o.overProvision)
+	}
+	if o.waitTimeout < 0 {
+		return fmt.Errorf("%w: negative wait timeout %v", ErrInvalidConfig, o.waitTimeout)
+	}
+	if o.shareWindow < 0 {
+		return fmt.Errorf("%w: negative shared window %v", ErrInvalidConfig, o.shareWindow)
+	}
+	if o.stallAfter < 0 {
+		return fmt.Errorf("%w: negative stall threshold %v", ErrInvalidConfig, o.stallAfter)
+	}
+	if o.warmup < 0 {
+		return fmt.Errorf("%w: negative warmup %v", ErrInvalidConfig, o.warmup)
+	}
+	return nil
+}
+
+// Creates a reservoir limiter admitting rate calls per second on average,
+// with bursts of up to burst calls.
+//
+// The rate may be fractional, e.g. 2.5 calls per second: tokens are refilled
+// one at a time, every 1/rate seconds rounded to the nanosecond. Panics if
+// the rate or burst is not positive, if the rate exceeds a call per
+// nanosecond, or if the options are invalid.
+func NewRateLimiter(rate float64, burst int, opts ...Option) Limiter {
+	return newRateLimiter(rate, time.Second, burst, opts)
+}
+
+// Creates a reservoir limiter admitting rate calls per minute on average,
+// with bursts of up to burst calls.
+//
+// Panics like NewRateLimiter.
+func NewRateLimiterPerMinute(rate float64, burst int, opts ...Option) Limiter {
+	return newRateLimiter(rate, time.Minute, burst, opts)
+}
+
+// Creates a reservoir limiter admitting rate calls per period.
+func newRateLimiter(rate float64, period time.Duration, burst int, opts []Option) Limiter {
+	if !(rate > 0) || burst <= 0 {
+		panic(fmt.Errorf("%w: rate %v with burst %d", ErrInvalidConfig, rate, burst))
+	}
+	refillDuration := math.Round(float64(period) / rate)
+	if refillDuration < 1 {
+		panic(fmt.Errorf("%w: rate %v per %v refills faster than a token per nanosecond", ErrInvalidConfig, rate, period))
 	}
+	return NewReservoirLimiter(burst, time.Duration(refillDuration), opts...)
 }
 
 // Blocks until a token is available or the context is canceled.
 func (l *reservoirLimiter) Limit(ctx context.Context) error {
-	l.mutex.Lock()
-	if l.numConcurrent == 0 {
-		l.numConcurrent = 2
-		go l.manageTokens()
-	} else {
-		l.numConcurrent++
+	_, _, err := l.wait(ctx, 1, PriorityNormal, false, "")
+	return err
+}
+
+// Blocks until a token is available or the context is canceled, and returns
+// how long the call was blocked waiting for it.
+func (l *reservoirLimiter) LimitTimed(ctx context.Context) (time.Duration, error) {
+	waited, _, err := l.wait(ctx, 1, PriorityNormal, false, "")
+	return waited, err
+}
+
+// Blocks until a token is available or the context is canceled, and reports
+// whether the call had to wait for it, and for how long.
+//
+// A call is blocked when no token was available right away, even if one
+// came back before any time elapsed.
+func (l *reservoirLimiter) LimitReport(ctx context.Context) (blocked bool, waited time.Duration, err error) {
+	waited, blocked, err = l.wait(ctx, 1, PriorityNormal, false, "")
+	return blocked, waited, err
+}
+
+// Blocks until a token is available or the context is canceled, going ahead
+// of the waiters with a lower priority.
+func (l *reservoirLimiter) LimitPriority(ctx context.Context, p Priority) error {
+	_, _, err := l.wait(ctx, 1, p, false, "")
+	return err
+}
+
+// Blocks until n tokens are available at once or the context is canceled.
+//
+// Unlike LimitN, the call holds no token while waiting: the tokens stay in the
+// reservoir until all n can be taken together, so a canceled call never
+// holds some of them. Waiters behind it still wait their turn, unless
+// fairness is disabled.
+func (l *reservoirLimiter) LimitNAtomic(ctx context.Context, n int) error {
+	_, _, err := l.wait(ctx, n, PriorityNormal, true, "")
+	return err
+}
+
+// Blocks for at most d until a token is available.
+//
+// Returns ErrTimeout if no token could be obtained in time.
+func (l *reservoirLimiter) LimitWithin(d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	err := l.Limit(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	return err
+}
+
+// Blocks until n tokens are available or the context is canceled.
+//
+// Tokens are handed out as they become available. If the context is canceled,
+// the tokens already taken are given back to the reservoir.
+func (l *reservoirLimiter) LimitN(ctx context.Context, n int) error {
+	_, _, err := l.wait(ctx, n, PriorityNormal, false, "")
+	return err
+}
+
+// Consumes a token if one is immediately available, without blocking.
+//
+// Returns false if no token could be taken right away, in which case no token
+// was consumed.
+func (l *reservoirLimiter) TryLimit() bool {
+	return l.TryLimitN(1)
+}
+
+// Consumes n tokens if they are immediately available, without blocking.
+//
+// The tokens are taken all at once, as a single call: returns false if they
+// could not all be taken right away, in which case no token was consumed.
+func (l *reservoirLimiter) TryLimitN(n int) bool {
+	if n <= 0 {
+		return true
 	}
+	l.mutex.Lock()
+	ok := !l.closed && !l.draining && (l.paused || (!l.fair || l.waiters.Len() == 0) && l.tryTake(n))
+	remaining := l.tokenCount
 	l.mutex.Unlock()
-	select {
-	case <-l.out:
-		l.mutex.Lock()
-		l.numConcurrent--
+	if !ok {
+		l.dropped.Add(1)
+		l.observe(Observer.OnReject)
+		return false
+	}
+	l.admitted.Add(1)
+	l.recordGrant()
+	if l.logger != nil {
+		l.debug("limiters: tokens granted", slog.Int("tokens", n), slog.Int("remaining", remaining), slog.Duration("wait", 0))
+	}
+	return true
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *reservoirLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Consumes up to n tokens among those immediately available, without
+// blocking, and returns how many were taken.
+//
+// No token is taken while other calls are waiting, unless fairness is
+// disabled.
+func (l *reservoirLimiter) LimitUpTo(ctx context.Context, n int) (int, error) {
+	if err := contextError(ctx); err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, nil
+	}
+	l.mutex.Lock()
+	if l.closed {
+		l.mutex.Unlock()
+		return 0, ErrLimiterClosed
+	}
+	if l.draining {
 		l.mutex.Unlock()
+		return 0, ErrLimiterDraining
+	}
+	granted := 0
+	if l.paused {
+		granted = n
+	} else if !l.fair || l.waiters.Len() == 0 {
+		now := l.clock.Now()
+		l.refill(now)
+		granted = min(n, l.tokenCount, l.burstAllowance(now))
+		if granted > 0 {
+			l.take(granted, now)
+			l.throughput.record(now)
+		}
+	}
+	l.mutex.Unlock()
+	if granted == 0 {
+		l.dropped.Add(1)
+		l.observe(Observer.OnReject)
+		return 0, nil
+	}
+	l.admitted.Add(1)
+	l.recordGrant()
+	return granted, nil
+}
+
+// Takes all the tokens immediately available, without blocking, and returns
+// how many were taken.
+//
+// Like LimitUpTo, no token is taken while other calls are waiting, unless
+// fairness is disabled, nor beyond the burst. While paused, as many tokens
+// as the reservoir holds are admitted for free.
+func (l *reservoirLimiter) DrainAvailable() int {
+	n, _ := l.LimitUpTo(context.Background(), l.MaxTokens())
+	return n
+}
+
+// Returns the name of the limiter, empty unless set with WithName.
+func (l *reservoirLimiter) Name() string {
+	return l.name
+}
+
+// Returns a snapshot of the limiter's counters.
+func (l *reservoirLimiter) Stats() Stats {
+	return Stats{
+		Granted:  l.granted.Load(),
+		Canceled: l.canceled.Load(),
+		Waiting:  l.waiting.Load(),
+	}
+}
+
+// Returns the number of non-blocking calls, through TryLimit, Allow,
+// LimitUpTo or Admit, that were admitted.
+func (l *reservoirLimiter) Admitted() uint64 {
+	return l.admitted.Load()
+}
+
+// Returns the number of non-blocking calls that were not admitted.
+func (l *reservoirLimiter) Dropped() uint64 {
+	return l.dropped.Load()
+}
+
+// Returns the fraction of non-blocking calls that were not admitted, zero if
+// there was none.
+func (l *reservoirLimiter) DropRate() float64 {
+	dropped := l.dropped.Load()
+	total := dropped + l.admitted.Load()
+	if total == 0 {
+		return 0
+	}
+	return float64(dropped) / float64(total)
+}
+
+// Returns the number of calls admitted per second over the rate window, set
+// with WithRateWindow, whatever the configured rate.
+//
+// A rate well below the configured one means that demand is low rather than
+// the limiter being the bottleneck.
+func (l *reservoirLimiter) Rate() float64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.throughput.rate(l.clock.Now())
+}
+
+// Returns the estimated q-quantile of how long granted calls to Limit and
+// LimitN waited over the last minute or two, e.g. 0.99 for the 99th
+// percentile.
+//
+// Always returns zero unless the limiter was created WithWaitHistogram.
+func (l *reservoirLimiter) WaitQuantile(q float64) time.Duration {
+	if l.waits == nil {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.waits.quantile(q, l.clock.Now())
+}
+
+// Reports whether wait times are recorded, i.e. whether the limiter was
+// created WithWaitHistogram.
+func (l *reservoirLimiter) recordsWaits() bool {
+	return l.waits != nil
+}
+
+// Returns the number of tokens currently in the reservoir.
+func (l *reservoirLimiter) Available() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.refill(l.clock.Now())
+	return l.tokenCount
+}
+
+// Returns the estimated time until a call would be admitted, zero if a token
+// is available right away.
+func (l *reservoirLimiter) EstimateDelay() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	l.refill(now)
+	if l.waiters.Len() == 0 && l.tokenCount > 0 {
+		return 0
+	}
+	return max(l.nextTokenTime().Sub(now), 0)
+}
+
+// Gives n unused tokens back to the reservoir, dropping those that do not fit.
+func (l *reservoirLimiter) ReturnTokens(n int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed || n <= 0 {
+		return
+	}
+	l.refill(l.clock.Now())
+	l.releaseTokens(n)
+}
+
+// Returns the capacity and refill duration of the reservoir.
+func (l *reservoirLimiter) CurrentRate() (maxTokens int, refillDuration time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.maxTokens, l.refillDuration
+}
+
+// Returns the capacity of the reservoir.
+func (l *reservoirLimiter) MaxTokens() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.maxTokens
+}
+
+// Returns the most tokens a single call may take: the capacity, or the burst
+// if it is lower.
+func (l *reservoirLimiter) maxCallTokens() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.burst > 0 {
+		return min(l.maxTokens, l.burst)
+	}
+	return l.maxTokens
+}
+
+// Returns the time to refill a token.
+func (l *reservoirLimiter) RefillDuration() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.refillDuration
+}
+
+// Changes the capacity and refill rate of the reservoir.
+//
+// Tokens refilled so far are accounted for at the previous rate. If the
+// capacity shrinks below the number of available tokens, the excess tokens are
+// dropped. Waiters keep their place and are served at the new rate, except for
+// those asking for more tokens than the new capacity, which fail with
+// ErrExceedsCapacity.
+func (l *reservoirLimiter) SetRate(maxTokens int, refillDuration time.Duration) error {
+	if maxTokens < 0 || refillDuration <= 0 {
+		return fmt.Errorf("%w: rate of %d tokens every %v", ErrInvalidConfig, maxTokens, refillDuration)
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		return ErrLimiterClosed
+	}
+	now := l.clock.Now()
+	l.refill(now)
+	if l.tokenCount >= l.maxTokens {
+		// The refill period starts now for a reservoir that grows from full.
+		l.lastRefill = now
+	}
+	l.maxTokens = maxTokens
+	l.refillDuration = refillDuration
+	l.nextInterval = l.refillInterval()
+	l.tokenCount = min(l.tokenCount, maxTokens)
+	for elem := l.waiters.Front(); elem != nil; {
+		next := elem.Next()
+		if w := elem.Value.(*waiter); w.n > maxTokens {
+			l.removeWaiter(elem)
+			w.err = ErrExceedsCapacity
+			l.releaseTokens(w.got)
+			w.got = 0
+			close(w.ready)
+		}
+		elem = next
+	}
+	l.distributeTokens()
+	l.stopRefillTicker()
+	if l.needsRefillTicker() {
+		l.startRefillTicker()
+	}
+	return nil
+}
+
+// Refills the reservoir to its capacity, discarding the progress of the
+// current refill period.
+//
+// Waiters are served from the refilled tokens, in order, and the refill
+// ticker only keeps running if some of them are left waiting.
+func (l *reservoirLimiter) Reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		return
+	}
+	l.lastRefill = l.clock.Now()
+	l.nextInterval = l.refillInterval()
+	l.overdraft = 0
+	l.releaseTokens(l.maxTokens)
+	if !l.needsRefillTicker() {
+		l.stopRefillTicker()
+	}
+}
+
+// Stops admitting new calls, which fail with ErrLimiterDraining, while calls
+// already waiting keep being served.
+//
+// Waiting calls can still give up when their context is canceled: their
+// tokens then go to the next waiters. Drain is idempotent.
+func (l *reservoirLimiter) Drain() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.draining = true
+}
+
+// Stops the refill ticker and makes pending and future calls fail with
+// ErrLimiterClosed.
+//
+// Close is idempotent and always returns nil.
+func (l *reservoirLimiter) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
 		return nil
-	case <-ctx.Done():
+	}
+	l.closed = true
+	close(l.done)
+	if l.unregister != nil {
+		l.unregister()
+	}
+	for elem := l.waiters.Front(); elem != nil; elem = elem.Next() {
+		w := elem.Value.(*waiter)
+		w.err = ErrLimiterClosed
+		close(w.ready)
+	}
+	l.waiters.Init()
+	l.prioritized = 0
+	clear(l.classes)
+	l.stopRefillTicker()
+	return nil
+}
+
+// Takes n tokens from the reservoir, queuing behind other waiters if there
+// are not enough of them.
+//
+// Returns how long the call waited, and whether it was blocked in the queue
+// rather than served right away.
+func (l *reservoirLimiter) wait(ctx context.Context, n int, p Priority, whole bool, class string) (waited time.Duration, blocked bool, err error) {
+	waited, blocked, _, err = l.acquire(ctx, n, p, whole, class)
+	return waited, blocked, err
+}
+
+// Same as wait, also reporting whether the call was admitted for free, while
+// paused, without taking tokens from the reservoir.
+func (l *reservoirLimiter) acquire(ctx context.Context, n int, p Priority, whole bool, class string) (waited time.Duration, blocked, free bool, err error) {
+	if n <= 0 {
+		return 0, false, false, nil
+	}
+	if err := contextError(ctx); err != nil {
+		// Do not even compete for tokens.
+		l.recordCancel()
+		if l.logger != nil {
+			l.debug("limiters: wait canceled", slog.Int("tokens", n), slog.Duration("wait", 0), slog.Any("error", err))
+		}
+		return 0, false, false, err
+	}
+	l.mutex.Lock()
+	if l.closed {
+		l.mutex.Unlock()
+		l.recordCancel()
+		return 0, false, false, ErrLimiterClosed
+	}
+	if l.draining {
+		l.mutex.Unlock()
+		l.recordCancel()
+		return 0, false, false, ErrLimiterDraining
+	}
+	if l.paused {
+		l.mutex.Unlock()
+		l.recordGrant()
+		return 0, false, true, nil
+	}
+	caller := ""
+	if l.callerCap > 0 {
+		caller, _ = CallerFromContext(ctx)
+	}
+	if n > l.maxTokens || (l.burst > 0 && n > l.burst) || (caller != "" && n > l.callerCap) {
+		l.mutex.Unlock()
+		l.recordCancel()
+		return 0, false, false, ErrExceedsCapacity
+	}
+	if (!l.fair || l.waiters.Len() == 0) && l.tryTakeFor(caller, n) {
+		remaining := l.tokenCount
+		if l.waits != nil {
+			l.waits.record(0, l.clock.Now())
+		}
+		l.mutex.Unlock()
+		l.recordGrant()
+		if l.logger != nil {
+			l.debug("limiters: tokens granted", slog.Int("tokens", n), slog.Int("remaining", remaining), slog.Duration("wait", 0))
+		}
+		return 0, false, false, nil
+	}
+	start := l.clock.Now()
+	w := &waiter{n: n, ready: make(chan struct{}), priority: p, since: start, whole: whole, caller: caller}
+	if l.edf {
+		w.deadline, _ = ctx.Deadline()
+	}
+	if l.weights != nil {
+		w.class = l.trafficClass(class)
+	}
+	elem := l.pushWaiter(w)
+	l.distributeTokens()
+	l.startRefillTicker()
+	l.markThrottled(start)
+	l.mutex.Unlock()
+	l.notifyTransitions()
+
+	l.waiting.Add(1)
+	l.observe(Observer.OnWaitStart)
+	err = l.await(ctx, w, elem, l.waitTimeout)
+	waited = l.clock.Now().Sub(start)
+	l.waiting.Add(-1)
+	l.observe(Observer.OnWaitEnd)
+	if err != nil {
+		l.recordCancel()
+		if l.logger != nil {
+			l.debug("limiters: wait canceled", slog.Int("tokens", n), slog.Duration("wait", waited), slog.Any("error", err))
+		}
+		return waited, true, false, err
+	}
+	if l.waits != nil {
 		l.mutex.Lock()
-		l.numConcurrent--
+		l.waits.record(waited, l.clock.Now())
 		l.mutex.Unlock()
-		return ctx.Err()
+	}
+	l.recordGrant()
+	if l.logger != nil {
+		l.debug("limiters: tokens granted", slog.Int("tokens", n), slog.Duration("wait", waited))
+	}
+	return waited, true, w.free, nil
+}
+
+// Logs an event at debug level.
+//
+// Callers check that a logger is set first, so that nothing is computed
+// otherwise.
+func (l *reservoirLimiter) debug(msg string, attrs ...slog.Attr) {
+	l.logger.LogAttrs(context.Background(), slog.LevelDebug, msg, append(attrs, slog.String("limiter", l.name))...)
+}
+
+// Blocks until the waiter is served, the context is canceled or the timeout
+// elapses, in which cases the waiter leaves the queue and gives its tokens
+// back.
+//
+// A zero timeout, or one beyond the deadline of the context, is ignored.
+func (l *reservoirLimiter) await(ctx context.Context, w *waiter, elem *list.Element, timeout time.Duration) error {
+	var expired <-chan time.Time
+	if deadline, ok := ctx.Deadline(); timeout > 0 && (!ok || deadline.Sub(l.clock.Now()) > timeout) {
+		ticker := l.clock.NewTicker(timeout)
+		defer ticker.Stop()
+		expired = ticker.C()
+	}
+	select {
+	case <-w.ready:
+		return w.err
+	case <-ctx.Done():
+		l.leave(w, elem)
+		return contextError(ctx)
+	case <-expired:
+		l.leave(w, elem)
+		return ErrWaitTimeout
+	}
+}
+
+// Takes a waiter that gave up out of the queue, giving its tokens back.
+func (l *reservoirLimiter) leave(w *waiter, elem *list.Element) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	select {
+	case <-w.ready:
+		// Served or closed concurrently.
+	default:
+		l.removeWaiter(elem)
+	}
+	l.releaseTokens(w.got)
+	if !l.needsRefillTicker() {
+		// No one is waiting anymore: free resources.
+		l.stopRefillTicker()
+	}
+}
+
+// Takes n tokens if the reservoir holds enough of them.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) tryTake(n int) bool {
+	now := l.clock.Now()
+	l.refill(now)
+	if l.tokenCount < n || l.burstAllowance(now) < n {
+		return false
+	}
+	l.take(n, now)
+	l.throughput.record(now)
+	return true
+}
+
+// Takes n tokens for the caller if the reservoir holds enough of them and
+// the caller is under its cap.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) tryTakeFor(caller string, n int) bool {
+	if caller == "" {
+		return l.tryTake(n)
+	}
+	now := l.clock.Now()
+	if !l.underCallerCap(caller, n, now) || !l.tryTake(n) {
+		return false
+	}
+	l.chargeCaller(caller, n, now)
+	return true
+}
+
+// Counts an admitted call.
+func (l *reservoirLimiter) recordGrant() {
+	l.granted.Add(1)
+	l.observe(Observer.OnGrant)
+}
+
+// Counts a call that gave up waiting.
+func (l *reservoirLimiter) recordCancel() {
+	l.canceled.Add(1)
+	l.observe(Observer.OnReject)
+}
+
+// Removes n tokens from the reservoir.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) take(n int, now time.Time) {
+	if l.tokenCount == l.maxTokens {
+		// The refill period starts when the reservoir stops being full.
+		l.lastRefill = now
+		if l.warming(now) {
+			l.nextInterval = l.refillInterval()
+		}
+	}
+	l.tokenCount -= n
+	if l.burst > 0 {
+		l.windowGrants += n
+	}
+	if l.tokenCount == 0 {
+		if l.logger != nil {
+			l.debug("limiters: reservoir emptied")
+		}
+	}
+}
+
+// Returns how many tokens may still be handed out in the current burst
+// window, starting a new window if the current one is over.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) burstAllowance(now time.Time) int {
+	if l.burst == 0 {
+		return l.tokenCount
+	}
+	if now.Sub(l.windowStart) >= l.refillDuration {
+		l.windowStart = now
+		l.windowGrants = 0
+	}
+	return l.burst - l.windowGrants
+}
+
+// Adds the tokens refilled since the last refill.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) refill(now time.Time) {
+	if l.tokenCount >= l.maxTokens {
+		return
+	}
+	if l.jitter == 0 && !l.warming(l.lastRefill) {
+		period := l.refillPeriod()
+		elapsed := now.Sub(l.lastRefill)
+		if elapsed < period {
+			return
+		}
+		n := elapsed / period
+		l.lastRefill = l.lastRefill.Add(n * period)
+		l.addRefilled(int(min(n, time.Duration(l.maxTokens))) * l.batch)
+		return
+	}
+	n := 0
+	for l.tokenCount+n < l.maxTokens && now.Sub(l.lastRefill) >= l.nextInterval {
+		l.lastRefill = l.lastRefill.Add(l.nextInterval)
+		l.nextInterval = l.refillInterval()
+		n += l.batch
+	}
+	l.addRefilled(n)
+}
+
+// Adds n refilled tokens to the reservoir.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) addRefilled(n int) {
+	if n == 0 {
+		return
+	}
+	if l.logger != nil {
+		n = min(n, l.maxTokens-l.tokenCount)
+		l.debug("limiters: reservoir refilled", slog.Int("tokens", n), slog.Int("available", l.tokenCount+n))
+	}
+	l.releaseTokens(n)
+}
+
+// Returns the time between two refills, during which a batch of tokens is
+// refilled.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) refillPeriod() time.Duration {
+	return l.refillDuration * time.Duration(l.batch)
+}
+
+// Returns the interval until the next refill, from the last one: the refill
+// period, stretched during the warmup and randomized within the jitter
+// fraction.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) refillInterval() time.Duration {
+	period := l.refillPeriod()
+	if l.warming(l.lastRefill) {
+		elapsed := l.warmup - l.warmupEnd.Sub(l.lastRefill)
+		period = time.Duration(float64(period) / l.warmupFraction(float64(elapsed)/float64(l.warmup)))
+	}
+	if l.jitter == 0 {
+		return period
+	}
+	factor := 1 + l.jitter*(2*l.random()-1)
+	return time.Duration(float64(period) * factor)
+}
+
+// Lowest fraction of the rate during a warmup.
+const minWarmupFraction = 0.01
+
+// Fraction of the configured rate at the start of a linear warmup.
+const warmupStart = 0.1
+
+// Default warmup curve, growing linearly from warmupStart to 1.
+func linearWarmup(progress float64) float64 {
+	return warmupStart + (1-warmupStart)*progress
+}
+
+// Reports whether the rate is still ramping up at the given time.
+func (l *reservoirLimiter) warming(t time.Time) bool {
+	return t.Before(l.warmupEnd)
+}
+
+// Returns the fraction of the rate given by the warmup curve at the given
+// progress, clamped to [minWarmupFraction, 1].
+func (l *reservoirLimiter) warmupFraction(progress float64) float64 {
+	return min(max(l.warmupCurve(min(max(progress, 0), 1)), minWarmupFraction), 1)
+}
+
+// Returns the time at which a token would be available for a new waiter.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) nextTokenTime() time.Time {
+	missing := 1 - l.tokenCount
+	for elem := l.waiters.Front(); elem != nil; elem = elem.Next() {
+		w := elem.Value.(*waiter)
+		missing += w.n - w.got
+	}
+	if missing <= 0 {
+		return l.lastRefill
+	}
+	batches := (missing + l.batch - 1) / l.batch
+	return l.lastRefill.Add(l.nextInterval + time.Duration(batches-1)*l.refillPeriod())
+}
+
+// Hands available tokens to waiters, by priority and then in order of
+// arrival.
+//
+// In fair mode, the next waiter accumulates tokens until it is served, and
+// the others wait behind it. Otherwise, waiters are served as soon as enough
+// tokens are available for them, overtaking those asking for more.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) distributeTokens() {
+	if l.waiters.Len() == 0 {
+		// Keep the uncontended path away from the clock.
+		return
+	}
+	now := l.clock.Now()
+	for l.tokenCount > 0 {
+		allowance := min(l.tokenCount, l.burstAllowance(now))
+		if allowance == 0 {
+			// Burst exhausted, wait for the next window.
+			return
+		}
+		elem := l.nextWaiter(now, allowance)
+		if elem == nil {
+			return
+		}
+		w := elem.Value.(*waiter)
+		if w.peek {
+			l.removeWaiter(elem)
+			close(w.ready)
+			continue
+		}
+		if w.whole && allowance < w.n {
+			// Leave the tokens in the reservoir until there are enough.
+			return
+		}
+		taken := min(allowance, w.n-w.got)
+		l.take(taken, now)
+		l.progress(now)
+		w.got += taken
+		if w.got < w.n {
+			return
+		}
+		l.chargeClass(w)
+		l.chargeCaller(w.caller, w.n, now)
+		l.removeWaiter(elem)
+		l.throughput.record(now)
+		close(w.ready)
+	}
+}
+
+// Returns the waiter to hand tokens to next, nil if there is none.
+//
+// A waiter already handed some tokens goes first. Otherwise, the waiter with
+// the highest priority is chosen, then with weights the one of the class
+// served the least for its weight, with EDF the one with the nearest
+// deadline, and the first to arrive among equals. When not fair, only waiters
+// asking for at most allowance tokens are considered.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) nextWaiter(now time.Time, allowance int) *list.Element {
+	var (
+		best     *list.Element
+		priority Priority
+	)
+	for elem := l.waiters.Front(); elem != nil; elem = elem.Next() {
+		w := elem.Value.(*waiter)
+		if w.got > 0 {
+			return elem
+		}
+		if !l.fair && w.n > allowance {
+			continue
+		}
+		if !l.underCallerCap(w.caller, w.n, now) {
+			// Over its cap, let the others go first.
+			continue
+		}
+		if l.prioritized == 0 && l.aging == 0 && !l.edf && l.weights == nil {
+			// Everyone has the same priority.
+			return elem
+		}
+		p := l.effectivePriority(w, now)
+		if best == nil || p > priority || (p == priority && l.before(w, best.Value.(*waiter))) {
+			best, priority = elem, p
+		}
+	}
+	return best
+}
+
+// Reports whether waiter a goes before waiter b of the same priority, which
+// arrived first: with weights if its class has a lower pass, then with EDF
+// if its deadline is nearer.
+func (l *reservoirLimiter) before(a, b *waiter) bool {
+	if a.class != nil && b.class != nil && a.class.pass != b.class.pass {
+		return a.class.pass < b.class.pass
+	}
+	return l.edf && earlier(a.deadline, b.deadline)
+}
+
+// Reports whether deadline a is strictly nearer than b, the zero time
+// meaning no deadline.
+func earlier(a, b time.Time) bool {
+	return !a.IsZero() && (b.IsZero() || a.Before(b))
+}
+
+// Returns the priority of the waiter, raised by one for each aging period
+// spent waiting.
+func (l *reservoirLimiter) effectivePriority(w *waiter, now time.Time) Priority {
+	if l.aging == 0 {
+		return w.priority
+	}
+	return w.priority + Priority(now.Sub(w.since)/l.aging)
+}
+
+// Queues a waiter.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) pushWaiter(w *waiter) *list.Element {
+	if w.priority != PriorityNormal {
+		l.prioritized++
+	}
+	if w.class != nil {
+		w.class.waiting++
+	}
+	if l.waiters.Len() == 0 {
+		l.progress(w.since)
+	}
+	return l.waiters.PushBack(w)
+}
+
+// Removes a waiter from the queue.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) removeWaiter(elem *list.Element) {
+	w := elem.Value.(*waiter)
+	if w.priority != PriorityNormal {
+		l.prioritized--
+	}
+	if w.class != nil {
+		l.leaveClass(w.class)
+	}
+	l.waiters.Remove(elem)
+}
+
+// Gives tokens back to the reservoir, dropping those that do not fit once
+// the overdraft of LimitOptimistic is paid.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) releaseTokens(n int) {
+	if l.overdraft > 0 {
+		paid := min(n, l.overdraft)
+		l.overdraft -= paid
+		n -= paid
+	}
+	l.tokenCount = min(l.tokenCount+n, l.maxTokens)
+	l.distributeTokens()
+}
+
+// Starts the refill ticker if it is not running yet.
+//
+// The first tick happens when the next token is due, and the next ones
+// every refill duration. With scheduled refills, a timer is armed anew for
+// each refill instead.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) startRefillTicker() {
+	if l.stopRefill != nil {
+		if l.scheduled {
+			// The new waiter may be served before the timer fires.
+			l.rearmRefillTimer()
+		}
+		return
+	}
+	l.startWatchdog()
+	if l.scheduled {
+		l.stopRefill = l.stopRefillTimer
+		l.armRefillTimer()
+		return
+	}
+	first := l.nextRefillDelay()
+	if s, ok := l.clock.(scheduler); ok {
+		l.stopRefill = s.schedule(first, l.refillPeriod(), func() { l.refillTick() })
+		return
+	}
+	stop := make(chan struct{})
+	l.stopRefill = func() { close(stop) }
+	if l.alwaysOn {
+		go l.refillAlwaysOn(stop)
+		return
+	}
+	go l.refillTokens(l.clock.NewTicker(first), first, l.refillPeriod(), stop)
+}
+
+// Returns the time until the next tokens are due, at most a refill period.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) nextRefillDelay() time.Duration {
+	period := l.refillPeriod()
+	if l.tokenCount >= l.maxTokens {
+		return period
+	}
+	d := l.lastRefill.Add(l.nextInterval).Sub(l.clock.Now())
+	if d <= 0 || d > period {
+		return period
+	}
+	return d
+}
+
+// Stops the refill ticker if it is running.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) stopRefillTicker() {
+	if l.stopRefill == nil {
+		return
+	}
+	l.stopRefill()
+	l.stopRefill = nil
+	l.stopWatchdog()
+}
+
+// Refills missing tokens on each tick, until no one is waiting anymore.
+//
+// The ticker first ticks after first, then it is replaced by a ticker of the
+// given period.
+func (l *reservoirLimiter) refillTokens(ticker Ticker, first, period time.Duration, stop <-chan struct{}) {
+	defer func() { ticker.Stop() }()
+	for {
+		select {
+		case <-ticker.C():
+		case <-stop:
+			return
+		}
+		if first != period {
+			ticker.Stop()
+			ticker = l.clock.NewTicker(period)
+			first = period
+		}
+		if !l.refillTick() {
+			return
+		}
 	}
 }
 
-// Manages the tokens in the reservoir (distribution and refill).
-func (l *reservoirLimiter) manageTokens() {
-	tokenCount := l.maxTokens
+// Refills missing tokens until the limiter is closed.
+//
+// The ticker is armed anew after each tick, for when the next tokens are due:
+// taking tokens from a full reservoir restarts the refill period, which a
+// ticker of fixed phase would miss by up to a period.
+func (l *reservoirLimiter) refillAlwaysOn(stop <-chan struct{}) {
 	for {
-		switch tokenCount {
-		case 0:
-			<-l.in
-			tokenCount++
-		case l.maxTokens:
-			l.mutex.Lock()
-			if l.numConcurrent == 1 {
-				// No one is waiting: free resources.
-				l.numConcurrent = 0
-				l.mutex.Unlock()
-				return
-			}
-			l.mutex.Unlock()
-			l.out <- token{}
-			tokenCount--
-			go l.refillTokens()
-		default:
-			select {
-			case <-l.in:
-				tokenCount++
-			case l.out <- token{}:
-				tokenCount--
-			}
-		}
-	}
-}
-
-// Starts a ticker to refill missing tokens.
-func (l *reservoirLimiter) refillTokens() {
-	ticker := time.NewTicker(l.refillDuration)
-	for range ticker.C {
+		l.mutex.Lock()
+		d := l.nextRefillDelay()
+		l.mutex.Unlock()
+		ticker := l.clock.NewTicker(d)
 		select {
-		case l.in <- token{}:
-			// Token refilled, wait for next tick.
-		default:
-			// Reservoir full, stop ticking.
+		case <-ticker.C():
+			ticker.Stop()
+		case <-stop:
 			ticker.Stop()
 			return
 		}
+		l.refillTick()
+	}
+}
+
+// Refills missing tokens, stopping the ticker if no one is waiting anymore.
+//
+// Returns false once the ticker is stopped.
+func (l *reservoirLimiter) refillTick() bool {
+	defer l.notifyTransitions()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	l.refill(now)
+	if l.callerCap > 0 {
+		// Capped callers may be served from tokens already there.
+		l.distributeTokens()
+	}
+	l.checkRecovered(now)
+	if !l.needsRefillTicker() {
+		// Every waiter was served, stop ticking.
+		l.stopRefillTicker()
+		return false
 	}
+	return true
+}
+
+// Reports whether the refill ticker must keep running: while calls are
+// waiting, until a throttled limiter is seen to recover, and until the
+// limiter is closed if it is always on.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) needsRefillTicker() bool {
+	return l.waiters.Len() > 0 || l.throttled || (l.alwaysOn && !l.closed)
 }
@@ -1,3 +1,1860 @@
 package limiters_test
 
-// TODO implement tests
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestReservoirLimiterAvailable(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(3, time.Hour)
+	counter := limiter.(limiters.TokenCounter)
+	if got := counter.Available(); got != 3 {
+		t.Fatalf("expected 3 tokens, got %d", got)
+	}
+	if err := limiter.LimitN(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected 1 token, got %d", got)
+	}
+}
+
+func TestReservoirLimiterAvailableConcurrent(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(10, time.Millisecond)
+	counter := limiter.(limiters.TokenCounter)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Limit(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if got := counter.Available(); got < 0 || got > 10 {
+				t.Errorf("available tokens out of range: %d", got)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestReservoirLimiterTryLimitWhileAvailable(t *testing.T) {
+	const tokens = 50
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(tokens, time.Second, limiters.WithClock(clock))
+	counter := limiter.(limiters.TokenCounter)
+	for counter.Available() > 0 {
+		if !limiter.TryLimit() {
+			t.Fatalf("expected TryLimit to succeed with %d tokens available", counter.Available())
+		}
+	}
+
+	// Concurrent calls stopping at their first failure take every token.
+	clock.Advance(tokens * time.Second)
+	var granted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for limiter.TryLimit() {
+				granted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got, left := granted.Load(), counter.Available(); got != tokens || left != 0 {
+		t.Fatalf("expected %d grants and no token left, got %d grants and %d tokens", tokens, got, left)
+	}
+}
+
+func TestReservoirLimiterReturnedTokensNotLost(t *testing.T) {
+	const tokens = 100
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(tokens, time.Second, limiters.WithClock(clock))
+	counter := limiter.(limiters.TokenCounter)
+	if err := limiter.LimitN(context.Background(), tokens); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens returned concurrently with other calls all make it back.
+	var wg sync.WaitGroup
+	for i := 0; i < tokens; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			limiter.(limiters.TokenReturner).ReturnTokens(1)
+		}()
+		go func() {
+			defer wg.Done()
+			counter.Available()
+			limiter.(limiters.StatsReporter).Stats()
+		}()
+	}
+	wg.Wait()
+	if got := counter.Available(); got != tokens {
+		t.Fatalf("expected the %d returned tokens to be available, got %d", tokens, got)
+	}
+
+	// So do the tokens gathered by a call giving up.
+	if err := limiter.LimitN(context.Background(), tokens-2); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.LimitN(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if got := counter.Available(); got != 2 {
+		t.Fatalf("expected the tokens of the canceled call to be given back, got %d", got)
+	}
+}
+
+func TestReservoirLimiterClose(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour)
+	closer := limiter.(io.Closer)
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error)
+	for i := 0; i < 3; i++ {
+		go func() { errs <- limiter.Limit(context.Background()) }()
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := closer.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 3; i++ {
+		if err := <-errs; !errors.Is(err, limiters.ErrLimiterClosed) {
+			t.Fatalf("expected ErrLimiterClosed for pending call, got %v", err)
+		}
+	}
+	if err := limiter.Limit(context.Background()); !errors.Is(err, limiters.ErrLimiterClosed) {
+		t.Fatalf("expected ErrLimiterClosed after close, got %v", err)
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected TryLimit to fail after close")
+	}
+}
+
+func TestReservoirLimiterUseAfterClose(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second, limiters.WithClock(clock), limiters.WithInitialTokens(0))
+	limiter.(io.Closer).Close()
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 1000; i++ {
+		if err := limiter.Limit(context.Background()); !errors.Is(err, limiters.ErrLimiterClosed) {
+			t.Fatalf("expected ErrLimiterClosed, got %v", err)
+		}
+		if err := limiter.(limiters.ReadyWaiter).WaitReady(context.Background()); !errors.Is(err, limiters.ErrLimiterClosed) {
+			t.Fatalf("expected ErrLimiterClosed, got %v", err)
+		}
+		if _, err := limiter.(limiters.Reserver).Reserve(context.Background()); !errors.Is(err, limiters.ErrLimiterClosed) {
+			t.Fatalf("expected ErrLimiterClosed, got %v", err)
+		}
+		if err := limiter.(limiters.RateSetter).SetRate(2, time.Second); !errors.Is(err, limiters.ErrLimiterClosed) {
+			t.Fatalf("expected ErrLimiterClosed, got %v", err)
+		}
+		limiter.(limiters.ReadySignaler).Ready()
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("expected no goroutine to be started after close, got %d more", after-before)
+	}
+	if active := clock.ActiveTickers(); active != 0 {
+		t.Fatalf("expected no ticker to be started after close, got %d", active)
+	}
+}
+
+func TestReservoirLimiterInitialTokens(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(5, time.Hour, limiters.WithInitialTokens(2), limiters.WithName("test"))
+	if got := limiter.(limiters.TokenCounter).Available(); got != 2 {
+		t.Fatalf("expected 2 tokens, got %d", got)
+	}
+}
+
+func TestReservoirLimiterColdStart(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(3, time.Second, limiters.WithClock(clock), limiters.WithInitialTokens(0))
+	counter := limiter.(limiters.TokenCounter)
+	if limiter.TryLimit() {
+		t.Fatal("expected an empty reservoir at startup")
+	}
+
+	done := make(chan error)
+	go func() { done <- limiter.Limit(context.Background()) }()
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	eventually(t, func() bool { return clock.ActiveTickers() == 0 })
+
+	clock.Advance(5 * time.Second)
+	if got := counter.Available(); got != 3 {
+		t.Fatalf("expected the reservoir to ramp up to full, got %d", got)
+	}
+}
+
+func TestReservoirLimiterWarmStart(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(3, time.Second, limiters.WithClock(clock), limiters.WithInitialTokens(3))
+	if err := limiter.LimitN(context.Background(), 3); err != nil {
+		t.Fatalf("expected a full reservoir at startup, got %v", err)
+	}
+	if active := clock.ActiveTickers(); active != 0 {
+		t.Fatalf("expected no ticker without waiters, got %d", active)
+	}
+}
+
+func TestReservoirLimiterInvalidOptions(t *testing.T) {
+	for _, n := range []int{-1, 6} {
+		_, err := limiters.NewReservoirLimiterWithError(5, time.Second, limiters.WithInitialTokens(n))
+		if !errors.Is(err, limiters.ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig for %d initial tokens, got %v", n, err)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected NewReservoirLimiter to panic on invalid options")
+		}
+	}()
+	limiters.NewReservoirLimiter(5, time.Second, limiters.WithInitialTokens(6))
+}
+
+func TestReservoirLimiterRefillWithFakeClock(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(3, time.Second, limiters.WithClock(clock))
+	counter := limiter.(limiters.TokenCounter)
+	if err := limiter.LimitN(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected the reservoir to be empty")
+	}
+
+	for want := 1; want <= 3; want++ {
+		clock.Advance(time.Second)
+		eventually(t, func() bool { return counter.Available() == want })
+	}
+	eventually(t, func() bool { return clock.ActiveTickers() == 0 })
+
+	clock.Advance(time.Second)
+	if got := counter.Available(); got != 3 {
+		t.Fatalf("expected the reservoir to stay full, got %d tokens", got)
+	}
+}
+
+func TestReservoirLimiterCanceledWaitersReleaseResources(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second, limiters.WithClock(clock))
+	if !limiter.TryLimit() {
+		t.Fatal("expected a token")
+	}
+	baseline := runtime.NumGoroutine()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2000; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Limit(ctx); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+		}()
+		go cancel()
+	}
+	wg.Wait()
+
+	eventually(t, func() bool { return runtime.NumGoroutine() <= baseline })
+	if active := clock.ActiveTickers(); active != 0 {
+		t.Fatalf("expected no active ticker, got %d", active)
+	}
+
+	// Refill keeps going while idle.
+	clock.Advance(time.Second)
+	if !limiter.TryLimit() {
+		t.Fatal("expected the token to be refilled")
+	}
+}
+
+// Observer counting the events it receives.
+type countingObserver struct {
+	grants, rejects, waiting atomic.Int64
+}
+
+func (o *countingObserver) OnGrant()     { o.grants.Add(1) }
+func (o *countingObserver) OnReject()    { o.rejects.Add(1) }
+func (o *countingObserver) OnWaitStart() { o.waiting.Add(1) }
+func (o *countingObserver) OnWaitEnd()   { o.waiting.Add(-1) }
+
+func TestReservoirLimiterExceedsCapacityRejected(t *testing.T) {
+	observer := &countingObserver{}
+	limiter := limiters.NewReservoirLimiter(2, time.Hour, limiters.WithObserver(observer))
+
+	if err := limiter.LimitN(context.Background(), 3); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity, got %v", err)
+	}
+	if got := observer.rejects.Load(); got != 1 {
+		t.Fatalf("expected the call to be observed as rejected, got %d rejects", got)
+	}
+	if got := limiter.(limiters.StatsReporter).Stats().Canceled; got != 1 {
+		t.Fatalf("expected the call to be counted as canceled, got %d", got)
+	}
+}
+
+func TestReservoirLimiterStats(t *testing.T) {
+	observer := &countingObserver{}
+	limiter := limiters.NewReservoirLimiter(2, time.Hour, limiters.WithObserver(observer))
+	reporter := limiter.(limiters.StatsReporter)
+
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !limiter.TryLimit() {
+		t.Fatal("expected a token")
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected the reservoir to be empty")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- limiter.Limit(ctx) }()
+	eventually(t, func() bool { return reporter.Stats().Waiting == 1 })
+	if observer.waiting.Load() != 1 {
+		t.Fatalf("expected the observer to see 1 waiting call, got %d", observer.waiting.Load())
+	}
+	cancel()
+	<-done
+
+	want := limiters.Stats{Granted: 2, Canceled: 1, Waiting: 0}
+	if got := reporter.Stats(); got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if observer.grants.Load() != 2 || observer.rejects.Load() != 2 || observer.waiting.Load() != 0 {
+		t.Fatalf("unexpected observer counts: %d grants, %d rejects, %d waiting",
+			observer.grants.Load(), observer.rejects.Load(), observer.waiting.Load())
+	}
+}
+
+func TestReservoirLimiterStatsConcurrent(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(50, time.Millisecond)
+	reporter := limiter.(limiters.StatsReporter)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			limiter.Limit(context.Background())
+		}()
+		go func() {
+			defer wg.Done()
+			reporter.Stats()
+		}()
+	}
+	wg.Wait()
+	if got := reporter.Stats().Granted; got != 100 {
+		t.Fatalf("expected 100 grants, got %d", got)
+	}
+}
+
+func TestReservoirLimiterSetRate(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(10, time.Second, limiters.WithClock(clock))
+	setter := limiter.(limiters.RateSetter)
+	counter := limiter.(limiters.TokenCounter)
+
+	if err := setter.SetRate(4, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got := counter.Available(); got != 4 {
+		t.Fatalf("expected excess tokens to be dropped, got %d", got)
+	}
+	if err := limiter.LimitN(context.Background(), 5); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity above the new capacity, got %v", err)
+	}
+	if err := limiter.LimitN(context.Background(), 4); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() { done <- limiter.Limit(context.Background()) }()
+	eventually(t, func() bool { return limiter.(limiters.StatsReporter).Stats().Waiting == 1 })
+
+	if err := setter.SetRate(4, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("expected the waiter to be served at the new rate, got %v", err)
+	}
+}
+
+func TestReservoirLimiterSetRateShrinkMidFlight(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(100, 10*time.Millisecond, limiters.WithClock(clock))
+	counter := limiter.(limiters.TokenCounter)
+	if err := limiter.LimitN(context.Background(), 50); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := limiter.(limiters.RateSetter).SetRate(10, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if got := counter.Available(); got != 10 {
+		t.Fatalf("expected the excess tokens to be dropped at once, got %d", got)
+	}
+	for i := 0; i < 10; i++ {
+		if !limiter.TryLimit() {
+			t.Fatalf("expected token %d to be available", i)
+		}
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected the new capacity to be enforced")
+	}
+
+	done := make(chan error)
+	go func() { done <- limiter.Limit(context.Background()) }()
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+	for i := 0; i < 200; i++ {
+		clock.Advance(10 * time.Millisecond)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// The refill stops once the reservoir is full at the new capacity.
+	eventually(t, func() bool { return clock.ActiveTickers() == 0 })
+	clock.Advance(time.Hour)
+	if got := counter.Available(); got != 10 {
+		t.Fatalf("expected the reservoir to refill up to the new capacity, got %d", got)
+	}
+}
+
+func TestReservoirLimiterSetRateFailsOversizedWaiters(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(4, time.Hour, limiters.WithInitialTokens(0))
+	done := make(chan error)
+	go func() { done <- limiter.LimitN(context.Background(), 3) }()
+	eventually(t, func() bool { return limiter.(limiters.StatsReporter).Stats().Waiting == 1 })
+
+	if err := limiter.(limiters.RateSetter).SetRate(2, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity, got %v", err)
+	}
+}
+
+func TestReservoirLimiterSetRateInvalid(t *testing.T) {
+	setter := limiters.NewReservoirLimiter(4, time.Second).(limiters.RateSetter)
+	for _, d := range []time.Duration{0, -time.Second} {
+		if err := setter.SetRate(4, d); !errors.Is(err, limiters.ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig for %v, got %v", d, err)
+		}
+	}
+	if err := setter.SetRate(-1, time.Second); !errors.Is(err, limiters.ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig for a negative capacity, got %v", err)
+	}
+}
+
+func TestReservoirLimiterConfigurable(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(4, time.Second)
+	config := limiter.(limiters.Configurable)
+	if got, want := config.MaxTokens(), 4; got != want {
+		t.Errorf("expected %d max tokens, got %d", want, got)
+	}
+	if got, want := config.RefillDuration(), time.Second; got != want {
+		t.Errorf("expected a refill duration of %v, got %v", want, got)
+	}
+
+	if err := limiter.(limiters.RateSetter).SetRate(10, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := config.MaxTokens(), 10; got != want {
+		t.Errorf("expected %d max tokens after SetRate, got %d", want, got)
+	}
+	if got, want := config.RefillDuration(), time.Minute; got != want {
+		t.Errorf("expected a refill duration of %v after SetRate, got %v", want, got)
+	}
+}
+
+func TestReservoirLimiterBurst(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(10, time.Second, limiters.WithClock(clock), limiters.WithBurst(3))
+	counter := limiter.(limiters.TokenCounter)
+
+	for window := 0; window < 3; window++ {
+		granted := 0
+		for limiter.TryLimit() {
+			granted++
+		}
+		if granted != 3 {
+			t.Fatalf("expected 3 grants in window %d, got %d", window, granted)
+		}
+		clock.Advance(time.Second)
+	}
+	// 9 tokens were taken and 3 were refilled meanwhile.
+	if got := counter.Available(); got != 4 {
+		t.Fatalf("expected 4 tokens in the reservoir, got %d", got)
+	}
+	if err := limiter.LimitN(context.Background(), 4); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity above the burst, got %v", err)
+	}
+}
+
+func TestReservoirLimiterBurstWaiters(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(10, time.Second, limiters.WithClock(clock), limiters.WithBurst(2))
+	reporter := limiter.(limiters.StatsReporter)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.Limit(context.Background())
+		}()
+	}
+	eventually(t, func() bool { return reporter.Stats().Granted == 2 })
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+	for want := uint64(4); want <= 6; want += 2 {
+		clock.Advance(time.Second)
+		eventually(t, func() bool { return reporter.Stats().Granted == want })
+	}
+	wg.Wait()
+}
+
+func TestReservoirLimiterInvalidBurst(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		_, err := limiters.NewReservoirLimiterWithError(5, time.Second, limiters.WithBurst(n))
+		if !errors.Is(err, limiters.ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig for a burst of %d, got %v", n, err)
+		}
+	}
+}
+
+func TestReservoirLimiterJitter(t *testing.T) {
+	const refill = 100 * time.Millisecond
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1000, refill,
+		limiters.WithClock(clock),
+		limiters.WithInitialTokens(0),
+		limiters.WithJitter(0.5),
+		limiters.WithRandSource(rand.NewPCG(1, 2)),
+	)
+
+	var intervals []time.Duration
+	last := clock.Now()
+	for len(intervals) < 200 {
+		clock.Advance(time.Millisecond)
+		if limiter.TryLimit() {
+			intervals = append(intervals, clock.Now().Sub(last))
+			last = clock.Now()
+		}
+	}
+
+	var total time.Duration
+	distinct := make(map[time.Duration]bool)
+	for _, interval := range intervals {
+		if interval < refill/2 || interval > refill*3/2+time.Millisecond {
+			t.Errorf("interval %v out of the jitter range", interval)
+		}
+		total += interval
+		distinct[interval] = true
+	}
+	if mean := total / time.Duration(len(intervals)); mean < refill*95/100 || mean > refill*105/100 {
+		t.Errorf("expected a mean interval close to %v, got %v", refill, mean)
+	}
+	if len(distinct) < 10 {
+		t.Errorf("expected intervals to vary, got %d distinct values", len(distinct))
+	}
+}
+
+func TestReservoirLimiterInvalidJitter(t *testing.T) {
+	for _, fraction := range []float64{-0.1, 1} {
+		_, err := limiters.NewReservoirLimiterWithError(5, time.Second, limiters.WithJitter(fraction))
+		if !errors.Is(err, limiters.ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig for a jitter of %v, got %v", fraction, err)
+		}
+	}
+}
+
+func TestReservoirLimiterFairness(t *testing.T) {
+	const n = 50
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second,
+		limiters.WithClock(clock),
+		limiters.WithInitialTokens(0),
+		limiters.WithFairness(true),
+	)
+	reporter := limiter.(limiters.StatsReporter)
+
+	var (
+		mutex sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Limit(context.Background()); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			mutex.Lock()
+			order = append(order, i)
+			mutex.Unlock()
+		}()
+		eventually(t, func() bool { return reporter.Stats().Waiting == int64(i+1) })
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected TryLimit not to overtake waiters")
+	}
+	for i := 0; i < n; i++ {
+		clock.Advance(time.Second)
+		eventually(t, func() bool {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return len(order) == i+1
+		})
+	}
+	wg.Wait()
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("expected grants in order of arrival, got %v", order)
+		}
+	}
+}
+
+func TestReservoirLimiterUnfairOvertakes(t *testing.T) {
+	for _, fair := range []bool{true, false} {
+		clock := limiterstest.NewClock()
+		limiter := limiters.NewReservoirLimiter(2, time.Second,
+			limiters.WithClock(clock),
+			limiters.WithInitialTokens(0),
+			limiters.WithFairness(fair),
+		)
+		reporter := limiter.(limiters.StatsReporter)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		large := make(chan error, 1)
+		go func() { large <- limiter.LimitN(ctx, 2) }()
+		eventually(t, func() bool { return reporter.Stats().Waiting == 1 })
+		small := make(chan error, 1)
+		go func() { small <- limiter.Limit(ctx) }()
+		eventually(t, func() bool { return reporter.Stats().Waiting == 2 })
+
+		clock.Advance(time.Second)
+		if fair {
+			clock.Advance(time.Second)
+			if err := <-large; err != nil {
+				t.Fatalf("fair: unexpected error: %v", err)
+			}
+			if got := reporter.Stats().Waiting; got != 1 {
+				t.Fatalf("fair: expected the small call to wait, got %d waiting", got)
+			}
+		} else if err := <-small; err != nil {
+			t.Fatalf("unfair: unexpected error: %v", err)
+		}
+		cancel()
+	}
+}
+
+func TestReservoirLimiterLimitTimed(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second, limiters.WithClock(clock))
+	timed := limiter.(limiters.TimedLimiter)
+
+	waited, err := timed.LimitTimed(context.Background())
+	if err != nil || waited != 0 {
+		t.Fatalf("expected no wait, got %v and %v", waited, err)
+	}
+
+	done := make(chan time.Duration, 1)
+	go func() {
+		waited, err := timed.LimitTimed(context.Background())
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		done <- waited
+	}()
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+	clock.Advance(time.Second)
+	if waited := <-done; waited != time.Second {
+		t.Fatalf("expected to wait %v, got %v", time.Second, waited)
+	}
+}
+
+func TestReservoirLimiterLimitWithin(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour)
+	bounded := limiter.(limiters.BoundedLimiter)
+
+	if err := bounded.LimitWithin(time.Second); err != nil {
+		t.Fatalf("expected a token, got %v", err)
+	}
+	err := bounded.LimitWithin(10 * time.Millisecond)
+	if !errors.Is(err, limiters.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the timeout to be distinct from context errors, got %v", err)
+	}
+	if got := limiter.(limiters.StatsReporter).Stats().Waiting; got != 0 {
+		t.Fatalf("expected no waiter left, got %d", got)
+	}
+}
+
+func BenchmarkReservoirLimiterLimit(b *testing.B) {
+	limiter := limiters.NewReservoirLimiter(1<<30, time.Nanosecond)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := limiter.Limit(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReservoirLimiterLimitParallel(b *testing.B) {
+	limiter := limiters.NewReservoirLimiter(1<<30, time.Nanosecond)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := limiter.Limit(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkReservoirLimiterLimitWithLogger(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	limiter := limiters.NewReservoirLimiter(1<<30, time.Nanosecond, limiters.WithLogger(logger))
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := limiter.Limit(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReservoirLimiterTryLimit(b *testing.B) {
+	limiter := limiters.NewReservoirLimiter(1<<30, time.Nanosecond)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		limiter.TryLimit()
+	}
+}
+
+func TestReservoirLimiterDrain(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second,
+		limiters.WithClock(clock),
+		limiters.WithInitialTokens(0),
+	)
+	reporter := limiter.(limiters.StatsReporter)
+
+	served := make(chan error, 1)
+	go func() { served <- limiter.Limit(context.Background()) }()
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() { canceled <- limiter.Limit(ctx) }()
+	eventually(t, func() bool { return reporter.Stats().Waiting == 2 })
+
+	limiter.(limiters.Drainer).Drain()
+	if err := limiter.Limit(context.Background()); !errors.Is(err, limiters.ErrLimiterDraining) {
+		t.Fatalf("expected ErrLimiterDraining, got %v", err)
+	}
+	if _, err := limiter.(limiters.Reserver).Reserve(context.Background()); !errors.Is(err, limiters.ErrLimiterDraining) {
+		t.Fatalf("expected ErrLimiterDraining from Reserve, got %v", err)
+	}
+	clock.Advance(time.Second)
+	if limiter.TryLimit() {
+		t.Fatal("expected TryLimit to fail while draining")
+	}
+
+	// Waiting calls are still served, or give up on cancellation.
+	cancel()
+	if err := <-canceled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("expected the waiting call to be served, got %v", err)
+	}
+}
+
+func TestReservoirLimiterReset(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(3, time.Second,
+		limiters.WithClock(clock),
+		limiters.WithInitialTokens(0),
+	)
+	counter := limiter.(limiters.TokenCounter)
+
+	done := make(chan error, 1)
+	go func() { done <- limiter.LimitN(context.Background(), 2) }()
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+
+	limiter.(limiters.Resetter).Reset()
+	if err := <-done; err != nil {
+		t.Fatalf("expected the waiter to be served, got %v", err)
+	}
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected 1 token left, got %d", got)
+	}
+	eventually(t, func() bool { return clock.ActiveTickers() == 0 })
+
+	// Refill resumes from the reset.
+	if !limiter.TryLimit() {
+		t.Fatal("expected a token")
+	}
+	clock.Advance(time.Second)
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected 1 refilled token, got %d", got)
+	}
+}
+
+func TestReservoirLimiterTypedErrors(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour, limiters.WithInitialTokens(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := limiter.Limit(ctx)
+	if !errors.Is(err, limiters.ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected ErrCanceled wrapping context.Canceled, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	err = limiter.Limit(ctx)
+	if !errors.Is(err, limiters.ErrDeadlineExceeded) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrDeadlineExceeded wrapping context.DeadlineExceeded, got %v", err)
+	}
+	if errors.Is(err, limiters.ErrCanceled) {
+		t.Fatalf("expected a deadline not to count as a cancellation, got %v", err)
+	}
+}
+
+func TestReservoirLimiterAllow(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour)
+	if !limiter.Allow() {
+		t.Fatal("expected the call to be allowed")
+	}
+	if limiter.Allow() {
+		t.Fatal("expected the call not to be allowed")
+	}
+	if stats := limiter.(limiters.StatsReporter).Stats(); stats.Granted != 1 {
+		t.Fatalf("expected 1 grant, got %d", stats.Granted)
+	}
+}
+
+func TestReservoirLimiterPriority(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second,
+		limiters.WithClock(clock),
+		limiters.WithInitialTokens(0),
+	)
+	prioritized := limiter.(limiters.PriorityLimiter)
+	reporter := limiter.(limiters.StatsReporter)
+
+	var (
+		mutex sync.Mutex
+		order []limiters.Priority
+		wg    sync.WaitGroup
+	)
+	priorities := []limiters.Priority{
+		limiters.PriorityLow,
+		limiters.PriorityNormal,
+		limiters.PriorityHigh,
+		limiters.PriorityNormal,
+	}
+	for i, p := range priorities {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := prioritized.LimitPriority(context.Background(), p); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			mutex.Lock()
+			order = append(order, p)
+			mutex.Unlock()
+		}()
+		eventually(t, func() bool { return reporter.Stats().Waiting == int64(i+1) })
+	}
+	for i := range priorities {
+		clock.Advance(time.Second)
+		eventually(t, func() bool {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return len(order) == i+1
+		})
+	}
+	wg.Wait()
+
+	want := []limiters.Priority{
+		limiters.PriorityHigh,
+		limiters.PriorityNormal,
+		limiters.PriorityNormal,
+		limiters.PriorityLow,
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected grants in order %v, got %v", want, order)
+		}
+	}
+}
+
+func TestReservoirLimiterPriorityAging(t *testing.T) {
+	for _, aging := range []time.Duration{0, time.Second} {
+		clock := limiterstest.NewClock()
+		limiter := limiters.NewReservoirLimiter(1, time.Second,
+			limiters.WithClock(clock),
+			limiters.WithInitialTokens(0),
+			limiters.WithPriorityAging(aging),
+		)
+		prioritized := limiter.(limiters.PriorityLimiter)
+		reporter := limiter.(limiters.StatsReporter)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		low := make(chan error, 1)
+		go func() { low <- prioritized.LimitPriority(ctx, limiters.PriorityLow) }()
+		eventually(t, func() bool { return reporter.Stats().Waiting == 1 })
+
+		// A steady stream of high priority calls, one per refill.
+		for i := 0; i < 4; i++ {
+			go prioritized.LimitPriority(ctx, limiters.PriorityHigh)
+			eventually(t, func() bool { return reporter.Stats().Waiting == 2 })
+			clock.Advance(time.Second)
+			eventually(t, func() bool { return reporter.Stats().Granted == uint64(i+1) })
+		}
+		if aging == 0 {
+			select {
+			case err := <-low:
+				t.Fatalf("expected the low priority call to wait without aging, got %v", err)
+			default:
+			}
+		} else if err := <-low; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cancel()
+	}
+}
+
+func TestReservoirLimiterInvalidPriorityAging(t *testing.T) {
+	_, err := limiters.NewReservoirLimiterWithError(5, time.Second, limiters.WithPriorityAging(-time.Second))
+	if !errors.Is(err, limiters.ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestRateLimiterFractional(t *testing.T) {
+	const duration = 100 * time.Second
+	for _, rate := range []float64{2.5, 1.0 / 3, 7.3} {
+		clock := limiterstest.NewClock()
+		limiter := limiters.NewRateLimiter(rate, 1, limiters.WithClock(clock))
+		granted := 0
+		for elapsed := time.Duration(0); elapsed < duration; elapsed += time.Millisecond {
+			for limiter.TryLimit() {
+				granted++
+			}
+			clock.Advance(time.Millisecond)
+		}
+		want := rate * duration.Seconds()
+		if got := float64(granted); got < want*0.99 || got > want*1.01+1 {
+			t.Errorf("rate %v: expected about %v grants, got %d", rate, want, granted)
+		}
+	}
+}
+
+func TestRateLimiterPerMinute(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewRateLimiterPerMinute(600, 10, limiters.WithClock(clock))
+	granted := 0
+	for elapsed := time.Duration(0); elapsed < 10*time.Minute; elapsed += 50 * time.Millisecond {
+		for limiter.TryLimit() {
+			granted++
+		}
+		clock.Advance(50 * time.Millisecond)
+	}
+	// The burst, then 600 calls per minute.
+	if want := 10 + 6000; granted < want-1 || granted > want {
+		t.Fatalf("expected about %d grants, got %d", want, granted)
+	}
+}
+
+func TestRateLimiterRoundsRefills(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewRateLimiter(3, 1, limiters.WithClock(clock), limiters.WithInitialTokens(0))
+	if _, refill := limiter.(limiters.RateReporter).CurrentRate(); refill != 333333333*time.Nanosecond {
+		t.Fatalf("expected a refill every 333333333ns, got %v", refill)
+	}
+	limiter = limiters.NewRateLimiter(1.5e9, 1)
+	if _, refill := limiter.(limiters.RateReporter).CurrentRate(); refill != time.Nanosecond {
+		t.Fatalf("expected the refill to be rounded to a nanosecond, got %v", refill)
+	}
+}
+
+func TestRateLimiterInvalid(t *testing.T) {
+	for _, build := range []func(){
+		func() { limiters.NewRateLimiter(0, 1) },
+		func() { limiters.NewRateLimiter(1, 0) },
+		func() { limiters.NewRateLimiter(1e10, 1) },
+		func() { limiters.NewRateLimiterPerMinute(-1, 1) },
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, limiters.ErrInvalidConfig) {
+					t.Errorf("expected a panic with ErrInvalidConfig, got %v", err)
+				}
+			}()
+			build()
+		}()
+	}
+}
+
+func TestReservoirLimiterLimitUpTo(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(5, time.Second, limiters.WithClock(clock))
+	partial := limiter.(limiters.PartialLimiter)
+	counter := limiter.(limiters.TokenCounter)
+	ctx := context.Background()
+
+	if granted, err := partial.LimitUpTo(ctx, 3); err != nil || granted != 3 {
+		t.Fatalf("expected 3 tokens, got %d and %v", granted, err)
+	}
+	if granted, err := partial.LimitUpTo(ctx, 10); err != nil || granted != 2 {
+		t.Fatalf("expected the 2 remaining tokens, got %d and %v", granted, err)
+	}
+	if granted, err := partial.LimitUpTo(ctx, 1); err != nil || granted != 0 {
+		t.Fatalf("expected no token, got %d and %v", granted, err)
+	}
+	clock.Advance(2 * time.Second)
+	if granted, _ := partial.LimitUpTo(ctx, 10); granted != 2 || counter.Available() != 0 {
+		t.Fatalf("expected the 2 refilled tokens, got %d with %d left", granted, counter.Available())
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := partial.LimitUpTo(canceled, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestReservoirLimiterDrainAvailable(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(10, time.Second, limiters.WithClock(clock))
+	drainer := limiter.(limiters.AvailableDrainer)
+	counter := limiter.(limiters.TokenCounter)
+
+	// Concurrent calls and the drain share the tokens without losing any.
+	var (
+		wg      sync.WaitGroup
+		granted atomic.Int64
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2; j++ {
+				if limiter.TryLimit() {
+					granted.Add(1)
+				}
+			}
+		}()
+	}
+	drained := drainer.DrainAvailable()
+	wg.Wait()
+	if got := int64(drained) + granted.Load(); got != 10 {
+		t.Fatalf("expected the drain and the calls to take 10 tokens, got %d drained and %d granted", drained, granted.Load())
+	}
+	if got := counter.Available(); got != 0 {
+		t.Fatalf("expected an empty reservoir, got %d tokens", got)
+	}
+	if got := drainer.DrainAvailable(); got != 0 {
+		t.Fatalf("expected nothing to drain, got %d", got)
+	}
+
+	clock.Advance(3 * time.Second)
+	if got := drainer.DrainAvailable(); got != 3 {
+		t.Fatalf("expected the refilled tokens to be drained, got %d", got)
+	}
+}
+
+func TestReservoirLimiterLogger(t *testing.T) {
+	var logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second,
+		limiters.WithClock(clock),
+		limiters.WithName("api"),
+		limiters.WithLogger(logger),
+	)
+
+	limiter.TryLimit()
+	clock.Advance(time.Second)
+	limiter.TryLimit()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter.Limit(ctx)
+
+	for _, msg := range []string{
+		"limiters: tokens granted",
+		"limiters: reservoir emptied",
+		"limiters: reservoir refilled",
+		"limiters: wait canceled",
+	} {
+		if !strings.Contains(logs.String(), msg) {
+			t.Errorf("expected %q to be logged, got:\n%s", msg, logs.String())
+		}
+	}
+	if !strings.Contains(logs.String(), "limiter=api") {
+		t.Errorf("expected the limiter name to be logged, got:\n%s", logs.String())
+	}
+}
+
+func TestReservoirLimiterNoLoggerAllocations(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1<<30, time.Nanosecond)
+	allocs := testing.AllocsPerRun(1000, func() {
+		limiter.Limit(context.Background())
+		limiter.TryLimit()
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocation without a logger, got %v", allocs)
+	}
+}
+
+func TestReservoirLimiterNegativeMaxTokens(t *testing.T) {
+	for _, n := range []int{-1, -100} {
+		if _, err := limiters.NewReservoirLimiterWithError(n, time.Second); !errors.Is(err, limiters.ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig for %d max tokens, got %v", n, err)
+		}
+	}
+}
+
+func TestReservoirLimiterZeroMaxTokens(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(0, time.Second, limiters.WithClock(clock))
+
+	if limiter.TryLimit() {
+		t.Fatal("expected a limiter of zero tokens to admit nothing")
+	}
+	if err := limiter.Limit(context.Background()); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity rather than blocking, got %v", err)
+	}
+	clock.Advance(time.Hour)
+	if got := limiter.(limiters.TokenCounter).Available(); got != 0 {
+		t.Fatalf("expected no token to be refilled, got %d", got)
+	}
+	if active := clock.ActiveTickers(); active != 0 {
+		t.Fatalf("expected no ticker, got %d", active)
+	}
+
+	if err := limiter.(limiters.RateSetter).SetRate(1, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clock.Advance(time.Second)
+	if !limiter.TryLimit() {
+		t.Fatal("expected a token once the limiter has some capacity")
+	}
+}
+
+func TestReservoirLimiterInvalidRefillDuration(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Second} {
+		_, err := limiters.NewReservoirLimiterWithError(5, d)
+		if !errors.Is(err, limiters.ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig for a refill duration of %v, got %v", d, err)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected NewReservoirLimiter to panic for a refill duration of %v", d)
+				}
+			}()
+			limiters.NewReservoirLimiter(5, d)
+		}()
+	}
+}
+
+func TestReservoirLimiterRefillRateUnderLoad(t *testing.T) {
+	const ticks = 100
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(5, time.Second, limiters.WithClock(clock))
+	reporter := limiter.(limiters.StatsReporter)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for limiter.Limit(ctx) == nil {
+			}
+		}()
+	}
+	eventually(t, func() bool { return reporter.Stats().Waiting == 20 })
+	for i := 1; i <= ticks; i++ {
+		if active := clock.ActiveTickers(); active != 1 {
+			t.Fatalf("expected a single refill ticker, got %d", active)
+		}
+		clock.Advance(time.Second)
+		eventually(t, func() bool { return reporter.Stats().Granted == uint64(5+i) })
+		eventually(t, func() bool { return reporter.Stats().Waiting == 20 })
+	}
+	cancel()
+	wg.Wait()
+
+	if got := reporter.Stats().Granted; got != 5+ticks {
+		t.Fatalf("expected %d grants at the refill rate, got %d", 5+ticks, got)
+	}
+}
+
+func TestReservoirLimiterConformance(t *testing.T) {
+	limiterstest.RunConformanceTests(t, func(clock limiters.Clock, burst int, interval time.Duration) limiters.Limiter {
+		return limiters.NewReservoirLimiter(burst, interval, limiters.WithClock(clock))
+	})
+}
+
+func TestReservoirLimiterCanceledBeforeWait(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second, limiters.WithClock(clock))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := limiter.Limit(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if got := limiter.(limiters.TokenCounter).Available(); got != 1 {
+		t.Fatalf("expected the token to be left, got %d", got)
+	}
+
+	limiter.TryLimit()
+	if err := limiter.LimitN(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if active := clock.ActiveTickers(); active != 0 {
+		t.Fatalf("expected no ticker to be started, got %d", active)
+	}
+}
+
+func TestReservoirLimiterWaitQuantiles(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, 100*time.Millisecond,
+		limiters.WithClock(clock), limiters.WithInitialTokens(0), limiters.WithWaitHistogram())
+	quantiler := limiter.(limiters.WaitQuantiler)
+	reporter := limiter.(limiters.StatsReporter)
+
+	// Each call arrives d before the next token is due, so it waits for d.
+	waits := map[time.Duration]int{9 * time.Millisecond: 60, 53 * time.Millisecond: 35, 90 * time.Millisecond: 5}
+	granted := uint64(0)
+	for _, d := range []time.Duration{9 * time.Millisecond, 53 * time.Millisecond, 90 * time.Millisecond} {
+		for i := 0; i < waits[d]; i++ {
+			clock.Advance(100*time.Millisecond - d)
+			done := make(chan error)
+			go func() { done <- limiter.Limit(context.Background()) }()
+			eventually(t, func() bool { return reporter.Stats().Waiting == 1 })
+			clock.Advance(d)
+			if err := <-done; err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			granted++
+		}
+	}
+	if got := reporter.Stats().Granted; got != granted {
+		t.Fatalf("expected %d grants, got %d", granted, got)
+	}
+
+	for _, tc := range []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0.5, 9 * time.Millisecond},
+		{0.9, 53 * time.Millisecond},
+		{0.99, 90 * time.Millisecond},
+	} {
+		got := quantiler.WaitQuantile(tc.q)
+		if got < tc.want*9/10 || got > tc.want*11/10 {
+			t.Errorf("expected quantile %v around %v, got %v", tc.q, tc.want, got)
+		}
+	}
+
+	clock.Advance(2 * time.Minute)
+	if got := quantiler.WaitQuantile(0.99); got != 0 {
+		t.Fatalf("expected old waits to be forgotten, got %v", got)
+	}
+}
+
+func TestReservoirLimiterWaitQuantilesDisabled(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Second, limiters.WithClock(limiterstest.NewClock()))
+	limiter.Limit(context.Background())
+
+	if got := limiter.(limiters.WaitQuantiler).WaitQuantile(0.5); got != 0 {
+		t.Fatalf("expected no quantile without a histogram, got %v", got)
+	}
+}
+
+func TestReservoirLimiterRefillBatch(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(10, 100*time.Millisecond,
+		limiters.WithClock(clock), limiters.WithRefillBatch(10), limiters.WithInitialTokens(0))
+	counter := limiter.(limiters.TokenCounter)
+
+	granted := 0
+	for i := 1; i <= 100; i++ {
+		clock.Advance(100 * time.Millisecond)
+		for limiter.TryLimit() {
+			granted++
+		}
+		if i%10 != 0 && granted != i/10*10 {
+			t.Fatalf("expected tokens to be refilled by batches of 10, got %d after %d periods", granted, i)
+		}
+	}
+	if granted != 100 {
+		t.Fatalf("expected the average rate to be kept, got %d tokens in 10s", granted)
+	}
+
+	clock.Advance(time.Hour)
+	if got := counter.Available(); got != 10 {
+		t.Fatalf("expected the reservoir not to overfill, got %d", got)
+	}
+}
+
+func TestReservoirLimiterRefillBatchWaiters(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(3, time.Second,
+		limiters.WithClock(clock), limiters.WithRefillBatch(5), limiters.WithInitialTokens(0))
+	reporter := limiter.(limiters.StatsReporter)
+
+	for i := 0; i < 3; i++ {
+		go limiter.Limit(context.Background())
+	}
+	eventually(t, func() bool { return reporter.Stats().Waiting == 3 })
+	clock.Advance(4 * time.Second)
+	time.Sleep(10 * time.Millisecond)
+	if got := reporter.Stats().Granted; got != 0 {
+		t.Fatalf("expected no grant before the first batch, got %d", got)
+	}
+	clock.Advance(time.Second)
+	eventually(t, func() bool { return reporter.Stats().Granted == 3 })
+	if got := limiter.(limiters.TokenCounter).Available(); got != 0 {
+		t.Fatalf("expected the batch to be capped by the capacity, got %d left", got)
+	}
+}
+
+func TestReservoirLimiterInvalidRefillBatch(t *testing.T) {
+	_, err := limiters.NewReservoirLimiterWithError(1, time.Second, limiters.WithRefillBatch(0))
+	if !errors.Is(err, limiters.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestReservoirLimiterRate(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(100, time.Millisecond, limiters.WithClock(clock))
+	reporter := limiter.(limiters.ThroughputReporter)
+	if got := reporter.Rate(); got != 0 {
+		t.Fatalf("expected no rate before any call, got %v", got)
+	}
+
+	// Steady load of 50 calls per second.
+	for i := 0; i < 200; i++ {
+		for j := 0; j < 5; j++ {
+			limiter.TryLimit()
+		}
+		clock.Advance(100 * time.Millisecond)
+		if i == 9 {
+			if got := reporter.Rate(); got < 47.5 || got > 52.5 {
+				t.Fatalf("expected about 50 calls per second since creation, got %v", got)
+			}
+		}
+	}
+	if got := reporter.Rate(); got < 47.5 || got > 52.5 {
+		t.Fatalf("expected about 50 calls per second, got %v", got)
+	}
+
+	clock.Advance(10 * time.Second)
+	if got := reporter.Rate(); got != 0 {
+		t.Fatalf("expected old calls to leave the window, got %v", got)
+	}
+}
+
+func TestReservoirLimiterRateWindow(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(100, time.Millisecond, limiters.WithClock(clock), limiters.WithRateWindow(time.Second))
+	reporter := limiter.(limiters.ThroughputReporter)
+
+	clock.Advance(time.Minute)
+	for i := 0; i < 20; i++ {
+		limiter.TryLimit()
+		clock.Advance(50 * time.Millisecond)
+	}
+	if got := reporter.Rate(); got < 19 || got > 21 {
+		t.Fatalf("expected about 20 calls per second over the window, got %v", got)
+	}
+
+	if _, err := limiters.NewReservoirLimiterWithError(1, time.Second, limiters.WithRateWindow(0)); !errors.Is(err, limiters.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+// Queues 20 calls with a distant deadline, then 4 with a near one, on a
+// limiter refilling a token every 20ms, and returns how many timed out.
+func mixedDeadlineTimeouts(t *testing.T, opts ...limiters.Option) int {
+	t.Helper()
+	limiter := limiters.NewReservoirLimiter(1, 20*time.Millisecond, append(opts, limiters.WithInitialTokens(0))...)
+	defer limiter.(io.Closer).Close()
+	reporter := limiter.(limiters.StatsReporter)
+
+	errs := make(chan error, 24)
+	for i := 0; i < 24; i++ {
+		deadline := 5 * time.Second
+		if i >= 20 {
+			deadline = 200 * time.Millisecond
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), deadline)
+			defer cancel()
+			errs <- limiter.Limit(ctx)
+		}()
+		eventually(t, func() bool {
+			stats := reporter.Stats()
+			return stats.Waiting+int64(stats.Granted+stats.Canceled) == int64(i+1)
+		})
+	}
+	timeouts := 0
+	for i := 0; i < 24; i++ {
+		if err := <-errs; errors.Is(err, limiters.ErrDeadlineExceeded) {
+			timeouts++
+		} else if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return timeouts
+}
+
+func TestReservoirLimiterEDF(t *testing.T) {
+	fifo := mixedDeadlineTimeouts(t)
+	edf := mixedDeadlineTimeouts(t, limiters.WithEDF())
+	if edf != 0 || edf >= fifo {
+		t.Fatalf("expected fewer timeouts with EDF, got %d against %d in order of arrival", edf, fifo)
+	}
+}
+
+func TestReservoirLimiterLimitNAtomic(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(3, time.Second, limiters.WithClock(clock), limiters.WithInitialTokens(0))
+	atomicLimiter := limiter.(limiters.AtomicLimiter)
+	counter := limiter.(limiters.TokenCounter)
+
+	done := make(chan error)
+	go func() { done <- atomicLimiter.LimitNAtomic(context.Background(), 3) }()
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+	clock.Advance(time.Second)
+	clock.Advance(time.Second)
+	eventually(t, func() bool { return counter.Available() == 2 })
+	select {
+	case err := <-done:
+		t.Fatalf("expected to wait for all tokens, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := counter.Available(); got != 0 {
+		t.Fatalf("expected the 3 tokens to be taken at once, %d left", got)
+	}
+}
+
+func TestReservoirLimiterLimitNAtomicConcurrent(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(4, time.Millisecond, limiters.WithClock(clock), limiters.WithInitialTokens(0))
+	atomicLimiter := limiter.(limiters.AtomicLimiter)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Duration(j%3)*time.Millisecond)
+				atomicLimiter.LimitNAtomic(ctx, 1+(i+j)%4)
+				cancel()
+			}
+		}()
+	}
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case <-finished:
+			return
+		case <-deadline:
+			t.Fatal("expected bulk requests not to deadlock")
+		default:
+			clock.Advance(time.Millisecond)
+			time.Sleep(100 * time.Microsecond)
+		}
+	}
+}
+
+func TestReservoirLimiterLimitReport(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second, limiters.WithClock(clock))
+	reporter := limiter.(limiters.ReportingLimiter)
+
+	blocked, waited, err := reporter.LimitReport(context.Background())
+	if err != nil || blocked || waited != 0 {
+		t.Fatalf("expected the first call to sail through, got %v, %v and %v", blocked, waited, err)
+	}
+
+	type report struct {
+		blocked bool
+		waited  time.Duration
+		err     error
+	}
+	reports := make(chan report)
+	limit := func() {
+		blocked, waited, err := reporter.LimitReport(context.Background())
+		reports <- report{blocked, waited, err}
+	}
+	go limit()
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+	clock.Advance(time.Second)
+	if r := <-reports; r.err != nil || !r.blocked || r.waited != time.Second {
+		t.Fatalf("expected the call to be blocked for a second, got %+v", r)
+	}
+
+	go limit()
+	eventually(t, func() bool { return limiter.(limiters.StatsReporter).Stats().Waiting == 1 })
+	limiter.(limiters.TokenReturner).ReturnTokens(1)
+	if r := <-reports; r.err != nil || !r.blocked || r.waited != 0 {
+		t.Fatalf("expected the call to be blocked without time passing, got %+v", r)
+	}
+}
+
+func TestReservoirLimiterStartupDelay(t *testing.T) {
+	for seed := uint64(0); seed < 10; seed++ {
+		clock := limiterstest.NewClock()
+		limiter := limiters.NewReservoirLimiter(5, time.Second, limiters.WithClock(clock),
+			limiters.WithStartupDelay(10*time.Second), limiters.WithRandSource(rand.NewPCG(seed, seed)))
+		delay := time.Duration(rand.New(rand.NewPCG(seed, seed)).Float64() * float64(10*time.Second))
+
+		elapsed := time.Duration(0)
+		for !limiter.TryLimit() {
+			clock.Advance(100 * time.Millisecond)
+			elapsed += 100 * time.Millisecond
+		}
+		if elapsed < delay || elapsed >= delay+100*time.Millisecond {
+			t.Fatalf("seed %d: expected the first grant after %v, got it after %v", seed, delay, elapsed)
+		}
+	}
+}
+
+func TestReservoirLimiterStartupDelayInvalid(t *testing.T) {
+	if _, err := limiters.NewReservoirLimiterWithError(1, time.Second, limiters.WithStartupDelay(-time.Second)); !errors.Is(err, limiters.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestReservoirLimiterAlwaysOn(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second, limiters.WithClock(clock), limiters.WithAlwaysOn())
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+
+	// Taking the token half-way through a period restarts the refill period:
+	// the next token is due a second later, not at the next tick.
+	clock.Advance(500 * time.Millisecond)
+	if !limiter.TryLimit() {
+		t.Fatal("expected a full reservoir")
+	}
+	done := make(chan error)
+	go func() { done <- limiter.Limit(context.Background()) }()
+	waited := time.Duration(0)
+	for served := false; !served; {
+		clock.Advance(10 * time.Millisecond)
+		waited += 10 * time.Millisecond
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			served = true
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if waited < time.Second || waited > 1100*time.Millisecond {
+		t.Fatalf("expected the call to be served when its token was due, after %v", waited)
+	}
+
+	clock.Advance(5 * time.Second)
+	if active := clock.ActiveTickers(); active != 1 {
+		t.Fatalf("expected the ticker to keep running without waiters, got %d", active)
+	}
+	limiter.(io.Closer).Close()
+	eventually(t, func() bool { return clock.ActiveTickers() == 0 })
+}
+
+func BenchmarkReservoirLimiterFirstWait(b *testing.B) {
+	for _, mode := range []struct {
+		name string
+		opts []limiters.Option
+	}{
+		{"lazy", nil},
+		{"always-on", []limiters.Option{limiters.WithAlwaysOn()}},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			limiter := limiters.NewReservoirLimiter(1, 10*time.Microsecond, append(mode.opts, limiters.WithInitialTokens(0))...)
+			defer limiter.(io.Closer).Close()
+			ctx := context.Background()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				// Each call finds the reservoir empty and no one waiting.
+				if err := limiter.Limit(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestReservoirLimiterDropCounters(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(3, time.Second, limiters.WithClock(clock))
+	reporter := limiter.(limiters.DropReporter)
+	if rate := reporter.DropRate(); rate != 0 {
+		t.Fatalf("expected no drop rate without calls, got %v", rate)
+	}
+
+	// 3 admitted then 5 dropped, then 1 admitted after a refill.
+	for i := 0; i < 8; i++ {
+		limiter.TryLimit()
+	}
+	clock.Advance(time.Second)
+	limiter.Allow()
+	if got := reporter.Admitted(); got != 4 {
+		t.Errorf("expected 4 admitted calls, got %d", got)
+	}
+	if got := reporter.Dropped(); got != 5 {
+		t.Errorf("expected 5 dropped calls, got %d", got)
+	}
+	if rate := reporter.DropRate(); rate != 5.0/9 {
+		t.Errorf("expected a drop rate of 5/9, got %v", rate)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter.Limit(ctx)
+	if admitted, dropped := reporter.Admitted(), reporter.Dropped(); admitted != 4 || dropped != 5 {
+		t.Fatalf("expected blocking calls not to be counted, got %d admitted and %d dropped", admitted, dropped)
+	}
+}
+
+func TestReservoirLimiterWarmup(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(10, 100*time.Millisecond,
+		limiters.WithClock(clock), limiters.WithWarmup(10*time.Second))
+	if got := limiter.(limiters.TokenCounter).Available(); got != 1 {
+		t.Fatalf("expected the reservoir to start with a tenth of its tokens, got %d", got)
+	}
+	limiter.TryLimit()
+
+	// Count the tokens granted over each second, against the linear ramp from
+	// a tenth of the rate.
+	elapsed := time.Duration(0)
+	for _, start := range []time.Duration{0, 2 * time.Second, 5 * time.Second, 8 * time.Second, 12 * time.Second} {
+		for elapsed < start {
+			clock.Advance(10 * time.Millisecond)
+			elapsed += 10 * time.Millisecond
+			limiter.TryLimit()
+		}
+		granted := 0
+		for elapsed < start+time.Second {
+			clock.Advance(10 * time.Millisecond)
+			elapsed += 10 * time.Millisecond
+			if limiter.TryLimit() {
+				granted++
+			}
+		}
+		progress := min(float64(start+time.Second/2)/float64(10*time.Second), 1)
+		want := 10 * (0.1 + 0.9*progress)
+		if float64(granted) < want-1 || float64(granted) > want+1 {
+			t.Errorf("expected about %.1f tokens in the second after %v, got %d", want, start, granted)
+		}
+	}
+}
+
+func TestReservoirLimiterWarmupCurve(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(10, 100*time.Millisecond, limiters.WithClock(clock),
+		limiters.WithWarmup(10*time.Second), limiters.WithWarmupCurve(func(float64) float64 { return 0.5 }))
+	if err := limiter.LimitN(context.Background(), 5); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+	if got := limiter.(limiters.TokenCounter).Available(); got != 5 {
+		t.Fatalf("expected half the rate to refill 5 tokens in a second, got %d", got)
+	}
+	if _, err := limiters.NewReservoirLimiterWithError(1, time.Second, limiters.WithWarmup(-time.Second)); !errors.Is(err, limiters.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig for a negative warmup, got %v", err)
+	}
+}
+
+func TestReservoirLimiterDefaultWaitTimeout(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Hour, limiters.WithClock(clock),
+		limiters.WithInitialTokens(0), limiters.WithDefaultWaitTimeout(2*time.Second))
+
+	// Without a deadline, or with a farther one, the default timeout applies.
+	farther, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	for _, ctx := range []context.Context{context.Background(), farther} {
+		done := make(chan error)
+		go func() { done <- limiter.Limit(ctx) }()
+		eventually(t, func() bool { return clock.ActiveTickers() == 2 })
+		clock.Advance(2 * time.Second)
+		err := <-done
+		if !errors.Is(err, limiters.ErrWaitTimeout) || !errors.Is(err, limiters.ErrTimeout) {
+			t.Fatalf("expected ErrWaitTimeout, got %v", err)
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the timeout to be told apart from the context, got %v", err)
+		}
+	}
+
+	// A nearer deadline of the context wins.
+	nearer, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Limit(nearer); !errors.Is(err, limiters.ErrDeadlineExceeded) || errors.Is(err, limiters.ErrWaitTimeout) {
+		t.Fatalf("expected the deadline of the context, got %v", err)
+	}
+	eventually(t, func() bool { return clock.ActiveTickers() == 0 })
+}
+
+func TestReservoirLimiterCancellationAtGrant(t *testing.T) {
+	const tokens = 100
+	limiter := limiters.NewReservoirLimiter(tokens, time.Hour, limiters.WithInitialTokens(0))
+	returner := limiter.(limiters.TokenReturner)
+
+	// Calls are canceled while tokens are handed to them: every token must end
+	// up either taken by a successful call or back in the reservoir.
+	var (
+		wg      sync.WaitGroup
+		granted atomic.Int64
+	)
+	for i := 0; i < 4*tokens; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				runtime.Gosched()
+				cancel()
+			}()
+			if limiter.Limit(ctx) == nil {
+				granted.Add(1)
+			}
+		}()
+	}
+	for i := 0; i < tokens; i++ {
+		returner.ReturnTokens(1)
+		runtime.Gosched()
+	}
+	wg.Wait()
+	if got := granted.Load() + int64(limiter.(limiters.TokenCounter).Available()); got != tokens {
+		t.Fatalf("expected %d tokens taken or available, got %d", tokens, got)
+	}
+}
+
+func TestReservoirLimiterCancellationLatencyUnderLoad(t *testing.T) {
+	// Tokens are handed out continuously to other waiters meanwhile.
+	limiter := limiters.NewReservoirLimiter(1, 100*time.Microsecond, limiters.WithInitialTokens(0))
+	ctx, stop := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer stop()
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for limiter.Limit(ctx) == nil {
+			}
+		}()
+	}
+
+	worst := time.Duration(0)
+	for i := 0; i < 20; i++ {
+		call, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- limiter.Limit(call) }()
+		time.Sleep(time.Millisecond)
+		start := time.Now()
+		cancel()
+		err := <-done
+		worst = max(worst, time.Since(start))
+		if err != nil && !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if worst > limiterstest.MaxCancellationLatency {
+		t.Fatalf("expected canceled calls to return within %v, took up to %v", limiterstest.MaxCancellationLatency, worst)
+	}
+}
+
+func BenchmarkReservoirLimiterCancel(b *testing.B) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour, limiters.WithInitialTokens(0))
+	reporter := limiter.(limiters.StatsReporter)
+	b.ReportAllocs()
+	total := time.Duration(0)
+	for i := 0; i < b.N; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- limiter.Limit(ctx) }()
+		for reporter.Stats().Waiting == 0 {
+			runtime.Gosched()
+		}
+		start := time.Now()
+		cancel()
+		<-done
+		total += time.Since(start)
+	}
+	b.ReportMetric(float64(total.Nanoseconds())/float64(b.N), "ns/cancel")
+}
@@ -0,0 +1,89 @@
+package limiters
+
+import (
+	"context"
+	"sync"
+)
+
+// Struct implementing the Settlement interface for a reservoir limiter.
+type reservoirSettlement struct {
+	limiter *reservoirLimiter
+	// Whether the token was handed out on credit, rather than taken from the
+	// reservoir.
+	lent bool
+	// Whether the call was admitted while paused, without taking a token.
+	free bool
+	once sync.Once
+}
+
+// Admits a call right away, on credit, when the reservoir is empty but fewer
+// tokens than set with WithOverProvision are lent, and blocks like Limit
+// otherwise.
+//
+// The call is only charged if it is settled as successful: a failed call
+// gives its token back. Calls lent a token count against the over-provision
+// until settled, as do successful ones until the reservoir refilled the
+// tokens they were charged.
+func (l *reservoirLimiter) LimitOptimistic(ctx context.Context) (Settlement, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if l.lend() {
+		l.recordGrant()
+		return &reservoirSettlement{limiter: l, lent: true}, nil
+	}
+	free, err := l.limitFree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &reservoirSettlement{limiter: l, free: free}, nil
+}
+
+// Lends a token if the reservoir has none to hand out right away and the
+// over-provision allows it.
+func (l *reservoirLimiter) lend() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed || l.draining || l.paused {
+		return false
+	}
+	l.refill(l.clock.Now())
+	if l.credits+l.overdraft >= l.overProvision || l.tokenCount > 0 && (!l.fair || l.waiters.Len() == 0) {
+		return false
+	}
+	l.credits++
+	return true
+}
+
+// Charges the call if its operation succeeded, and gives its token back
+// otherwise, unless it was admitted without taking one.
+//
+// Only the first call to Settle has an effect.
+func (s *reservoirSettlement) Settle(success bool) {
+	s.once.Do(func() {
+		switch {
+		case !s.lent && !s.free && !success:
+			s.limiter.ReturnTokens(1)
+		case s.lent:
+			s.limiter.repay(success)
+		}
+	})
+}
+
+// Settles a lent token, charging it to the reservoir if its operation
+// succeeded.
+func (l *reservoirLimiter) repay(success bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.credits--
+	if !success || l.closed {
+		return
+	}
+	now := l.clock.Now()
+	l.refill(now)
+	if l.tokenCount > 0 {
+		l.take(1, now)
+		return
+	}
+	l.overdraft++
+}
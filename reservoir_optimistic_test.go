@@ -0,0 +1,84 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+// Admits a call optimistically, failing the test if it is not admitted.
+func limitOptimistic(t *testing.T, limiter limiters.OptimisticLimiter) limiters.Settlement {
+	t.Helper()
+	s, err := limiter.LimitOptimistic(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestReservoirLimiterOptimisticCharging(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(2, time.Hour, limiters.WithClock(clock), limiters.WithOverProvision(2))
+	optimistic := limiter.(limiters.OptimisticLimiter)
+	counter := limiter.(limiters.TokenCounter)
+
+	failed, succeeded := limitOptimistic(t, optimistic), limitOptimistic(t, optimistic)
+	failed.Settle(false)
+	succeeded.Settle(true)
+	failed.Settle(true)
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected only the success to be charged, got %d tokens", got)
+	}
+
+	limitOptimistic(t, optimistic).Settle(true)
+	lentFailed, lentSucceeded := limitOptimistic(t, optimistic), limitOptimistic(t, optimistic)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := optimistic.LimitOptimistic(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the over-provision to be capped, got %v", err)
+	}
+
+	lentFailed.Settle(false)
+	lentSucceeded.Settle(true)
+	clock.Advance(time.Hour)
+	if got := counter.Available(); got != 0 {
+		t.Fatalf("expected the refilled token to pay the lent success, got %d tokens", got)
+	}
+	clock.Advance(time.Hour)
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected the lent failure not to be charged, got %d tokens", got)
+	}
+}
+
+func TestReservoirLimiterOptimisticWithoutOverProvision(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour)
+	optimistic := limiter.(limiters.OptimisticLimiter)
+
+	limitOptimistic(t, optimistic).Settle(true)
+	if limiter.TryLimit() {
+		t.Fatal("expected the success to be charged")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := optimistic.LimitOptimistic(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected no token on credit by default, got %v", err)
+	}
+}
+
+func TestReservoirLimiterLimitOptimisticWhilePaused(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour, limiters.WithInitialTokens(0))
+	limiter.(limiters.Pauser).Pause()
+	settlement, err := limiter.(limiters.OptimisticLimiter).LimitOptimistic(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter.(limiters.Pauser).Resume()
+	settlement.Settle(false)
+	if got := limiter.(limiters.TokenCounter).Available(); got != 0 {
+		t.Fatalf("expected a call admitted while paused to give no token back, got %d tokens", got)
+	}
+}
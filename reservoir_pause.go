@@ -0,0 +1,40 @@
+package limiters
+
+// Stops throttling: until Resume, calls are admitted right away without
+// consuming tokens, and the calls waiting are admitted too.
+//
+// Tokens keep being refilled while paused. Pause is idempotent.
+func (l *reservoirLimiter) Pause() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed || l.paused {
+		return
+	}
+	l.paused = true
+	now := l.clock.Now()
+	l.refill(now)
+	got := 0
+	for elem := l.waiters.Front(); elem != nil; {
+		next := elem.Next()
+		w := elem.Value.(*waiter)
+		got += w.got
+		w.got = 0
+		w.free = true
+		l.removeWaiter(elem)
+		close(w.ready)
+		elem = next
+	}
+	// The waiters took nothing in the end.
+	l.releaseTokens(got)
+	if !l.needsRefillTicker() {
+		l.stopRefillTicker()
+	}
+}
+
+// Resumes throttling, from the tokens left in the reservoir when paused and
+// those refilled since.
+func (l *reservoirLimiter) Resume() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.paused = false
+}
@@ -0,0 +1,115 @@
+package limiters_test
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestReservoirLimiterPause(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(3, time.Second, limiters.WithClock(clock))
+	counter := limiter.(limiters.TokenCounter)
+	pauser := limiter.(limiters.Pauser)
+	if !limiter.TryLimit() {
+		t.Fatal("expected a full reservoir")
+	}
+
+	pauser.Pause()
+	for i := 0; i < 10; i++ {
+		if err := limiter.Limit(context.Background()); err != nil {
+			t.Fatalf("unexpected error while paused: %v", err)
+		}
+		if !limiter.TryLimit() {
+			t.Fatal("expected TryLimit to succeed while paused")
+		}
+	}
+	if got := counter.Available(); got != 2 {
+		t.Fatalf("expected paused calls to leave 2 tokens, got %d", got)
+	}
+
+	pauser.Resume()
+	if err := limiter.LimitN(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected throttling to resume from the prior state")
+	}
+}
+
+func TestReservoirLimiterDrainAvailableWhilePaused(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(5, time.Hour, limiters.WithInitialTokens(2))
+	limiter.(limiters.Pauser).Pause()
+	if got := limiter.(limiters.AvailableDrainer).DrainAvailable(); got != 5 {
+		t.Fatalf("expected a paused limiter to admit its capacity, got %d tokens", got)
+	}
+	if got := limiter.(limiters.TokenCounter).Available(); got != 2 {
+		t.Fatalf("expected the tokens drained while paused to be free, got %d left", got)
+	}
+}
+
+func TestReservoirLimiterPauseReleasesWaiters(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(2, time.Second, limiters.WithClock(clock), limiters.WithInitialTokens(1))
+	reporter := limiter.(limiters.StatsReporter)
+
+	done := make(chan error)
+	go func() { done <- limiter.LimitN(context.Background(), 2) }()
+	eventually(t, func() bool { return reporter.Stats().Waiting == 1 })
+	limiter.(limiters.Pauser).Pause()
+	if err := <-done; err != nil {
+		t.Fatalf("expected the waiter to be admitted, got %v", err)
+	}
+	if got := limiter.(limiters.TokenCounter).Available(); got != 1 {
+		t.Fatalf("expected the waiter to give its token back, got %d tokens", got)
+	}
+	eventually(t, func() bool { return clock.ActiveTickers() == 0 })
+}
+
+func TestReservoirLimiterPauseUnderLoad(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	clock := limiterstest.NewClock()
+	const maxTokens = 5
+	limiter := limiters.NewReservoirLimiter(maxTokens, time.Millisecond, limiters.WithClock(clock))
+	counter := limiter.(limiters.TokenCounter)
+	pauser := limiter.(limiters.Pauser)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				limiter.Limit(ctx)
+				limiter.TryLimit()
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		if i%2 == 0 {
+			pauser.Pause()
+		} else {
+			pauser.Resume()
+		}
+		clock.Advance(time.Millisecond)
+		if got := counter.Available(); got < 0 || got > maxTokens {
+			t.Fatalf("expected between 0 and %d tokens, got %d", maxTokens, got)
+		}
+	}
+	cancel()
+	wg.Wait()
+
+	clock.Advance(maxTokens * time.Millisecond)
+	if got := counter.Available(); got != maxTokens {
+		t.Fatalf("expected the reservoir to refill to %d tokens, got %d", maxTokens, got)
+	}
+	limiter.(io.Closer).Close()
+	eventually(t, func() bool { return runtime.NumGoroutine() <= baseline })
+}
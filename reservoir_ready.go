@@ -0,0 +1,102 @@
+package limiters
+
+import "context"
+
+// Returns a channel receiving from which consumes exactly one token.
+//
+// The channel is shared by all callers and safe for concurrent use: each
+// token is received by a single one of them. A token is set aside for the
+// channel as soon as it is available, and stays so until it is received.
+// The channel stops delivering once the limiter is closed or draining.
+func (l *reservoirLimiter) Ready() <-chan struct{} {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.ready == nil {
+		l.ready = make(chan struct{})
+		if !l.closed {
+			go l.feedReady(l.ready)
+		}
+	}
+	return l.ready
+}
+
+// Takes tokens one at a time and hands them over to receivers of the ready
+// channel, until the limiter is closed or draining.
+func (l *reservoirLimiter) feedReady(ready chan<- struct{}) {
+	for {
+		if err := l.Limit(context.Background()); err != nil {
+			return
+		}
+		select {
+		case ready <- struct{}{}:
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// Returns a channel receiving a value each time a token is taken for it, to
+// pace a loop ranging over it.
+//
+// Unlike Ready, each call gets its own channel, closed once the context is
+// done or the limiter is closed or draining. A token taken while the context
+// is done, before its value was received, is given back to the reservoir,
+// unless it was admitted for free while paused.
+func (l *reservoirLimiter) Tokens(ctx context.Context) <-chan struct{} {
+	tokens := make(chan struct{})
+	go l.feedTokens(ctx, tokens)
+	return tokens
+}
+
+// Takes tokens one at a time and hands them over to the receiver of the
+// channel, until the context is done or the limiter is closed or draining.
+func (l *reservoirLimiter) feedTokens(ctx context.Context, tokens chan<- struct{}) {
+	defer close(tokens)
+	for {
+		free, err := l.limitFree(ctx)
+		if err != nil {
+			return
+		}
+		select {
+		case tokens <- struct{}{}:
+		case <-ctx.Done():
+			if !free {
+				l.ReturnTokens(1)
+			}
+			return
+		}
+	}
+}
+
+// Blocks until a token is available or the context is canceled, leaving the
+// token in the reservoir.
+//
+// The call waits its turn behind calls already waiting, but the token is not
+// set aside: another goroutine may take it before the caller does, so
+// following with TryLimit is best-effort. When the token must be guaranteed,
+// use Reserve instead, and Cancel the reservation if it ends up unused.
+func (l *reservoirLimiter) WaitReady(ctx context.Context) error {
+	if err := contextError(ctx); err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	if l.closed {
+		l.mutex.Unlock()
+		return ErrLimiterClosed
+	}
+	if l.draining {
+		l.mutex.Unlock()
+		return ErrLimiterDraining
+	}
+	now := l.clock.Now()
+	l.refill(now)
+	if l.paused || (!l.fair || l.waiters.Len() == 0) && l.tokenCount > 0 && l.burstAllowance(now) > 0 {
+		l.mutex.Unlock()
+		return nil
+	}
+	w := &waiter{n: 1, ready: make(chan struct{}), since: now, peek: true}
+	elem := l.pushWaiter(w)
+	l.startRefillTicker()
+	l.mutex.Unlock()
+	return l.await(ctx, w, elem, l.waitTimeout)
+}
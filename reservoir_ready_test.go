@@ -0,0 +1,178 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestReservoirLimiterReady(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(3, time.Second, limiters.WithClock(clock))
+	ready := limiter.(limiters.ReadySignaler).Ready()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ready:
+		case <-time.After(time.Second):
+			t.Fatalf("expected token %d to be signaled", i)
+		}
+	}
+	select {
+	case <-ready:
+		t.Fatal("expected no token left")
+	case <-time.After(10 * time.Millisecond):
+	}
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+	clock.Advance(time.Second)
+	select {
+	case <-ready:
+	case <-time.After(time.Second):
+		t.Fatal("expected the refilled token to be signaled")
+	}
+}
+
+func TestReservoirLimiterReadyConcurrent(t *testing.T) {
+	const tokens = 100
+	limiter := limiters.NewReservoirLimiter(tokens, time.Hour)
+	signaler := limiter.(limiters.ReadySignaler)
+
+	var (
+		received atomic.Int64
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-signaler.Ready():
+					received.Add(1)
+				case <-time.After(50 * time.Millisecond):
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := received.Load(); got != tokens {
+		t.Fatalf("expected %d tokens, got %d", tokens, got)
+	}
+}
+
+func TestReservoirLimiterReadyStopsOnClose(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour)
+	baseline := runtime.NumGoroutine()
+	limiter.(limiters.ReadySignaler).Ready()
+	limiter.(io.Closer).Close()
+	eventually(t, func() bool { return runtime.NumGoroutine() <= baseline })
+}
+
+func TestReservoirLimiterWaitReady(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second, limiters.WithClock(clock))
+	waiter := limiter.(limiters.ReadyWaiter)
+	counter := limiter.(limiters.TokenCounter)
+
+	if err := waiter.WaitReady(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected the token to be left, got %d", got)
+	}
+	limiter.TryLimit()
+
+	done := make(chan error)
+	go func() { done <- waiter.WaitReady(context.Background()) }()
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+	select {
+	case err := <-done:
+		t.Fatalf("expected to wait for a token, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected the refilled token to be left, got %d", got)
+	}
+	eventually(t, func() bool { return clock.ActiveTickers() == 0 })
+	if !limiter.TryLimit() {
+		t.Fatal("expected the token to be taken after waiting")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := waiter.WaitReady(ctx); !errors.Is(err, limiters.ErrDeadlineExceeded) {
+		t.Fatalf("expected ErrDeadlineExceeded, got %v", err)
+	}
+	if got := limiter.(limiters.StatsReporter).Stats().Granted; got != 2 {
+		t.Fatalf("expected waiting for tokens not to count as grants, got %d", got)
+	}
+}
+
+func TestReservoirLimiterTokens(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(2, 100*time.Millisecond, limiters.WithClock(clock))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tokens := limiter.(limiters.TokenStreamer).Tokens(ctx)
+
+	// Receives the two initial tokens, then one per refill.
+	received := 0
+	for step := 0; step < 10; step++ {
+		for {
+			select {
+			case <-tokens:
+				received++
+				continue
+			case <-time.After(10 * time.Millisecond):
+			}
+			break
+		}
+		eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+		clock.Advance(100 * time.Millisecond)
+	}
+	if received != 11 {
+		t.Fatalf("expected 2 tokens then 1 per refill, got %d over 9 refills", received)
+	}
+
+	// The last refilled token is taken but not received yet.
+	eventually(t, func() bool { return limiter.(limiters.TokenCounter).Available() == 0 })
+	cancel()
+	late := 0
+	for range tokens {
+		late++
+	}
+	if got := limiter.(limiters.TokenCounter).Available(); got+late != 1 {
+		t.Fatalf("expected the token taken for the channel to be received or given back, got %d tokens and %d late values", got, late)
+	}
+}
+
+func TestReservoirLimiterTokensWhilePaused(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour, limiters.WithInitialTokens(0))
+	limiter.(limiters.Pauser).Pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	tokens := limiter.(limiters.TokenStreamer).Tokens(ctx)
+	<-tokens
+
+	// Lets the next value be admitted for free before canceling.
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	for range tokens {
+	}
+	limiter.(limiters.Pauser).Resume()
+	if got := limiter.(limiters.TokenCounter).Available(); got != 0 {
+		t.Fatalf("expected values admitted while paused to give no token back, got %d tokens", got)
+	}
+}
@@ -0,0 +1,160 @@
+package limiters
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Struct implementing the Reservation interface for a reservoir limiter.
+type reservoirReservation struct {
+	limiter  *reservoirLimiter
+	waiter   *waiter
+	elem     *list.Element
+	readyAt  time.Time
+	canceled bool
+}
+
+// Reserves a token without waiting for it.
+//
+// The reservation takes its place among the waiters: its token is set aside
+// as soon as it is refilled. Fails if the context is already done or the
+// limiter is closed or draining, and with ErrExceedsCapacity if it has no
+// capacity, rather than reserving a token that will never come.
+func (l *reservoirLimiter) Reserve(ctx context.Context) (Reservation, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		return nil, ErrLimiterClosed
+	}
+	if l.draining {
+		return nil, ErrLimiterDraining
+	}
+	now := l.clock.Now()
+	l.refill(now)
+	r := &reservoirReservation{
+		limiter: l,
+		waiter:  &waiter{n: 1, ready: make(chan struct{}), since: now},
+		readyAt: now,
+	}
+	if l.paused {
+		close(r.waiter.ready)
+		return r, nil
+	}
+	if l.maxTokens < 1 {
+		return nil, ErrExceedsCapacity
+	}
+	if (!l.fair || l.waiters.Len() == 0) && l.tryTake(1) {
+		r.waiter.got = 1
+		close(r.waiter.ready)
+		return r, nil
+	}
+	r.readyAt = l.nextTokenTime()
+	r.elem = l.pushWaiter(r.waiter)
+	l.startRefillTicker()
+	l.markThrottled(now)
+	return r, nil
+}
+
+// Returns the estimated time until the reserved token can be used.
+func (r *reservoirReservation) Delay() time.Duration {
+	select {
+	case <-r.waiter.ready:
+		return 0
+	default:
+	}
+	return max(r.readyAt.Sub(r.limiter.clock.Now()), 0)
+}
+
+// Gives the reserved token back to the limiter.
+//
+// Cancel is idempotent.
+func (r *reservoirReservation) Cancel() {
+	l := r.limiter
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if r.canceled || l.closed {
+		return
+	}
+	r.canceled = true
+	select {
+	case <-r.waiter.ready:
+	default:
+		l.removeWaiter(r.elem)
+		if !l.needsRefillTicker() {
+			l.stopRefillTicker()
+		}
+	}
+	l.releaseTokens(r.waiter.got)
+}
+
+// Default number of reservations watching their context at once.
+const defaultMaxWatchers = 1024
+
+// Reservation of a reservoir limiter watching its context.
+type contextReservation struct {
+	*reservoirReservation
+	stop    func() bool
+	release sync.Once
+}
+
+// Reserves a token like Reserve, giving it back to the limiter if the context
+// is done before the reservation is used.
+//
+// The context is watched without a goroutine until it is done. Fails with
+// ErrTooManyReservations if too many reservations are watching their context,
+// see WithMaxReservationWatchers.
+func (l *reservoirLimiter) ReserveContext(ctx context.Context) (ContextReservation, error) {
+	l.mutex.Lock()
+	if l.watchers >= l.maxWatchers {
+		l.mutex.Unlock()
+		return nil, ErrTooManyReservations
+	}
+	l.watchers++
+	l.mutex.Unlock()
+	r, err := l.Reserve(ctx)
+	if err != nil {
+		l.unwatch()
+		return nil, err
+	}
+	cr := &contextReservation{reservoirReservation: r.(*reservoirReservation)}
+	cr.stop = context.AfterFunc(ctx, cr.giveBack)
+	return cr, nil
+}
+
+// Counts a reservation that stopped watching its context.
+func (l *reservoirLimiter) unwatch() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.watchers--
+}
+
+// Marks the token as used, so that it stays taken when the context is done.
+//
+// Use has no effect once the context is done: the token is given back.
+func (r *contextReservation) Use() {
+	if r.stop() {
+		r.release.Do(r.limiter.unwatch)
+	}
+}
+
+// Gives the reserved token back to the limiter, unless it was used.
+//
+// Cancel is idempotent.
+func (r *contextReservation) Cancel() {
+	r.stop()
+	r.giveBack()
+}
+
+// Gives the reserved token back and stops counting the reservation as
+// watching its context, unless it was used.
+func (r *contextReservation) giveBack() {
+	r.release.Do(func() {
+		r.reservoirReservation.Cancel()
+		r.limiter.unwatch()
+	})
+}
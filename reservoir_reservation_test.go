@@ -0,0 +1,141 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestReservoirReservationDelay(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second, limiters.WithClock(clock))
+	reserver := limiter.(limiters.Reserver)
+
+	first, err := reserver.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delay := first.Delay(); delay != 0 {
+		t.Fatalf("expected no delay for the first reservation, got %v", delay)
+	}
+	second, err := reserver.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delay := second.Delay(); delay != time.Second {
+		t.Fatalf("expected a delay of 1s, got %v", delay)
+	}
+	third, err := reserver.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delay := third.Delay(); delay != 2*time.Second {
+		t.Fatalf("expected a delay of 2s, got %v", delay)
+	}
+
+	clock.Advance(time.Second)
+	eventually(t, func() bool { return second.Delay() == 0 })
+	if delay := third.Delay(); delay != time.Second {
+		t.Fatalf("expected a remaining delay of 1s, got %v", delay)
+	}
+}
+
+func TestReservoirReservationCancel(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(2, time.Second, limiters.WithClock(clock))
+	reserver := limiter.(limiters.Reserver)
+	counter := limiter.(limiters.TokenCounter)
+
+	granted, err := reserver.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	granted.Cancel()
+	granted.Cancel()
+	if got := counter.Available(); got != 2 {
+		t.Fatalf("expected the canceled token to be given back, got %d tokens", got)
+	}
+
+	if err := limiter.LimitN(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := reserver.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending.Cancel()
+	clock.Advance(2 * time.Second)
+	if got := counter.Available(); got != 2 {
+		t.Fatalf("expected the reservoir to refill fully, got %d tokens", got)
+	}
+}
+
+func TestReservoirReservationErrors(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Second)
+	reserver := limiter.(limiters.Reserver)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := reserver.Reserve(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	limiter.(io.Closer).Close()
+	if _, err := reserver.Reserve(context.Background()); !errors.Is(err, limiters.ErrLimiterClosed) {
+		t.Fatalf("expected ErrLimiterClosed, got %v", err)
+	}
+
+	empty := limiters.NewReservoirLimiter(0, time.Second)
+	if _, err := empty.(limiters.Reserver).Reserve(context.Background()); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity without capacity, got %v", err)
+	}
+	if got := empty.(limiters.StatsReporter).Stats().Waiting; got != 0 {
+		t.Fatalf("expected no reservation to be queued, got %d waiting", got)
+	}
+}
+
+func TestReservoirReservationContext(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(2, time.Second, limiters.WithClock(clock), limiters.WithMaxReservationWatchers(2))
+	reserver := limiter.(limiters.ContextReserver)
+	counter := limiter.(limiters.TokenCounter)
+
+	// An abandoned reservation gives its token back once its context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	abandoned, err := reserver.ReserveContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	used, err := reserver.ReserveContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reserver.ReserveContext(context.Background()); !errors.Is(err, limiters.ErrTooManyReservations) {
+		t.Fatalf("expected ErrTooManyReservations, got %v", err)
+	}
+	used.Use()
+	if got := counter.Available(); got != 0 {
+		t.Fatalf("expected both tokens to be reserved, got %d available", got)
+	}
+	cancel()
+	eventually(t, func() bool { return counter.Available() == 1 })
+	if abandoned.Delay() != 0 {
+		t.Fatal("expected the abandoned reservation to have had its token")
+	}
+
+	// Both reservations stopped watching their context.
+	for i := 0; i < 2; i++ {
+		r, err := reserver.ReserveContext(context.Background())
+		if err != nil {
+			t.Fatalf("reservation %d: unexpected error: %v", i, err)
+		}
+		r.Cancel()
+	}
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected canceled reservations to give their tokens back, got %d available", got)
+	}
+}
@@ -0,0 +1,99 @@
+package limiters
+
+import (
+	"context"
+	"time"
+)
+
+// Token shared by the calls of LimitShared with the same key.
+type sharedCall struct {
+	// Closed once the leader got its token or gave up.
+	done chan struct{}
+	err  error
+	// End of the window during which later calls share the token.
+	until time.Time
+}
+
+// Blocks until a token is available or the context is canceled, unless a
+// call with the same key already took one within the shared window, see
+// WithSharedWindow.
+//
+// The first call for a key is the leader: it takes a token and reports
+// leader as true. Calls with the same key made while the leader waits, or
+// within the window after it was admitted, are followers: they take no token,
+// wait for the leader to be admitted, and report leader as false, e.g. to
+// reuse its result. If the leader gives up, its followers try again, one of
+// them becoming the new leader.
+func (l *reservoirLimiter) LimitShared(ctx context.Context, key string) (leader bool, err error) {
+	for {
+		l.mutex.Lock()
+		c := l.shared[key]
+		if c == nil || isDone(c.done) && !l.clock.Now().Before(c.until) {
+			break
+		}
+		l.mutex.Unlock()
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return false, contextError(ctx)
+		}
+		if c.err == nil {
+			return false, nil
+		}
+	}
+	// The mutex is still held: lead the call.
+	l.sweepShared(l.clock.Now())
+	c := &sharedCall{done: make(chan struct{})}
+	l.shared[key] = c
+	l.mutex.Unlock()
+
+	err = l.Limit(ctx)
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	c.err = err
+	if err != nil {
+		delete(l.shared, key)
+	} else {
+		c.until = l.clock.Now().Add(l.sharedWindow())
+	}
+	close(c.done)
+	return true, err
+}
+
+// Returns how long calls share the token of their leader once admitted.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) sharedWindow() time.Duration {
+	if l.shareWindow > 0 {
+		return l.shareWindow
+	}
+	return l.refillDuration
+}
+
+// Forgets the shared tokens whose window is over, at most once per window.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) sweepShared(now time.Time) {
+	if l.shared == nil {
+		l.shared = map[string]*sharedCall{}
+	}
+	if now.Sub(l.sharedSwept) < l.sharedWindow() {
+		return
+	}
+	l.sharedSwept = now
+	for key, c := range l.shared {
+		if isDone(c.done) && !now.Before(c.until) {
+			delete(l.shared, key)
+		}
+	}
+}
+
+// Reports whether a channel is closed.
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
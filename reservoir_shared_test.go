@@ -0,0 +1,107 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestReservoirLimiterLimitSharedConsumesOneToken(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(100, time.Hour, limiters.WithClock(clock))
+	shared := limiter.(limiters.SharedLimiter)
+
+	const calls = 50
+	var leaders atomic.Int64
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			leader, err := shared.LimitShared(context.Background(), "report")
+			if err != nil {
+				t.Error(err)
+			}
+			if leader {
+				leaders.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if got := leaders.Load(); got != 1 {
+		t.Fatalf("expected a single leader, got %d", got)
+	}
+	if got := limiter.(limiters.TokenCounter).Available(); got != 99 {
+		t.Fatalf("expected exactly one token to be consumed, got %d tokens left", got)
+	}
+}
+
+func TestReservoirLimiterLimitSharedWindow(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(10, time.Hour, limiters.WithClock(clock),
+		limiters.WithSharedWindow(time.Second))
+	shared := limiter.(limiters.SharedLimiter)
+	lead := func(key string) bool {
+		t.Helper()
+		leader, err := shared.LimitShared(context.Background(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leader
+	}
+
+	if !lead("a") || lead("a") || !lead("b") {
+		t.Fatal("expected the first call of each key to lead, and the next one to follow")
+	}
+	clock.Advance(time.Second)
+	if !lead("a") {
+		t.Fatal("expected a new leader once the window is over")
+	}
+	if got := limiter.(limiters.TokenCounter).Available(); got != 7 {
+		t.Fatalf("expected 3 tokens to be consumed, got %d tokens left", got)
+	}
+}
+
+func TestReservoirLimiterLimitSharedLeaderGivesUp(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Hour, limiters.WithClock(clock),
+		limiters.WithInitialTokens(0))
+	shared := limiter.(limiters.SharedLimiter)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := shared.LimitShared(ctx, "key")
+		done <- err
+	}()
+	eventually(t, func() bool { return limiter.(limiters.StatsReporter).Stats().Waiting == 1 })
+	follower := make(chan bool)
+	go func() {
+		leader, err := shared.LimitShared(context.Background(), "key")
+		if err != nil {
+			t.Error(err)
+		}
+		follower <- leader
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the leader to give up, got %v", err)
+	}
+
+	eventually(t, func() bool { return limiter.(limiters.StatsReporter).Stats().Waiting == 1 })
+	clock.Advance(time.Hour)
+	if !<-follower {
+		t.Fatal("expected the follower to lead once the leader gave up")
+	}
+}
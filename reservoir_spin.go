@@ -0,0 +1,47 @@
+package limiters
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// Takes a token, polling the reservoir for up to d before blocking like
+// Limit.
+//
+// Polling spares the parking and waking of the goroutine when a token is due
+// within microseconds, at the cost of a busy core: it is only worthwhile when
+// tokens are almost always available and d is short, e.g. a millisecond at
+// most, compared to the refill duration. The spin is measured in real time,
+// whatever the clock of the limiter.
+func (l *reservoirLimiter) LimitOrSpin(ctx context.Context, d time.Duration) error {
+	var deadline time.Time
+	for ctx.Err() == nil {
+		if l.trySpin(ctx) {
+			l.recordGrant()
+			return nil
+		}
+		now := time.Now()
+		if deadline.IsZero() {
+			// Only read the time once the token is not there right away.
+			deadline = now.Add(d)
+		}
+		if !now.Before(deadline) {
+			break
+		}
+		runtime.Gosched()
+	}
+	return l.Limit(ctx)
+}
+
+// Takes a token if one is immediately available, like TryLimit but without
+// counting a failure as a drop.
+func (l *reservoirLimiter) trySpin(ctx context.Context) bool {
+	caller := ""
+	if l.callerCap > 0 {
+		caller, _ = CallerFromContext(ctx)
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return !l.closed && !l.draining && (l.paused || (!l.fair || l.waiters.Len() == 0) && l.tryTakeFor(caller, 1))
+}
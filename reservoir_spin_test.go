@@ -0,0 +1,67 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestReservoirLimiterLimitOrSpin(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(1, time.Second, limiters.WithClock(clock))
+	spinner := limiter.(limiters.SpinLimiter)
+	if err := spinner.LimitOrSpin(context.Background(), time.Millisecond); err != nil {
+		t.Fatalf("expected the available token, got %v", err)
+	}
+
+	// The token comes while spinning.
+	done := make(chan error)
+	go func() { done <- spinner.LimitOrSpin(context.Background(), time.Minute) }()
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if active := clock.ActiveTickers(); active != 0 {
+		t.Fatalf("expected the call not to block, got %d tickers", active)
+	}
+
+	// Past the spin, the call blocks until its context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := spinner.LimitOrSpin(ctx, time.Millisecond); !errors.Is(err, limiters.ErrDeadlineExceeded) {
+		t.Fatalf("expected ErrDeadlineExceeded, got %v", err)
+	}
+	if got := limiter.(limiters.DropReporter).Dropped(); got != 0 {
+		t.Fatalf("expected polls not to count as drops, got %d", got)
+	}
+}
+
+func BenchmarkReservoirLimiterLimitOrSpin(b *testing.B) {
+	for _, tc := range []struct {
+		name  string
+		limit func(l limiters.Limiter, ctx context.Context) error
+	}{
+		{"limit", func(l limiters.Limiter, ctx context.Context) error { return l.Limit(ctx) }},
+		{"spin", func(l limiters.Limiter, ctx context.Context) error {
+			return l.(limiters.SpinLimiter).LimitOrSpin(ctx, time.Millisecond)
+		}},
+	} {
+		// Tokens available right away, then tokens due within microseconds.
+		for _, refill := range []time.Duration{time.Nanosecond, 20 * time.Microsecond} {
+			b.Run(tc.name+"/refill="+refill.String(), func(b *testing.B) {
+				limiter := limiters.NewReservoirLimiter(1, refill)
+				ctx := context.Background()
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := tc.limit(limiter, ctx); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package limiters
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Records that waiting calls were handed tokens, or started waiting.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) progress(now time.Time) {
+	l.lastProgress = now
+	l.stallReported = false
+}
+
+// Starts watching for stalls if a stall detector is set.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) startWatchdog() {
+	if l.onStall == nil || l.stopStallWatch != nil {
+		return
+	}
+	stop := make(chan struct{})
+	l.stopStallWatch = func() { close(stop) }
+	go l.watchStalls(l.clock.NewTicker(l.stallAfter), stop)
+}
+
+// Stops watching for stalls.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) stopWatchdog() {
+	if l.stopStallWatch == nil {
+		return
+	}
+	l.stopStallWatch()
+	l.stopStallWatch = nil
+}
+
+// Checks for a stall on each tick, until stopped.
+//
+// The watchdog runs apart from the refill goroutine, so that it still reports
+// a stall if that goroutine dies or hangs.
+func (l *reservoirLimiter) watchStalls(ticker Ticker, stop <-chan struct{}) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+		case <-stop:
+			return
+		}
+		l.mutex.Lock()
+		now := l.clock.Now()
+		stalled := !l.stallReported && l.waiters.Len() > 0 && now.Sub(l.lastProgress) > l.stallAfter
+		if stalled {
+			l.stallReported = true
+		}
+		waiting, since := l.waiters.Len(), now.Sub(l.lastProgress)
+		l.mutex.Unlock()
+		if stalled {
+			l.reportStall(waiting, since)
+		}
+	}
+}
+
+// Logs a stall and calls the stall detector.
+func (l *reservoirLimiter) reportStall(waiting int, since time.Duration) {
+	if l.logger != nil {
+		l.logger.LogAttrs(context.Background(), slog.LevelWarn, "limiters: refill stalled",
+			slog.Int("waiting", waiting), slog.Duration("since", since), slog.String("limiter", l.name))
+	}
+	defer l.recoverCallback("stall detector")
+	l.onStall()
+}
@@ -0,0 +1,93 @@
+package limiters_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+// Clock whose tickers never fire, except those of the given period, to stall
+// the refill goroutine while the stall detector keeps running.
+type stallingClock struct {
+	*limiterstest.Clock
+	live time.Duration
+}
+
+// Ticker that never fires.
+type deadTicker struct{}
+
+func (deadTicker) C() <-chan time.Time { return nil }
+func (deadTicker) Stop()               {}
+
+func (c stallingClock) NewTicker(d time.Duration) limiters.Ticker {
+	if d == c.live {
+		return c.Clock.NewTicker(d)
+	}
+	return deadTicker{}
+}
+
+func TestReservoirLimiterStallDetector(t *testing.T) {
+	clock := stallingClock{Clock: limiterstest.NewClock(), live: time.Second}
+	var stalls atomic.Int64
+	limiter := limiters.NewReservoirLimiter(1, 100*time.Millisecond, limiters.WithClock(clock),
+		limiters.WithStallDetector(time.Second, func() { stalls.Add(1) }))
+	if !limiter.TryLimit() {
+		t.Fatal("expected a token")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- limiter.Limit(ctx) }()
+	eventually(t, func() bool { return limiter.(limiters.StatsReporter).Stats().Waiting == 1 })
+	clock.Advance(time.Second)
+	time.Sleep(10 * time.Millisecond)
+	if stalls.Load() != 0 {
+		t.Fatal("expected no stall before the threshold")
+	}
+
+	clock.Advance(time.Second)
+	eventually(t, func() bool { return stalls.Load() == 1 })
+	clock.Advance(time.Second)
+	time.Sleep(10 * time.Millisecond)
+	if got := stalls.Load(); got != 1 {
+		t.Fatalf("expected the stall to be reported once, got %d reports", got)
+	}
+	cancel()
+	<-done
+}
+
+func TestReservoirLimiterNoStallWhenRefilling(t *testing.T) {
+	clock := limiterstest.NewClock()
+	var stalls atomic.Int64
+	limiter := limiters.NewReservoirLimiter(1, 100*time.Millisecond, limiters.WithClock(clock),
+		limiters.WithStallDetector(0, func() { stalls.Add(1) }))
+	if !limiter.TryLimit() {
+		t.Fatal("expected a token")
+	}
+
+	// Keeps a call waiting at all times, served every refill.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan struct{})
+	go func() {
+		for limiter.Limit(ctx) == nil {
+			served <- struct{}{}
+		}
+		close(served)
+	}()
+	for i := 0; i < 30; i++ {
+		eventually(t, func() bool { return limiter.(limiters.StatsReporter).Stats().Waiting == 1 })
+		clock.Advance(100 * time.Millisecond)
+		<-served
+	}
+	if got := stalls.Load(); got != 0 {
+		t.Fatalf("expected no stall while tokens are refilled, got %d reports", got)
+	}
+	cancel()
+	for range served {
+	}
+}
@@ -0,0 +1,38 @@
+package limiters
+
+import "fmt"
+
+// Returns the current state of the reservoir.
+func (l *reservoirLimiter) Snapshot() LimiterState {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.refill(l.clock.Now())
+	return LimiterState{Tokens: l.tokenCount, LastRefill: l.lastRefill}
+}
+
+// Restores a state returned by Snapshot, possibly from another process.
+//
+// Tokens refilled since the snapshot are added, so that the reservoir is
+// where it would be had it kept running. Returns an error wrapping
+// ErrInvalidConfig if the state holds more tokens than the capacity.
+func (l *reservoirLimiter) RestoreState(state LimiterState) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		return ErrLimiterClosed
+	}
+	if state.Tokens < 0 || state.Tokens > l.maxTokens {
+		return fmt.Errorf("%w: %d tokens out of range [0, %d]", ErrInvalidConfig, state.Tokens, l.maxTokens)
+	}
+	now := l.clock.Now()
+	l.tokenCount = state.Tokens
+	l.lastRefill = state.LastRefill
+	if l.lastRefill.After(now) {
+		// Snapshot taken by a clock ahead of ours.
+		l.lastRefill = now
+	}
+	l.nextInterval = l.refillInterval()
+	l.refill(now)
+	l.distributeTokens()
+	return nil
+}
@@ -0,0 +1,75 @@
+package limiters_test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestReservoirLimiterSnapshotRestore(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(10, time.Second, limiters.WithClock(clock))
+	for i := 0; i < 8; i++ {
+		limiter.TryLimit()
+	}
+	clock.Advance(1500 * time.Millisecond)
+
+	data, err := json.Marshal(limiter.(limiters.StateSnapshotter).Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restart three seconds later.
+	clock.Advance(3 * time.Second)
+	restarted := limiters.NewReservoirLimiter(10, time.Second, limiters.WithClock(clock))
+	var state limiters.LimiterState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.(limiters.StateSnapshotter).RestoreState(state); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 2 tokens left, 1 refilled before the snapshot and 3 after.
+	if got := restarted.(limiters.TokenCounter).Available(); got != 6 {
+		t.Fatalf("expected 6 tokens, got %d", got)
+	}
+	clock.Advance(500 * time.Millisecond)
+	if got := restarted.(limiters.TokenCounter).Available(); got != 7 {
+		t.Fatalf("expected the refill period to carry over, got %d tokens", got)
+	}
+}
+
+func TestReservoirLimiterRestoreInvalid(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(5, time.Second)
+	err := limiter.(limiters.StateSnapshotter).RestoreState(limiters.LimiterState{Tokens: 6})
+	if !errors.Is(err, limiters.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestReservoirLimiterRestoreDuringSetRate(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(5, time.Second)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			limiter.(limiters.RateSetter).SetRate(5+i%2, time.Second)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		err := limiter.(limiters.StateSnapshotter).RestoreState(limiters.LimiterState{Tokens: 6})
+		if err != nil && !errors.Is(err, limiters.ErrInvalidConfig) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	wg.Wait()
+	if got := limiter.(limiters.TokenCounter).Available(); got > 6 {
+		t.Fatalf("expected the restored tokens to fit the capacity, got %d", got)
+	}
+}
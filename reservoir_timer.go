@@ -0,0 +1,94 @@
+package limiters
+
+import (
+	"math"
+	"time"
+)
+
+// Arms the refill timer for when the next waiter can be served.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) armRefillTimer() {
+	l.refillGen++
+	gen := l.refillGen
+	l.refillTimer = afterFunc(l.clock, l.scheduledRefillDelay(), func() { l.refillTimerFired(gen) })
+}
+
+// Arms the refill timer anew, in case it is due sooner.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) rearmRefillTimer() {
+	l.refillTimer.Stop()
+	l.armRefillTimer()
+}
+
+// Stops the refill timer, and keeps a timer already firing from arming a
+// new one.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) stopRefillTimer() {
+	l.refillGen++
+	l.refillTimer.Stop()
+	l.refillTimer = nil
+}
+
+// Refills missing tokens, then arms the timer for the next refill as long as
+// it is needed.
+//
+// Timers fired after being stopped or armed anew do nothing.
+func (l *reservoirLimiter) refillTimerFired(gen uint64) {
+	l.mutex.Lock()
+	current := gen == l.refillGen
+	l.mutex.Unlock()
+	if !current || !l.refillTick() {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if gen == l.refillGen {
+		l.armRefillTimer()
+	}
+}
+
+// Returns the number of tokens the next waiter to be served still needs, or
+// zero if no waiter can be served.
+//
+// Outside of fair mode, the waiter needing the fewest tokens is served first.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) nextWaiterNeeds(now time.Time) int {
+	if l.fair {
+		if elem := l.nextWaiter(now, math.MaxInt); elem != nil {
+			w := elem.Value.(*waiter)
+			return w.n - w.got
+		}
+		return 0
+	}
+	needs := 0
+	for elem := l.waiters.Front(); elem != nil; elem = elem.Next() {
+		if w := elem.Value.(*waiter); needs == 0 || w.n-w.got < needs {
+			needs = w.n - w.got
+		}
+	}
+	return needs
+}
+
+// Returns the time until the next waiter has enough tokens to be served, or
+// until the next tokens are due if it cannot be told.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) scheduledRefillDelay() time.Duration {
+	now := l.clock.Now()
+	if l.waiters.Len() == 0 && l.throttled {
+		// Only the recovery is left to check.
+		if d := l.lastThrottled.Add(l.refillDuration).Sub(now); d > 0 {
+			return d
+		}
+	}
+	missing := l.nextWaiterNeeds(now) - l.tokenCount
+	if missing <= l.batch {
+		return l.nextRefillDelay()
+	}
+	batches := (missing + l.batch - 1) / l.batch
+	return max(l.nextRefillDelay()+time.Duration(batches-1)*l.refillPeriod(), 0)
+}
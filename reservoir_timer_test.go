@@ -0,0 +1,109 @@
+package limiters_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+// Clock counting the timers that fired.
+type firingClock struct {
+	*limiterstest.Clock
+	fired *atomic.Int64
+}
+
+func (c firingClock) AfterFunc(d time.Duration, f func()) limiters.Timer {
+	return c.Clock.AfterFunc(d, func() {
+		c.fired.Add(1)
+		f()
+	})
+}
+
+func TestReservoirLimiterScheduledRefill(t *testing.T) {
+	const grants = 10
+	clock := firingClock{Clock: limiterstest.NewClock(), fired: &atomic.Int64{}}
+	limiter := limiters.NewReservoirLimiter(3, 20*time.Minute, limiters.WithClock(clock), limiters.WithScheduledRefill())
+	if err := limiter.LimitN(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each call waits for the whole reservoir to refill, over three refills.
+	served := make(chan struct{})
+	go func() {
+		for i := 0; i < grants; i++ {
+			if err := limiter.LimitN(context.Background(), 3); err != nil {
+				t.Error(err)
+			}
+			served <- struct{}{}
+		}
+	}()
+	for i := 0; i < grants; i++ {
+		eventually(t, func() bool { return limiter.(limiters.StatsReporter).Stats().Waiting == 1 })
+		for elapsed := time.Duration(0); elapsed < time.Hour; elapsed += time.Minute {
+			select {
+			case <-served:
+				t.Fatalf("grant %d: expected the call to wait for an hour, served after %v", i, elapsed)
+			default:
+			}
+			clock.Advance(time.Minute)
+		}
+		<-served
+	}
+	if got := clock.fired.Load(); got != grants {
+		t.Fatalf("expected a timer firing per grant, got %d firings for %d grants", got, grants)
+	}
+
+	// An idle limiter arms no timer.
+	clock.Advance(24 * time.Hour)
+	if got := clock.fired.Load(); got != grants {
+		t.Fatalf("expected no firing while idle, got %d", got-grants)
+	}
+	if tickers, timers := clock.ActiveTickers(), clock.PendingTimers(); tickers != 0 || timers != 0 {
+		t.Fatalf("expected no ticker nor timer, got %d and %d", tickers, timers)
+	}
+}
+
+func TestReservoirLimiterScheduledRefillServesSooner(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewReservoirLimiter(3, time.Minute, limiters.WithClock(clock),
+		limiters.WithScheduledRefill(), limiters.WithFairness(false))
+	if err := limiter.LimitN(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	large := make(chan error, 1)
+	go func() { large <- limiter.LimitN(context.Background(), 3) }()
+	eventually(t, func() bool { return limiter.(limiters.StatsReporter).Stats().Waiting == 1 })
+	small := make(chan error, 1)
+	go func() { small <- limiter.Limit(context.Background()) }()
+	eventually(t, func() bool { return limiter.(limiters.StatsReporter).Stats().Waiting == 2 })
+
+	clock.Advance(time.Minute)
+	select {
+	case err := <-small:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the new waiter to be served at the next refill")
+	}
+	clock.Advance(3 * time.Minute)
+	select {
+	case err := <-large:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the first waiter to be served once the reservoir refilled")
+	}
+}
+
+func TestReservoirLimiterScheduledRefillConformance(t *testing.T) {
+	limiterstest.RunConformanceTests(t, func(clock limiters.Clock, burst int, interval time.Duration) limiters.Limiter {
+		return limiters.NewReservoirLimiter(burst, interval, limiters.WithClock(clock), limiters.WithScheduledRefill())
+	})
+}
@@ -0,0 +1,61 @@
+package limiters
+
+import "time"
+
+// Records that a call had to wait, reporting that the limiter started
+// throttling if it was not already.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) markThrottled(now time.Time) {
+	if l.onTransition == nil {
+		return
+	}
+	l.lastThrottled = now
+	if !l.throttled {
+		l.throttled = true
+		l.transitions = append(l.transitions, true)
+	}
+}
+
+// Reports that a throttled limiter recovered once no call is waiting, tokens
+// are available, and no call had to wait for a refill duration.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) checkRecovered(now time.Time) {
+	if !l.throttled || l.waiters.Len() > 0 || l.tokenCount == 0 || now.Sub(l.lastThrottled) < l.refillDuration {
+		return
+	}
+	l.throttled = false
+	l.transitions = append(l.transitions, false)
+}
+
+// Calls the transition function with the pending transitions, in order.
+//
+// Must be called without holding the mutex. If another goroutine is already
+// notifying, it takes care of the pending transitions, which also lets the
+// transition function call the limiter.
+func (l *reservoirLimiter) notifyTransitions() {
+	if l.onTransition == nil {
+		return
+	}
+	for l.notifying.TryLock() {
+		for {
+			l.mutex.Lock()
+			if len(l.transitions) == 0 {
+				l.mutex.Unlock()
+				break
+			}
+			empty := l.transitions[0]
+			l.transitions = l.transitions[1:]
+			l.mutex.Unlock()
+			l.transition(empty)
+		}
+		l.notifying.Unlock()
+		l.mutex.Lock()
+		pending := len(l.transitions) > 0
+		l.mutex.Unlock()
+		if !pending {
+			return
+		}
+	}
+}
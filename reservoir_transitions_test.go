@@ -0,0 +1,92 @@
+package limiters_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+// Records the transitions reported by a limiter.
+type transitionRecorder struct {
+	mutex       sync.Mutex
+	transitions []bool
+}
+
+func (r *transitionRecorder) record(empty bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.transitions = append(r.transitions, empty)
+}
+
+func (r *transitionRecorder) get() []bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return slices.Clone(r.transitions)
+}
+
+func TestReservoirLimiterTransitions(t *testing.T) {
+	clock := limiterstest.NewClock()
+	recorder := &transitionRecorder{}
+	limiter := limiters.NewReservoirLimiter(1, time.Second,
+		limiters.WithClock(clock), limiters.WithTransitionCallback(recorder.record))
+	reporter := limiter.(limiters.StatsReporter)
+	limit := func() {
+		done := make(chan error)
+		go func() { done <- limiter.Limit(context.Background()) }()
+		eventually(t, func() bool { return reporter.Stats().Waiting == 1 })
+		clock.Advance(time.Second)
+		if err := <-done; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	limiter.Limit(context.Background())
+	if got := recorder.get(); len(got) != 0 {
+		t.Fatalf("expected no transition without waiting, got %v", got)
+	}
+
+	limit()
+	if got := recorder.get(); !slices.Equal(got, []bool{true}) {
+		t.Fatalf("expected the limiter to report throttling, got %v", got)
+	}
+
+	// Another call waits right after: still throttling.
+	limit()
+	if got := recorder.get(); !slices.Equal(got, []bool{true}) {
+		t.Fatalf("expected flapping not to be reported, got %v", got)
+	}
+
+	clock.Advance(time.Second)
+	eventually(t, func() bool { return slices.Equal(recorder.get(), []bool{true, false}) })
+	eventually(t, func() bool { return clock.ActiveTickers() == 0 })
+
+	limiter.Limit(context.Background())
+	limit()
+	if got := recorder.get(); !slices.Equal(got, []bool{true, false, true}) {
+		t.Fatalf("expected the limiter to report throttling again, got %v", got)
+	}
+}
+
+func TestReservoirLimiterTransitionCallbackCallsLimiter(t *testing.T) {
+	clock := limiterstest.NewClock()
+	var limiter limiters.Limiter
+	calls := make(chan bool, 2)
+	limiter = limiters.NewReservoirLimiter(1, time.Second, limiters.WithClock(clock),
+		limiters.WithTransitionCallback(func(empty bool) {
+			limiter.TryLimit()
+			calls <- empty
+		}))
+	limiter.Limit(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go limiter.Limit(ctx)
+	if empty := <-calls; !empty {
+		t.Fatal("expected the limiter to report throttling")
+	}
+}
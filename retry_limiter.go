@@ -0,0 +1,81 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+)
+
+// Struct implementing the Limiter interface.
+type retryLimiter struct {
+	limiter     Limiter
+	backoff     Backoff
+	maxAttempts int
+	clock       Clock
+}
+
+// Creates a limiter trying to take a token from the given limiter without
+// blocking, up to maxAttempts times, sleeping between attempts as decided by
+// the backoff.
+//
+// This bounds the wait on limiters that cannot queue callers, e.g. those
+// sharing their budget through a remote store. Of the options, only
+// WithClock applies. Panics if the backoff is nil or maxAttempts is not
+// positive.
+func NewRetryLimiter(l Limiter, backoff Backoff, maxAttempts int, opts ...Option) Limiter {
+	switch {
+	case backoff == nil:
+		panic(fmt.Errorf("%w: nil backoff", ErrInvalidConfig))
+	case maxAttempts <= 0:
+		panic(fmt.Errorf("%w: %d attempts is not positive", ErrInvalidConfig, maxAttempts))
+	}
+	return &retryLimiter{limiter: l, backoff: backoff, maxAttempts: maxAttempts, clock: newOptions(opts).clock}
+}
+
+// Tries to take a token until an attempt succeeds, returning ErrExhausted
+// once all attempts failed.
+func (l *retryLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Tries to take n tokens at once until an attempt succeeds, returning
+// ErrExhausted once all attempts failed.
+//
+// The tokens are taken at once if the limiter implements BatchTryLimiter, and
+// otherwise one at a time: if the limiter runs out midway, those already
+// taken are given back when it implements TokenReturner.
+func (l *retryLimiter) LimitN(ctx context.Context, n int) error {
+	for attempt := 0; attempt < l.maxAttempts; attempt++ {
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		if tryLimitN(l.limiter, n) {
+			return nil
+		}
+		if attempt == l.maxAttempts-1 {
+			break
+		}
+		delay := l.backoff.Next(attempt)
+		if delay <= 0 {
+			continue
+		}
+		ticker := l.clock.NewTicker(delay)
+		select {
+		case <-ticker.C():
+			ticker.Stop()
+		case <-ctx.Done():
+			ticker.Stop()
+			return contextError(ctx)
+		}
+	}
+	return ErrExhausted
+}
+
+// Consumes a token if one is immediately available, in a single attempt.
+func (l *retryLimiter) TryLimit() bool {
+	return l.limiter.TryLimit()
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *retryLimiter) Allow() bool {
+	return l.limiter.Allow()
+}
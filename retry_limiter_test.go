@@ -0,0 +1,90 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+var errRejected = errors.New("rejected")
+
+func TestRetryLimiterAttempts(t *testing.T) {
+	fake := limiterstest.NewFakeLimiter()
+	fake.QueueResults(errRejected, errRejected, nil)
+	limiter := limiters.NewRetryLimiter(fake, limiters.ConstantBackoff{Delay: time.Millisecond}, 3)
+
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatalf("expected the third attempt to succeed, got %v", err)
+	}
+	if got := fake.Calls(); got != 3 {
+		t.Fatalf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestRetryLimiterExhausted(t *testing.T) {
+	fake := limiterstest.NewFakeLimiter()
+	fake.QueueResults(errRejected, errRejected, errRejected, errRejected, nil)
+	limiter := limiters.NewRetryLimiter(fake, limiters.ConstantBackoff{Delay: time.Millisecond}, 4)
+
+	if err := limiter.Limit(context.Background()); !errors.Is(err, limiters.ErrExhausted) {
+		t.Fatalf("expected ErrExhausted, got %v", err)
+	}
+	if got := fake.Calls(); got != 4 {
+		t.Fatalf("expected 4 attempts, got %d", got)
+	}
+}
+
+func TestRetryLimiterCanceledDuringBackoff(t *testing.T) {
+	fake := limiterstest.NewFakeLimiter()
+	fake.QueueResults(errRejected)
+	limiter := limiters.NewRetryLimiter(fake, limiters.ConstantBackoff{Delay: time.Hour}, 2)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := limiter.Limit(ctx); !errors.Is(err, limiters.ErrDeadlineExceeded) {
+		t.Fatalf("expected ErrDeadlineExceeded, got %v", err)
+	}
+	if got := fake.Calls(); got != 1 {
+		t.Fatalf("expected a single attempt, got %d", got)
+	}
+}
+
+func TestRetryLimiterBackoffOnClock(t *testing.T) {
+	clock := limiterstest.NewClock()
+	fake := limiterstest.NewFakeLimiter()
+	fake.QueueResults(errRejected, nil)
+	limiter := limiters.NewRetryLimiter(fake, limiters.ConstantBackoff{Delay: time.Hour}, 2, limiters.WithClock(clock))
+
+	done := make(chan error, 1)
+	go func() { done <- limiter.Limit(context.Background()) }()
+	eventually(t, func() bool { return clock.ActiveTickers() == 1 })
+	clock.Advance(time.Hour)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected the second attempt to succeed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the backoff to elapse on the clock")
+	}
+}
+
+func TestRetryLimiterZeroBackoff(t *testing.T) {
+	fake := limiterstest.NewFakeLimiter()
+	fake.QueueResults(errRejected, nil)
+	limiter := limiters.NewRetryLimiter(fake, limiters.ConstantBackoff{}, 2)
+
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatalf("expected the second attempt to succeed right away, got %v", err)
+	}
+}
+
+func TestRetryLimiterInvalid(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Second)
+	assertInvalidConfig(t, func() { limiters.NewRetryLimiter(limiter, nil, 3) })
+	assertInvalidConfig(t, func() { limiters.NewRetryLimiter(limiter, limiters.ConstantBackoff{Delay: time.Millisecond}, 0) })
+}
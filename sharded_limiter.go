@@ -0,0 +1,162 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Struct implementing the Limiter interface.
+type shardedLimiter struct {
+	shards     []*reservoirLimiter
+	next       atomic.Uint64
+	name       string
+	observer   Observer
+	unregister func()
+}
+
+// Creates a limiter splitting the budget of a reservoir limiter across
+// independent shards, each behind its own lock.
+//
+// Each shard holds its share of the tokens, and refills them shards times
+// slower, so that together they refill at the configured rate. Calls are
+// routed to the shards in turn. A blocking call waits on its shard even if
+// another one has tokens, and may ask for at most the tokens of a shard.
+// Options apply to every shard, e.g. WithInitialTokens sets the tokens of
+// each of them, except for WithName, WithObserver and WithRegistry, which
+// apply to the sharded limiter as a whole: each call is observed once, even
+// when TryLimit tries several shards. Panics if shards is not between 1 and
+// maxTokens, or if the options are invalid.
+//
+// Whether sharding lowers the cost of a call depends on the number of cores
+// and on the load: measure it before preferring it to a single reservoir.
+func NewShardedReservoirLimiter(maxTokens int, refillDuration time.Duration, shards int, opts ...Option) Limiter {
+	if shards <= 0 || shards > maxTokens {
+		panic(fmt.Errorf("%w: %d shards out of range [1, %d]", ErrInvalidConfig, shards, maxTokens))
+	}
+	o := newOptions(opts)
+	l := &shardedLimiter{shards: make([]*reservoirLimiter, shards), name: o.name, observer: o.observer}
+	var shardObserver Observer
+	if o.observer != nil {
+		shardObserver = waitObserver{o.observer}
+	}
+	shardOpts := append(opts[:len(opts):len(opts)], func(o *options) {
+		o.observer = shardObserver
+		o.registry = nil
+	})
+	for i := range l.shards {
+		tokens := maxTokens / shards
+		if i < maxTokens%shards {
+			tokens++
+		}
+		l.shards[i] = NewReservoirLimiter(tokens, refillDuration*time.Duration(shards), shardOpts...).(*reservoirLimiter)
+	}
+	if o.registry != nil {
+		l.unregister = o.registry.Register(l)
+	}
+	return l
+}
+
+// Observer forwarding only the wait events, the sharded limiter reporting
+// whether calls are admitted itself.
+type waitObserver struct {
+	Observer
+}
+
+func (waitObserver) OnGrant()  {}
+func (waitObserver) OnReject() {}
+
+// Calls the observer, recovering from its panics.
+func (l *shardedLimiter) observe(event func(Observer)) {
+	if l.observer == nil {
+		return
+	}
+	defer l.shards[0].recoverCallback("observer")
+	event(l.observer)
+}
+
+// Reports whether the call was admitted to the observer, and returns err.
+func (l *shardedLimiter) report(err error) error {
+	if err != nil {
+		l.observe(Observer.OnReject)
+	} else {
+		l.observe(Observer.OnGrant)
+	}
+	return err
+}
+
+// Returns the shard of the next call.
+func (l *shardedLimiter) shard() int {
+	return int((l.next.Add(1) - 1) % uint64(len(l.shards)))
+}
+
+// Blocks until a token is available or the context is canceled.
+func (l *shardedLimiter) Limit(ctx context.Context) error {
+	return l.report(l.shards[l.shard()].Limit(ctx))
+}
+
+// Blocks until n tokens are available or the context is canceled.
+func (l *shardedLimiter) LimitN(ctx context.Context, n int) error {
+	return l.report(l.shards[l.shard()].LimitN(ctx, n))
+}
+
+// Consumes a token if one is immediately available, without blocking.
+//
+// The shards are tried in turn, starting from the next one, so that the call
+// fails only if none of them has a token.
+func (l *shardedLimiter) TryLimit() bool {
+	first := l.shard()
+	for i := range l.shards {
+		if l.shards[(first+i)%len(l.shards)].TryLimit() {
+			l.observe(Observer.OnGrant)
+			return true
+		}
+	}
+	l.observe(Observer.OnReject)
+	return false
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *shardedLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Returns the number of tokens available across the shards.
+func (l *shardedLimiter) Available() int {
+	total := 0
+	for _, shard := range l.shards {
+		total += shard.Available()
+	}
+	return total
+}
+
+// Returns the name set with WithName.
+func (l *shardedLimiter) Name() string {
+	return l.name
+}
+
+// Returns the counters of the shards added up.
+func (l *shardedLimiter) Stats() Stats {
+	var total Stats
+	for _, shard := range l.shards {
+		stats := shard.Stats()
+		total.Granted += stats.Granted
+		total.Canceled += stats.Canceled
+		total.Waiting += stats.Waiting
+	}
+	return total
+}
+
+// Closes every shard, and unregisters the limiter.
+//
+// Close is idempotent and always returns nil.
+func (l *shardedLimiter) Close() error {
+	for _, shard := range l.shards {
+		shard.Close()
+	}
+	if l.unregister != nil {
+		l.unregister()
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestShardedReservoirLimiterAggregateRate(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewShardedReservoirLimiter(10, time.Second, 4, limiters.WithClock(clock))
+	defer limiter.(io.Closer).Close()
+	counter := limiter.(limiters.TokenCounter)
+
+	granted := 0
+	for limiter.TryLimit() {
+		granted++
+	}
+	if granted != 10 {
+		t.Fatalf("expected the shards to hold 10 tokens together, got %d", granted)
+	}
+	clock.Advance(8 * time.Second)
+	if got := counter.Available(); got != 8 {
+		t.Fatalf("expected 8 tokens refilled in 8 seconds, got %d", got)
+	}
+
+	// Blocking calls are spread over the shards.
+	for i := 0; i < 8; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := limiter.Limit(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestShardedReservoirLimiterInvalidShards(t *testing.T) {
+	for _, shards := range []int{0, 3} {
+		func() {
+			defer func() {
+				if err, _ := recover().(error); !errors.Is(err, limiters.ErrInvalidConfig) {
+					t.Errorf("expected a panic with ErrInvalidConfig for %d shards, got %v", shards, err)
+				}
+			}()
+			limiters.NewShardedReservoirLimiter(2, time.Second, shards)
+		}()
+	}
+}
+
+func TestShardedReservoirLimiterReportsOnce(t *testing.T) {
+	observer := &countingObserver{}
+	registry := &limiters.Registry{}
+	limiter := limiters.NewShardedReservoirLimiter(4, time.Hour, 4, limiters.WithObserver(observer), limiters.WithName("api"), limiters.WithRegistry(registry))
+
+	for i := 0; i < 4; i++ {
+		if !limiter.TryLimit() {
+			t.Fatalf("expected call %d to be admitted", i)
+		}
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected the shards to be exhausted")
+	}
+	if grants, rejects := observer.grants.Load(), observer.rejects.Load(); grants != 4 || rejects != 1 {
+		t.Fatalf("expected each call to be observed once, got %d grants and %d rejects", grants, rejects)
+	}
+	infos := registry.List()
+	if len(infos) != 1 || infos[0].Name != "api" {
+		t.Fatalf("expected the sharded limiter to be registered once as api, got %+v", infos)
+	}
+	if got := limiter.(limiters.StatsReporter).Stats().Granted; got != 4 {
+		t.Fatalf("expected the stats of the shards to add up to 4 grants, got %d", got)
+	}
+	limiter.(io.Closer).Close()
+	if got := len(registry.List()); got != 0 {
+		t.Fatalf("expected Close to unregister the limiter, got %d entries", got)
+	}
+}
+
+func BenchmarkShardedReservoirLimiterParallel(b *testing.B) {
+	for _, shards := range []int{1, 8} {
+		name := "single"
+		if shards > 1 {
+			name = "sharded"
+		}
+		b.Run(name, func(b *testing.B) {
+			limiter := limiters.NewShardedReservoirLimiter(1<<30, time.Nanosecond, shards)
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := limiter.Limit(ctx); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Struct implementing the Limiter interface.
+type slidingWindowLimiter struct {
+	limit  int
+	window time.Duration
+	clock  Clock
+	mutex  sync.Mutex
+	grants []time.Time
+}
+
+// Creates a new sliding window limiter.
+//
+// At most limit calls are admitted over any trailing window. Of the options,
+// only WithClock applies. Panics if the limit or the window is not positive.
+func NewSlidingWindowLimiter(limit int, window time.Duration, opts ...Option) Limiter {
+	switch {
+	case limit <= 0:
+		panic(fmt.Errorf("%w: limit %d is not positive", ErrInvalidConfig, limit))
+	case window <= 0:
+		panic(fmt.Errorf("%w: window %v is not positive", ErrInvalidConfig, window))
+	}
+	return &slidingWindowLimiter{
+		limit:  limit,
+		window: window,
+		clock:  newOptions(opts).clock,
+		grants: make([]time.Time, 0, limit),
+	}
+}
+
+// Blocks until a call can be admitted or the context is canceled.
+func (l *slidingWindowLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until n calls can be admitted at once or the context is canceled.
+func (l *slidingWindowLimiter) LimitN(ctx context.Context, n int) error {
+	for {
+		delay, err := l.admit(n)
+		if err != nil || delay == 0 {
+			return err
+		}
+		ticker := l.clock.NewTicker(delay)
+		select {
+		case <-ticker.C():
+			ticker.Stop()
+		case <-ctx.Done():
+			ticker.Stop()
+			return contextError(ctx)
+		}
+	}
+}
+
+// Admits a call if the window allows it, without blocking.
+//
+// Returns false if the call would have to wait, in which case nothing was
+// recorded.
+func (l *slidingWindowLimiter) TryLimit() bool {
+	delay, err := l.admit(1)
+	return err == nil && delay == 0
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *slidingWindowLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Returns the estimated time until a call would be admitted, zero if it
+// would be admitted right away.
+func (l *slidingWindowLimiter) EstimateDelay() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	l.prune(now)
+	if len(l.grants) < l.limit {
+		return 0
+	}
+	return l.grants[len(l.grants)-l.limit].Add(l.window).Sub(now)
+}
+
+// Forgets the n most recent grants, which were not used.
+func (l *slidingWindowLimiter) ReturnTokens(n int) {
+	if n <= 0 {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.grants = l.grants[:max(len(l.grants)-n, 0)]
+}
+
+// Records n grants if the window allows it.
+//
+// Returns zero on success and the time until enough grants expire otherwise,
+// or ErrExceedsCapacity if n grants never fit in the window.
+func (l *slidingWindowLimiter) admit(n int) (time.Duration, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	if n > l.limit {
+		return 0, ErrExceedsCapacity
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	l.prune(now)
+	if len(l.grants)+n <= l.limit {
+		for i := 0; i < n; i++ {
+			l.grants = append(l.grants, now)
+		}
+		return 0, nil
+	}
+	oldest := l.grants[len(l.grants)+n-l.limit-1]
+	return oldest.Add(l.window).Sub(now), nil
+}
+
+// Drops the grants that fell out of the window.
+//
+// Must be called with the mutex held.
+func (l *slidingWindowLimiter) prune(now time.Time) {
+	expired := 0
+	for expired < len(l.grants) && !now.Before(l.grants[expired].Add(l.window)) {
+		expired++
+	}
+	if expired > 0 {
+		kept := copy(l.grants, l.grants[expired:])
+		l.grants = l.grants[:kept]
+	}
+}
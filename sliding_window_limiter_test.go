@@ -0,0 +1,100 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestSlidingWindowLimiterBurst(t *testing.T) {
+	const (
+		limit  = 5
+		window = 50 * time.Millisecond
+	)
+	limiter := limiters.NewSlidingWindowLimiter(limit, window)
+
+	var (
+		mutex  sync.Mutex
+		grants []time.Time
+		wg     sync.WaitGroup
+	)
+	for i := 0; i < 3*limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Limit(context.Background()); err != nil {
+				t.Error(err)
+				return
+			}
+			mutex.Lock()
+			grants = append(grants, time.Now())
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	slices.SortFunc(grants, func(a, b time.Time) int { return a.Compare(b) })
+	for i := limit; i < len(grants); i++ {
+		if span := grants[i].Sub(grants[i-limit]); span < window-5*time.Millisecond {
+			t.Errorf("%d grants within %v, expected at most %d per %v", limit+1, span, limit, window)
+		}
+	}
+}
+
+func TestSlidingWindowLimiterTryLimit(t *testing.T) {
+	limiter := limiters.NewSlidingWindowLimiter(2, 30*time.Millisecond)
+	if !limiter.TryLimit() || !limiter.TryLimit() {
+		t.Fatal("expected the first two calls to be admitted")
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected the third call to be rejected")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if !limiter.TryLimit() {
+		t.Fatal("expected a call to be admitted once the window slid")
+	}
+}
+
+func TestSlidingWindowLimiterExceedsCapacity(t *testing.T) {
+	limiter := limiters.NewSlidingWindowLimiter(2, time.Second)
+	if err := limiter.LimitN(context.Background(), 3); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity, got %v", err)
+	}
+	if !limiter.TryLimit() {
+		t.Fatal("expected a call to be admitted")
+	}
+	if err := limiter.LimitN(context.Background(), 3); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity with grants in the window, got %v", err)
+	}
+}
+
+func TestSlidingWindowLimiterClock(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewSlidingWindowLimiter(2, time.Minute, limiters.WithClock(clock))
+	if !limiter.TryLimit() || !limiter.TryLimit() || limiter.TryLimit() {
+		t.Fatal("expected two calls to be admitted per window")
+	}
+	clock.Advance(time.Minute)
+	if !limiter.TryLimit() {
+		t.Fatal("expected a call to be admitted once the window slid on the clock")
+	}
+}
+
+func TestSlidingWindowLimiterInvalid(t *testing.T) {
+	assertInvalidConfig(t, func() { limiters.NewSlidingWindowLimiter(-1, time.Second) })
+	assertInvalidConfig(t, func() { limiters.NewSlidingWindowLimiter(0, time.Second) })
+	assertInvalidConfig(t, func() { limiters.NewSlidingWindowLimiter(1, 0) })
+}
+
+func TestSlidingWindowLimiterConformance(t *testing.T) {
+	profile := limiterstest.Profile{Burst: 3, Interval: time.Second, Refill: 3}
+	limiterstest.RunProfileConformanceTests(t, profile, func(clock limiters.Clock) limiters.Limiter {
+		return limiters.NewSlidingWindowLimiter(3, time.Second, limiters.WithClock(clock))
+	})
+}
@@ -0,0 +1,125 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// Window over which an SLO limiter admits its limit.
+const sloWindow = time.Second
+
+// Struct implementing the Limiter interface.
+type sloLimiter struct {
+	limiter  Limiter
+	target   time.Duration
+	minLimit int
+	maxLimit int
+	increase float64
+	decrease float64
+	mutex    sync.Mutex
+	// Kept fractional, so that small steps add up.
+	level float64
+	limit int
+}
+
+// Creates a new limiter adjusting its rate to keep the latency of the
+// operations it protects under the target, admitting between min and max
+// calls per second. It starts at min.
+//
+// Callers report the latency of each completed operation with Record. The
+// limit follows the relative error e = (target - latency) / target, bounded
+// to [-1, 1]: while latency is under target, the limit is multiplied by
+// 1 + increase*e, and while it is over, by 1 + decrease*e. The gains are set
+// with WithSLOGains: by default, the limit grows by up to 10% and shrinks by
+// up to 50% per operation, so that it probes slowly and backs off fast. It
+// settles where latency meets the target. Calls are spread over the second by
+// an underlying reservoir limiter, which receives the other options.
+//
+// Panics if the settings are invalid.
+func NewSLOLimiter(targetLatency time.Duration, min, max int, opts ...Option) Limiter {
+	o := newOptions(opts)
+	switch {
+	case targetLatency <= 0:
+		panic(fmt.Errorf("%w: target latency %v is not positive", ErrInvalidConfig, targetLatency))
+	case min <= 0 || min > max:
+		panic(fmt.Errorf("%w: limits must satisfy 0 < min %d <= max %d", ErrInvalidConfig, min, max))
+	case max > int(sloWindow):
+		panic(fmt.Errorf("%w: max %d exceeds %d calls per second", ErrInvalidConfig, max, int(sloWindow)))
+	case !(o.sloIncrease > 0) || math.IsInf(o.sloIncrease, 1):
+		panic(fmt.Errorf("%w: increase gain %v is not positive", ErrInvalidConfig, o.sloIncrease))
+	case !(o.sloDecrease > 0) || o.sloDecrease >= 1:
+		panic(fmt.Errorf("%w: decrease gain %v out of range (0, 1)", ErrInvalidConfig, o.sloDecrease))
+	}
+	return &sloLimiter{
+		limiter:  NewReservoirLimiter(min, sloWindow/time.Duration(min), opts...),
+		target:   targetLatency,
+		minLimit: min,
+		maxLimit: max,
+		increase: o.sloIncrease,
+		decrease: o.sloDecrease,
+		level:    float64(min),
+		limit:    min,
+	}
+}
+
+// Blocks until a token is available or the context is canceled.
+func (l *sloLimiter) Limit(ctx context.Context) error {
+	return l.limiter.Limit(ctx)
+}
+
+// Blocks until n tokens are available or the context is canceled.
+func (l *sloLimiter) LimitN(ctx context.Context, n int) error {
+	return l.limiter.LimitN(ctx, n)
+}
+
+// Consumes a token if one is immediately available, without blocking.
+func (l *sloLimiter) TryLimit() bool {
+	return l.limiter.TryLimit()
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *sloLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Adjusts the limit after a protected operation completed with the given
+// latency.
+//
+// Negative latencies are ignored.
+func (l *sloLimiter) Record(latency time.Duration) {
+	if latency < 0 {
+		return
+	}
+	e := max(float64(l.target-latency)/float64(l.target), -1)
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if e >= 0 {
+		l.level *= 1 + l.increase*e
+	} else {
+		l.level *= 1 + l.decrease*e
+	}
+	l.level = min(max(l.level, float64(l.minLimit)), float64(l.maxLimit))
+	limit := int(math.Round(l.level))
+	if limit == l.limit {
+		return
+	}
+	if l.limiter.(RateSetter).SetRate(limit, sloWindow/time.Duration(limit)) == nil {
+		l.limit = limit
+	}
+}
+
+// Returns the number of calls currently admitted per second.
+func (l *sloLimiter) CurrentLimit() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.limit
+}
+
+// Closes the underlying limiter.
+func (l *sloLimiter) Close() error {
+	return l.limiter.(io.Closer).Close()
+}
@@ -0,0 +1,110 @@
+package limiters_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+// Latency of a simulated queue serving capacity calls per second, each taking
+// service on its own, when offered rate calls per second: it rises with the
+// load, and grows without bound as the queue saturates.
+func queueLatency(rate, capacity int, service time.Duration) time.Duration {
+	load := float64(rate) / float64(capacity)
+	if load >= 1 {
+		return time.Minute
+	}
+	return time.Duration(float64(service) / (1 - load))
+}
+
+func TestSLOLimiterConverges(t *testing.T) {
+	const capacity = 100
+	limiter := limiters.NewSLOLimiter(50*time.Millisecond, 1, 1000)
+	slo := limiter.(limiters.LatencyRecorder)
+
+	// The target is met at 80% of the capacity of the queue.
+	for i := 0; i < 1000; i++ {
+		limit := slo.CurrentLimit()
+		if i >= 200 && (limit < 70 || limit > 85) {
+			t.Fatalf("iteration %d: limit %d did not converge around 80", i, limit)
+		}
+		slo.Record(queueLatency(limit, capacity, 10*time.Millisecond))
+	}
+
+	// The limit follows the queue when its capacity drops.
+	for i := 0; i < 1000; i++ {
+		limit := slo.CurrentLimit()
+		if i >= 200 && (limit < 35 || limit > 42) {
+			t.Fatalf("iteration %d: limit %d did not converge around 40", i, limit)
+		}
+		slo.Record(queueLatency(limit, capacity/2, 10*time.Millisecond))
+	}
+}
+
+func TestSLOLimiterBounds(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewSLOLimiter(time.Second, 2, 6, limiters.WithClock(clock), limiters.WithSLOGains(1, 0.9))
+	slo := limiter.(limiters.LatencyRecorder)
+	if got := slo.CurrentLimit(); got != 2 {
+		t.Fatalf("expected the limit to start at 2, got %d", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		slo.Record(0)
+	}
+	if got := slo.CurrentLimit(); got != 6 {
+		t.Fatalf("expected the limit to be capped at 6, got %d", got)
+	}
+	clock.Advance(time.Second)
+	for i := 0; i < 6; i++ {
+		if !limiter.TryLimit() {
+			t.Fatalf("expected call %d to be admitted", i)
+		}
+	}
+	if limiter.TryLimit() {
+		t.Fatal("expected the seventh call to be rejected")
+	}
+
+	slo.Record(-time.Second)
+	if got := slo.CurrentLimit(); got != 6 {
+		t.Fatalf("expected a negative latency to be ignored, got %d", got)
+	}
+	slo.Record(time.Hour)
+	if got := slo.CurrentLimit(); got != 2 {
+		t.Fatalf("expected the limit to floor at 2, got %d", got)
+	}
+}
+
+func TestSLOLimiterKeepsLimitWhenSetRateFails(t *testing.T) {
+	limiter := limiters.NewSLOLimiter(time.Second, 2, 6, limiters.WithSLOGains(1, 0.5))
+	slo := limiter.(limiters.LatencyRecorder)
+	if err := limiter.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	slo.Record(0)
+	if got := slo.CurrentLimit(); got != 2 {
+		t.Fatalf("expected the limit to stay at 2 while the rate cannot be set, got %d", got)
+	}
+}
+
+func TestSLOLimiterInvalidSettings(t *testing.T) {
+	for name, build := range map[string]func(){
+		"target":   func() { limiters.NewSLOLimiter(0, 1, 10) },
+		"min":      func() { limiters.NewSLOLimiter(time.Second, 0, 10) },
+		"max":      func() { limiters.NewSLOLimiter(time.Second, 5, 4) },
+		"increase": func() { limiters.NewSLOLimiter(time.Second, 1, 10, limiters.WithSLOGains(0, 0.5)) },
+		"decrease": func() { limiters.NewSLOLimiter(time.Second, 1, 10, limiters.WithSLOGains(0.1, 1)) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected a panic")
+				}
+			}()
+			build()
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package limiters
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Status of a limiter, as reported by StatusHandler.
+//
+// Fields the limiter cannot report are omitted.
+type limiterStatus struct {
+	Name           string   `json:"name,omitempty"`
+	MaxTokens      *int     `json:"max_tokens,omitempty"`
+	RefillDuration string   `json:"refill_duration,omitempty"`
+	Available      *int     `json:"available,omitempty"`
+	Waiting        *int64   `json:"waiting,omitempty"`
+	DropRate       *float64 `json:"drop_rate,omitempty"`
+	WaitP99        string   `json:"wait_p99,omitempty"`
+}
+
+// Implemented by limiters whose wait times may not be recorded, in which case
+// they cannot report their quantiles.
+type waitRecorder interface {
+	recordsWaits() bool
+}
+
+// Returns a handler reporting the status of the limiter as JSON, e.g. to be
+// mounted at /debug/limiter.
+//
+// The status holds the configured rate, available tokens, waiting calls,
+// drop rate and 99th percentile of wait times, each reported only if the
+// limiter implements the matching interface: RateReporter, TokenCounter,
+// StatsReporter, DropReporter and WaitQuantiler. Durations are formatted like
+// time.Duration.String. The wait times are left out of the status of a
+// reservoir limiter not created WithWaitHistogram.
+func StatusHandler(l Limiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var status limiterStatus
+		if namer, ok := l.(Namer); ok {
+			status.Name = namer.Name()
+		}
+		if reporter, ok := l.(RateReporter); ok {
+			maxTokens, refillDuration := reporter.CurrentRate()
+			status.MaxTokens = &maxTokens
+			status.RefillDuration = refillDuration.String()
+		}
+		if counter, ok := l.(TokenCounter); ok {
+			available := counter.Available()
+			status.Available = &available
+		}
+		if reporter, ok := l.(StatsReporter); ok {
+			waiting := reporter.Stats().Waiting
+			status.Waiting = &waiting
+		}
+		if reporter, ok := l.(DropReporter); ok {
+			rate := reporter.DropRate()
+			status.DropRate = &rate
+		}
+		if quantiler, ok := waitQuantiler(l); ok {
+			status.WaitP99 = quantiler.WaitQuantile(0.99).String()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
+// Returns the limiter as a WaitQuantiler, if it implements it and records its
+// wait times: otherwise, the quantiles would read as zero waits.
+func waitQuantiler(l Limiter) (WaitQuantiler, bool) {
+	if recorder, ok := l.(waitRecorder); ok && !recorder.recordsWaits() {
+		return nil, false
+	}
+	quantiler, ok := l.(WaitQuantiler)
+	return quantiler, ok
+}
@@ -0,0 +1,66 @@
+package limiters_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+// Serves a status request, returning the decoded JSON body.
+func getStatus(t *testing.T, l limiters.Limiter) map[string]any {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	limiters.StatusHandler(l)(rec, httptest.NewRequest(http.MethodGet, "/debug/limiter", nil))
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("expected a JSON response, got %q", got)
+	}
+	var status map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func TestStatusHandlerReservoir(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(3, time.Second, limiters.WithName("api"),
+		limiters.WithClock(limiterstest.NewClock()), limiters.WithWaitHistogram())
+	for i := 0; i < 4; i++ {
+		limiter.TryLimit()
+	}
+
+	want := map[string]any{
+		"name":            "api",
+		"max_tokens":      3.0,
+		"refill_duration": "1s",
+		"available":       0.0,
+		"waiting":         0.0,
+		"drop_rate":       0.25,
+		"wait_p99":        "0s",
+	}
+	if got := getStatus(t, limiter); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestStatusHandlerOmitsUnknownFields(t *testing.T) {
+	if got := getStatus(t, limiterstest.NewFakeLimiter()); len(got) != 0 {
+		t.Fatalf("expected an empty status, got %v", got)
+	}
+}
+
+func TestStatusHandlerOmitsUnrecordedWaits(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(3, time.Second)
+	status := getStatus(t, limiter)
+	if got, ok := status["wait_p99"]; ok {
+		t.Fatalf("expected no wait quantile without a histogram, got %v", got)
+	}
+	if _, ok := status["available"]; !ok {
+		t.Fatalf("expected the other fields to be reported, got %v", status)
+	}
+}
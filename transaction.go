@@ -0,0 +1,50 @@
+package limiters
+
+import (
+	"context"
+	"sync"
+)
+
+// Implemented by limiters able to admit calls without taking tokens, e.g.
+// while paused.
+type freeAdmitter interface {
+	// Same as Limit, also reporting whether the call was admitted for free.
+	limitFree(ctx context.Context) (free bool, err error)
+}
+
+// Takes a token from the limiter, to be kept with commit or given back with
+// rollback once the operation it authorized is known to have succeeded or
+// failed.
+//
+// Only the first of commit and rollback has an effect, and calling neither
+// keeps the token consumed. Rollback gives the token back if the limiter
+// implements TokenReturner, which never fills it past its capacity, unless the
+// call was admitted without taking a token, e.g. while the limiter was paused.
+func Begin(ctx context.Context, l Limiter) (commit, rollback func(), err error) {
+	free := false
+	if admitter, ok := l.(freeAdmitter); ok {
+		free, err = admitter.limitFree(ctx)
+	} else {
+		err = l.Limit(ctx)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	commit = func() { once.Do(func() {}) }
+	rollback = func() {
+		once.Do(func() {
+			if !free {
+				returnTokens([]Limiter{l}, 1)
+			}
+		})
+	}
+	return commit, rollback, nil
+}
+
+// Blocks until a token is available or the context is canceled, and reports
+// whether the call was admitted while paused, without taking it.
+func (l *reservoirLimiter) limitFree(ctx context.Context) (bool, error) {
+	_, _, free, err := l.acquire(ctx, 1, PriorityNormal, false, "")
+	return free, err
+}
@@ -0,0 +1,98 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestBeginRollback(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(2, time.Hour, limiters.WithClock(limiterstest.NewClock()))
+	counter := limiter.(limiters.TokenCounter)
+
+	commit, rollback, err := limiters.Begin(context.Background(), limiter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected the token to be taken, %d left", got)
+	}
+	rollback()
+	rollback()
+	commit()
+	if got := counter.Available(); got != 2 {
+		t.Fatalf("expected the token to be given back once, %d left", got)
+	}
+}
+
+func TestBeginCommit(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(2, time.Hour, limiters.WithClock(limiterstest.NewClock()))
+	counter := limiter.(limiters.TokenCounter)
+
+	commit, rollback, _ := limiters.Begin(context.Background(), limiter)
+	commit()
+	rollback()
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected a committed token to stay consumed, %d left", got)
+	}
+}
+
+func TestBeginRollbackDoesNotOverfill(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour, limiters.WithClock(limiterstest.NewClock()))
+	counter := limiter.(limiters.TokenCounter)
+
+	_, rollback, _ := limiters.Begin(context.Background(), limiter)
+	limiter.(limiters.Resetter).Reset()
+	rollback()
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected the reservoir not to overfill, got %d tokens", got)
+	}
+}
+
+func TestBeginError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	commit, rollback, err := limiters.Begin(ctx, limiters.NewReservoirLimiter(1, time.Hour))
+	if !errors.Is(err, limiters.ErrCanceled) || commit != nil || rollback != nil {
+		t.Fatalf("expected ErrCanceled and no functions, got %v", err)
+	}
+}
+
+func TestBeginRollbackWhilePaused(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour, limiters.WithInitialTokens(0))
+	limiter.(limiters.Pauser).Pause()
+	_, rollback, err := limiters.Begin(context.Background(), limiter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter.(limiters.Pauser).Resume()
+	rollback()
+	if got := limiter.(limiters.TokenCounter).Available(); got != 0 {
+		t.Fatalf("expected a call admitted while paused to give no token back, got %d tokens", got)
+	}
+}
+
+func TestBeginRollbackReleasedByPause(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(1, time.Hour, limiters.WithInitialTokens(0))
+	reporter := limiter.(limiters.StatsReporter)
+	rollbacks := make(chan func())
+	go func() {
+		_, rollback, err := limiters.Begin(context.Background(), limiter)
+		if err != nil {
+			t.Error(err)
+		}
+		rollbacks <- rollback
+	}()
+	eventually(t, func() bool { return reporter.Stats().Waiting == 1 })
+	limiter.(limiters.Pauser).Pause()
+	rollback := <-rollbacks
+	limiter.(limiters.Pauser).Resume()
+	rollback()
+	if got := limiter.(limiters.TokenCounter).Available(); got != 0 {
+		t.Fatalf("expected a call released by Pause to give no token back, got %d tokens", got)
+	}
+}
@@ -0,0 +1,83 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Creates a limiter admitting calls at up to burst calls per second, for up
+// to burstWindow after a quiet period, then at sustained calls per second
+// once the burst budget is exhausted.
+//
+// The burst budget holds the calls admitted beyond the sustained rate over a
+// whole burst window, and is refilled whenever traffic stays below the
+// sustained rate. A call first takes a token from the sustained reservoir
+// holding the budget, then waits for the burst rate to allow it: calls taking
+// several tokens are paced at the burst rate, one token at a time.
+//
+// The options apply to both reservoirs, except for those only applying to the
+// sustained one: the capacity ones, WithInitialTokens and WithBurst, and those
+// identifying and observing the limiter, such as WithName, WithObserver and
+// WithRegistry, so that each call is reported once. Closing the limiter
+// closes both reservoirs. Panics if the rates are not positive, if the burst
+// rate does not exceed the sustained one, if the burst window is not
+// positive, or if the options are invalid.
+func NewTwoRateLimiter(sustained, burst float64, burstWindow time.Duration, opts ...Option) Limiter {
+	if !(sustained > 0) || !(burst > sustained) || burstWindow <= 0 {
+		panic(fmt.Errorf("%w: sustained rate %v with bursts of %v over %v", ErrInvalidConfig, sustained, burst, burstWindow))
+	}
+	budget := int(math.Ceil((burst - sustained) * burstWindow.Seconds()))
+	return NewChainLimiter(
+		NewRateLimiter(sustained, max(budget, 1), opts...),
+		pacedLimiter{NewRateLimiter(burst, 1, append(opts[:len(opts):len(opts)], asPacer())...)},
+	)
+}
+
+// Drops the settings of the options before it that only apply to the
+// sustained reservoir.
+func asPacer() Option {
+	return func(o *options) {
+		o.initialTokens = nil
+		o.burst = nil
+		o.name = ""
+		o.observer = nil
+		o.logger = nil
+		o.registry = nil
+		o.waitHistogram = false
+		o.onTransition = nil
+	}
+}
+
+// Limiter taking tokens one at a time, so that calls may take more tokens
+// than its capacity.
+type pacedLimiter struct {
+	Limiter
+}
+
+// Blocks until n tokens were taken one after the other or the context is
+// canceled, in which case the tokens taken are given back.
+func (l pacedLimiter) LimitN(ctx context.Context, n int) error {
+	for i := 0; i < n; i++ {
+		if err := l.Limiter.Limit(ctx); err != nil {
+			returnTokens([]Limiter{l.Limiter}, i)
+			return err
+		}
+	}
+	return nil
+}
+
+// Gives n unused tokens back.
+func (l pacedLimiter) ReturnTokens(n int) {
+	returnTokens([]Limiter{l.Limiter}, n)
+}
+
+// Closes the limiter if it implements io.Closer.
+func (l pacedLimiter) Close() error {
+	if closer, ok := l.Limiter.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestTwoRateLimiterConverges(t *testing.T) {
+	const step = time.Millisecond
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewTwoRateLimiter(100, 500, time.Second, limiters.WithClock(clock))
+
+	// Calls as fast as possible for 10s, counting the grants of each second.
+	var perSecond [10]int
+	for elapsed := time.Duration(0); elapsed < 10*time.Second; elapsed += step {
+		for i := 0; i < 5; i++ {
+			if limiter.TryLimit() {
+				perSecond[elapsed/time.Second]++
+			}
+		}
+		clock.Advance(step)
+	}
+	if perSecond[0] < 490 || perSecond[0] > 501 {
+		t.Fatalf("expected a burst of about 500 calls in the first second, got %d", perSecond[0])
+	}
+	for s := 2; s < len(perSecond); s++ {
+		if perSecond[s] < 99 || perSecond[s] > 101 {
+			t.Fatalf("expected about 100 calls in second %d, got %d", s, perSecond[s])
+		}
+	}
+
+	// A quiet period refills the burst budget.
+	clock.Advance(5 * time.Second)
+	granted := 0
+	for elapsed := time.Duration(0); elapsed < 100*time.Millisecond; elapsed += step {
+		if limiter.TryLimit() {
+			granted++
+		}
+		clock.Advance(step)
+	}
+	if granted < 49 || granted > 51 {
+		t.Fatalf("expected the burst rate back after a quiet period, got %d calls in 100ms", granted)
+	}
+}
+
+func TestTwoRateLimiterLimitN(t *testing.T) {
+	limiter := limiters.NewTwoRateLimiter(100, 500, time.Second, limiters.WithInitialTokens(10))
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := limiter.LimitN(context.Background(), 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Ten tokens at 500 calls per second take at least 18ms.
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Fatalf("expected the tokens to be paced at the burst rate, took %v", elapsed)
+	}
+	if err := limiter.LimitN(context.Background(), 401); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected calls beyond the burst budget to fail with ErrExceedsCapacity, got %v", err)
+	}
+}
+
+func TestTwoRateLimiterInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a burst rate below the sustained one to panic")
+		}
+	}()
+	limiters.NewTwoRateLimiter(100, 50, time.Second)
+}
+
+func TestTwoRateLimiterReportsOnce(t *testing.T) {
+	clock := limiterstest.NewClock()
+	observer := &countingObserver{}
+	registry := &limiters.Registry{}
+	limiter := limiters.NewTwoRateLimiter(100, 500, time.Second, limiters.WithClock(clock), limiters.WithObserver(observer), limiters.WithName("api"), limiters.WithRegistry(registry))
+	defer limiter.(io.Closer).Close()
+
+	for i := 0; i < 3; i++ {
+		if !limiter.TryLimit() {
+			t.Fatalf("expected call %d to be admitted", i)
+		}
+		clock.Advance(2 * time.Millisecond)
+	}
+	if got := observer.grants.Load(); got != 3 {
+		t.Fatalf("expected each call to be observed once, got %d grants", got)
+	}
+	if got := len(registry.List()); got != 1 {
+		t.Fatalf("expected the limiter to be registered once, got %d entries", got)
+	}
+}
+
+func TestTwoRateLimiterClose(t *testing.T) {
+	clock := limiterstest.NewClock()
+	limiter := limiters.NewTwoRateLimiter(100, 500, time.Second, limiters.WithClock(clock), limiters.WithAlwaysOn())
+	eventually(t, func() bool { return clock.ActiveTickers() == 2 })
+	if err := limiter.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool { return clock.ActiveTickers() == 0 })
+	if err := limiter.Limit(context.Background()); !errors.Is(err, limiters.ErrLimiterClosed) {
+		t.Fatalf("expected ErrLimiterClosed, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// Class of calls waiting on a weighted fair limiter.
+//
+// Classes take turns by stride scheduling: each call served advances the
+// pass of its class by the inverse of its weight, and the waiting class with
+// the lowest pass is served next.
+type trafficClass struct {
+	name    string
+	weight  int
+	pass    float64
+	waiting int
+}
+
+// Creates a reservoir limiter sharing its tokens across classes of calls, in
+// proportion to their weights when several of them are waiting.
+//
+// Tokens go first come, first served within a class. A class alone waiting
+// gets all the tokens. Classes without a weight, including the one of calls
+// made through Limit or LimitN, have a weight of 1. Panics if a weight is not
+// positive or if the options are invalid.
+func NewWeightedFairLimiter(maxTokens int, refillDuration time.Duration, weights map[string]int, opts ...Option) WeightedFairLimiter {
+	for class, weight := range weights {
+		if weight <= 0 {
+			panic(fmt.Errorf("%w: weight %d of class %q is not positive", ErrInvalidConfig, weight, class))
+		}
+	}
+	l := NewReservoirLimiter(maxTokens, refillDuration, opts...).(*reservoirLimiter)
+	l.weights = maps.Clone(weights)
+	if l.weights == nil {
+		l.weights = map[string]int{}
+	}
+	l.classes = map[string]*trafficClass{}
+	return l
+}
+
+// Blocks until a token is available for the class or the context is
+// canceled.
+//
+// Without weights, set with NewWeightedFairLimiter, the class is ignored.
+func (l *reservoirLimiter) LimitClass(ctx context.Context, class string) error {
+	_, _, err := l.wait(ctx, 1, PriorityNormal, false, class)
+	return err
+}
+
+// Returns the class of the given name, created with the current virtual time
+// as its pass if none of its calls are waiting, so that a class coming back
+// does not catch up for the time it was idle.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) trafficClass(name string) *trafficClass {
+	if c, ok := l.classes[name]; ok {
+		return c
+	}
+	weight, ok := l.weights[name]
+	if !ok {
+		weight = 1
+	}
+	c := &trafficClass{name: name, weight: weight, pass: l.virtualTime}
+	l.classes[name] = c
+	return c
+}
+
+// Advances the pass of the class of a served waiter.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) chargeClass(w *waiter) {
+	if w.class == nil {
+		return
+	}
+	l.virtualTime = w.class.pass
+	w.class.pass += float64(w.n) / float64(w.class.weight)
+}
+
+// Counts a waiter leaving its class, forgetting the class once none of its
+// calls are waiting.
+//
+// Must be called with the mutex held.
+func (l *reservoirLimiter) leaveClass(c *trafficClass) {
+	c.waiting--
+	if c.waiting == 0 {
+		delete(l.classes, c.name)
+	}
+}
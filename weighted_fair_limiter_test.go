@@ -0,0 +1,71 @@
+package limiters_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestWeightedFairLimiterProportionalSplit(t *testing.T) {
+	clock := limiterstest.NewClock()
+	weights := map[string]int{"free": 1, "pro": 2, "enterprise": 3}
+	limiter := limiters.NewWeightedFairLimiter(1, time.Second, weights,
+		limiters.WithClock(clock), limiters.WithInitialTokens(0))
+	defer limiter.(io.Closer).Close()
+	reporter := limiter.(limiters.StatsReporter)
+
+	// Keep every class saturated with callers.
+	const callers = 5
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		wg     sync.WaitGroup
+		grants = map[string]*atomic.Int64{}
+	)
+	for class := range weights {
+		grants[class] = &atomic.Int64{}
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for limiter.LimitClass(ctx, class) == nil {
+					grants[class].Add(1)
+				}
+			}()
+		}
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	const rounds = 60
+	waiting := int64(len(weights) * callers)
+	eventually(t, func() bool { return reporter.Stats().Waiting == waiting })
+	for i := 1; i <= rounds; i++ {
+		clock.Advance(time.Second)
+		eventually(t, func() bool {
+			stats := reporter.Stats()
+			return stats.Granted == uint64(i) && stats.Waiting == waiting
+		})
+	}
+	for class, weight := range weights {
+		want := int64(rounds * weight / 6)
+		if got := grants[class].Load(); got < want-1 || got > want+1 {
+			t.Errorf("expected about %d tokens for class %s, got %d", want, class, got)
+		}
+	}
+}
+
+func TestWeightedFairLimiterInvalidWeight(t *testing.T) {
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, limiters.ErrInvalidConfig) {
+			t.Errorf("expected a panic with ErrInvalidConfig, got %v", err)
+		}
+	}()
+	limiters.NewWeightedFairLimiter(1, time.Second, map[string]int{"free": 0})
+}
@@ -0,0 +1,73 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+)
+
+// Struct implementing the Limiter interface.
+type weightedLimiter struct {
+	limiter Limiter
+	cost    int
+}
+
+// Creates a limiter charging cost tokens of the given limiter per call.
+//
+// Several weighted limiters can share the same limiter to charge cheap and
+// expensive operations differently. Calls costing more tokens than currently
+// available wait for enough of them to accumulate. Panics if the cost is not
+// positive.
+func NewWeightedLimiter(l Limiter, cost int) Limiter {
+	if cost <= 0 {
+		panic(fmt.Errorf("%w: cost %d is not positive", ErrInvalidConfig, cost))
+	}
+	return &weightedLimiter{limiter: l, cost: cost}
+}
+
+// Blocks until the tokens of a call are available or the context is
+// canceled.
+func (l *weightedLimiter) Limit(ctx context.Context) error {
+	return l.limiter.LimitN(ctx, l.cost)
+}
+
+// Blocks until the tokens of n calls are available or the context is
+// canceled.
+func (l *weightedLimiter) LimitN(ctx context.Context, n int) error {
+	return l.limiter.LimitN(ctx, n*l.cost)
+}
+
+// Consumes the tokens of a call if they are immediately available, without
+// blocking.
+//
+// The tokens are taken at once if the limiter implements BatchTryLimiter, and
+// one at a time otherwise: if the limiter runs out midway, those already taken
+// are given back when it implements TokenReturner.
+func (l *weightedLimiter) TryLimit() bool {
+	return tryLimitN(l.limiter, l.cost)
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *weightedLimiter) Allow() bool {
+	return l.TryLimit()
+}
+
+// Gives the tokens of n unused calls back to the limiter.
+func (l *weightedLimiter) ReturnTokens(n int) {
+	returnTokens([]Limiter{l.limiter}, n*l.cost)
+}
+
+// Consumes n tokens from the limiter if they are immediately available,
+// taking them at once if it implements BatchTryLimiter, and otherwise one at a
+// time, giving them back if the limiter runs out.
+func tryLimitN(l Limiter, n int) bool {
+	if batch, ok := l.(BatchTryLimiter); ok {
+		return batch.TryLimitN(n)
+	}
+	for i := 0; i < n; i++ {
+		if !l.TryLimit() {
+			returnTokens([]Limiter{l}, i)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package limiters_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+)
+
+func TestWeightedLimiterCost(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(10, time.Hour)
+	counter := limiter.(limiters.TokenCounter)
+	expensive := limiters.NewWeightedLimiter(limiter, 4)
+	cheap := limiters.NewWeightedLimiter(limiter, 1)
+
+	if err := expensive.Limit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !cheap.TryLimit() || !expensive.TryLimit() {
+		t.Fatal("expected enough tokens for both calls")
+	}
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected 1 token left, got %d", got)
+	}
+	if expensive.TryLimit() {
+		t.Fatal("expected the expensive call to be rejected")
+	}
+	if got := counter.Available(); got != 1 {
+		t.Fatalf("expected the rejected call to give its token back, got %d", got)
+	}
+}
+
+func TestWeightedLimiterNoStarvation(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(5, time.Millisecond, limiters.WithInitialTokens(0))
+	expensive := limiters.NewWeightedLimiter(limiter, 5)
+	cheap := limiters.NewWeightedLimiter(limiter, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		wg           sync.WaitGroup
+		cheapGranted atomic.Int64
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for cheap.Limit(ctx) == nil {
+				cheapGranted.Add(1)
+			}
+		}()
+	}
+	eventually(t, func() bool { return cheapGranted.Load() > 10 })
+
+	done := make(chan error)
+	go func() { done <- expensive.Limit(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expensive call starved by cheap ones")
+	}
+	cancel()
+	wg.Wait()
+}
+
+func TestWeightedLimiterCountsOneCall(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(5, time.Hour)
+	reporter := limiter.(limiters.DropReporter)
+	weighted := limiters.NewWeightedLimiter(limiter, 3)
+
+	if !weighted.TryLimit() || weighted.TryLimit() {
+		t.Fatal("expected a single call to fit")
+	}
+	if admitted, dropped := reporter.Admitted(), reporter.Dropped(); admitted != 1 || dropped != 1 {
+		t.Fatalf("expected each call to count once, got %d admitted and %d dropped", admitted, dropped)
+	}
+	if got := limiter.(limiters.TokenCounter).Available(); got != 2 {
+		t.Fatalf("expected the rejected call to take no token, got %d tokens left", got)
+	}
+}
+
+func TestWeightedLimiterInvalidCost(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	limiters.NewWeightedLimiter(limiters.NewReservoirLimiter(5, time.Hour), 0)
+}
@@ -0,0 +1,79 @@
+package limiters
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Writer throttling the bytes written to an underlying writer.
+type writer struct {
+	w             io.Writer
+	limiter       Limiter
+	bytesPerToken int
+	// Bytes written but not charged yet, since they make up less than a
+	// token, or charged but not written, when negative.
+	owed int
+}
+
+// Implemented by limiters capping the tokens of a call below their capacity.
+type callCapper interface {
+	// Returns the most tokens a single call may take.
+	maxCallTokens() int
+}
+
+// Creates a writer passing bytes on to w once the limiter admits them, each
+// token allowing bytesPerToken bytes through, e.g. to throttle bandwidth.
+//
+// Writes take tokens with LimitN: bytes adding up to less than a token are
+// charged with those of the next writes, and bytes short writes did not write
+// are not charged. Writes larger than the capacity of a limiter implementing
+// Configurable, or than its burst for a reservoir limiter, are split. A Write waiting for tokens cannot be canceled: it
+// fails once the limiter does, e.g. when closed. Panics if bytesPerToken is
+// not positive.
+func NewWriter(w io.Writer, l Limiter, bytesPerToken int) io.Writer {
+	if bytesPerToken <= 0 {
+		panic(fmt.Errorf("%w: %d bytes per token is not positive", ErrInvalidConfig, bytesPerToken))
+	}
+	return &writer{w: w, limiter: l, bytesPerToken: bytesPerToken}
+}
+
+// Writes p once the limiter admits its bytes, a chunk at a time if p holds
+// more bytes than the limiter can admit at once.
+func (w *writer) Write(p []byte) (int, error) {
+	chunk, tokens := len(p), 0
+	if c, ok := w.limiter.(callCapper); ok {
+		tokens = c.maxCallTokens()
+	} else if c, ok := w.limiter.(Configurable); ok {
+		tokens = c.MaxTokens()
+	}
+	if tokens > 0 {
+		chunk = min(chunk, tokens*w.bytesPerToken)
+	}
+	if chunk == 0 {
+		return w.w.Write(p)
+	}
+	written := 0
+	for written < len(p) {
+		n, err := w.writeChunk(p[written:min(written+chunk, len(p))])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Charges the bytes of a chunk, then writes it.
+func (w *writer) writeChunk(p []byte) (int, error) {
+	owed := w.owed + len(p)
+	if tokens := owed / w.bytesPerToken; tokens > 0 {
+		if err := w.limiter.LimitN(context.Background(), tokens); err != nil {
+			return 0, err
+		}
+		owed -= tokens * w.bytesPerToken
+	}
+	n, err := w.w.Write(p)
+	w.owed = owed - (len(p) - n)
+	return n, err
+}
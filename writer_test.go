@@ -0,0 +1,104 @@
+package limiters_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/limiterstest"
+)
+
+func TestWriterRate(t *testing.T) {
+	const (
+		bytesPerToken = 100
+		rate          = 100 // tokens per second, i.e. 10 000 bytes per second
+		burst         = 10
+	)
+	var out bytes.Buffer
+	w := limiters.NewWriter(&out, limiters.NewRateLimiter(rate, burst), bytesPerToken)
+
+	start := time.Now()
+	chunk := make([]byte, 250)
+	for i := 0; i < 20; i++ {
+		if n, err := w.Write(chunk); n != len(chunk) || err != nil {
+			t.Fatalf("write %d: wrote %d bytes, got %v", i, n, err)
+		}
+	}
+	elapsed := time.Since(start)
+	if out.Len() != 5000 {
+		t.Fatalf("expected 5000 bytes to get through, got %d", out.Len())
+	}
+	// Beyond the burst, 4000 bytes at 10 000 bytes per second.
+	if bps := float64(out.Len()-burst*bytesPerToken) / elapsed.Seconds(); bps < 6000 || bps > 11000 {
+		t.Fatalf("expected about 10 000 bytes per second, got %.0f", bps)
+	}
+}
+
+func TestWriterChargesFractions(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(10, time.Hour, limiters.WithClock(limiterstest.NewClock()))
+	w := limiters.NewWriter(io.Discard, limiter, 10)
+
+	for i := 0; i < 10; i++ {
+		if _, err := w.Write([]byte("abc")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := limiter.(limiters.TokenCounter).Available(); got != 7 {
+		t.Fatalf("expected 30 bytes to be charged 3 tokens, got %d tokens left", got)
+	}
+}
+
+// Writer writing at most max bytes per call.
+type shortWriter struct {
+	max int
+}
+
+func (w shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.max {
+		return w.max, io.ErrShortWrite
+	}
+	return len(p), nil
+}
+
+func TestWriterShortWrites(t *testing.T) {
+	limiter := limiters.NewReservoirLimiter(10, time.Hour, limiters.WithClock(limiterstest.NewClock()))
+	w := limiters.NewWriter(shortWriter{max: 5}, limiter, 10)
+
+	if n, err := w.Write(make([]byte, 10)); n != 5 || !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("expected a short write of 5 bytes, got %d and %v", n, err)
+	}
+	if n, err := w.Write(make([]byte, 5)); n != 5 || err != nil {
+		t.Fatalf("expected the rest to be written, got %d and %v", n, err)
+	}
+	if got := limiter.(limiters.TokenCounter).Available(); got != 9 {
+		t.Fatalf("expected the 10 written bytes to be charged a token, got %d tokens left", got)
+	}
+}
+
+func TestWriterSplitsLargeWrites(t *testing.T) {
+	var out bytes.Buffer
+	w := limiters.NewWriter(&out, limiters.NewReservoirLimiter(10, time.Millisecond), 10)
+
+	if n, err := w.Write(make([]byte, 300)); n != 300 || err != nil {
+		t.Fatalf("expected a write beyond the capacity to be split, got %d and %v", n, err)
+	}
+	if out.Len() != 300 {
+		t.Fatalf("expected 300 bytes to get through, got %d", out.Len())
+	}
+}
+
+func TestWriterSplitsByBurst(t *testing.T) {
+	var out bytes.Buffer
+	limiter := limiters.NewReservoirLimiter(10, time.Millisecond, limiters.WithBurst(2))
+	w := limiters.NewWriter(&out, limiter, 10)
+
+	if n, err := w.Write(make([]byte, 100)); n != 100 || err != nil {
+		t.Fatalf("expected a write beyond the burst to be split, got %d and %v", n, err)
+	}
+	if out.Len() != 100 {
+		t.Fatalf("expected 100 bytes to get through, got %d", out.Len())
+	}
+}
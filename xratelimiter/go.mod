@@ -0,0 +1,10 @@
+module github.com/p-nordmann/limiters/xratelimiter
+
+go 1.23.0
+
+require (
+	github.com/p-nordmann/limiters v0.0.0
+	golang.org/x/time v0.11.0
+)
+
+replace github.com/p-nordmann/limiters => ../
@@ -0,0 +1,78 @@
+// Package xratelimiter adapts golang.org/x/time/rate limiters to the
+// limiters package.
+package xratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/p-nordmann/limiters"
+	"golang.org/x/time/rate"
+)
+
+// Struct implementing the Limiter interface.
+type stdRateLimiter struct {
+	limiter *rate.Limiter
+}
+
+// Wraps a limiter of golang.org/x/time/rate behind the Limiter interface.
+//
+// Limit and LimitN wait with Wait and WaitN, TryLimit and Allow use Allow.
+// Errors are reported like those of the limiters package.
+func FromStdRate(l *rate.Limiter) limiters.Limiter {
+	return &stdRateLimiter{limiter: l}
+}
+
+// Blocks until a token is available or the context is canceled.
+func (l *stdRateLimiter) Limit(ctx context.Context) error {
+	return l.LimitN(ctx, 1)
+}
+
+// Blocks until n tokens are available or the context is canceled.
+//
+// Fails right away with ErrDeadlineExceeded if the tokens would not be
+// available before the context deadline.
+func (l *stdRateLimiter) LimitN(ctx context.Context, n int) error {
+	if n > l.limiter.Burst() && l.limiter.Limit() != rate.Inf {
+		return limiters.ErrExceedsCapacity
+	}
+	err := l.limiter.WaitN(ctx, n)
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return waitError(ctx)
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", limiters.ErrDeadlineExceeded, err)
+	default:
+		if _, ok := ctx.Deadline(); ok {
+			return fmt.Errorf("%w: %w", limiters.ErrDeadlineExceeded, err)
+		}
+		return err
+	}
+}
+
+// Takes a token if one is immediately available, without blocking.
+func (l *stdRateLimiter) TryLimit() bool {
+	return l.limiter.Allow()
+}
+
+// Same as TryLimit, mirroring golang.org/x/time/rate.
+func (l *stdRateLimiter) Allow() bool {
+	return l.limiter.Allow()
+}
+
+// Returns the number of whole tokens currently available.
+func (l *stdRateLimiter) Available() int {
+	return max(int(l.limiter.Tokens()), 0)
+}
+
+// Returns the error of a canceled context, wrapped like the errors of the
+// limiters package.
+func waitError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", limiters.ErrDeadlineExceeded, ctx.Err())
+	}
+	return fmt.Errorf("%w: %w", limiters.ErrCanceled, ctx.Err())
+}
@@ -0,0 +1,68 @@
+package xratelimiter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/p-nordmann/limiters"
+	"github.com/p-nordmann/limiters/xratelimiter"
+	"golang.org/x/time/rate"
+)
+
+func TestFromStdRateLimit(t *testing.T) {
+	std := rate.NewLimiter(rate.Every(time.Hour), 2)
+	limiter := xratelimiter.FromStdRate(std)
+
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatalf("expected the first token, got %v", err)
+	}
+	if tokens := std.Tokens(); tokens > 1.01 {
+		t.Fatalf("expected Limit to take a token from the wrapped limiter, %v left", tokens)
+	}
+	if !limiter.TryLimit() {
+		t.Fatal("expected the second token to be available")
+	}
+	if limiter.Allow() {
+		t.Fatal("expected no token left")
+	}
+	if got := limiter.(limiters.TokenCounter).Available(); got != 0 {
+		t.Fatalf("expected no token available, got %d", got)
+	}
+}
+
+func TestFromStdRateWaits(t *testing.T) {
+	std := rate.NewLimiter(rate.Every(20*time.Millisecond), 1)
+	limiter := xratelimiter.FromStdRate(std)
+	limiter.TryLimit()
+
+	start := time.Now()
+	if err := limiter.Limit(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if waited := time.Since(start); waited < 10*time.Millisecond {
+		t.Fatalf("expected Limit to wait for the next token, waited %v", waited)
+	}
+}
+
+func TestFromStdRateErrors(t *testing.T) {
+	limiter := xratelimiter.FromStdRate(rate.NewLimiter(rate.Every(time.Hour), 1))
+
+	if err := limiter.LimitN(context.Background(), 2); !errors.Is(err, limiters.ErrExceedsCapacity) {
+		t.Fatalf("expected ErrExceedsCapacity, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Limit(ctx); !errors.Is(err, limiters.ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected ErrCanceled, got %v", err)
+	}
+
+	limiter.TryLimit()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := limiter.Limit(ctx); !errors.Is(err, limiters.ErrDeadlineExceeded) {
+		t.Fatalf("expected ErrDeadlineExceeded, got %v", err)
+	}
+}